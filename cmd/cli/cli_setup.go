@@ -59,6 +59,7 @@ func CLITestServiceSetup() (*CLIService, error) {
 	store := repository.NewSqliteStore(db)
 
 	service := CreateCLIService(store, store, store, &testServiceCommander{}, &testCommandExecutor{})
+	service.Config = &config.Config{}
 
 	return service, nil
 }
@@ -4,14 +4,49 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/jms-guy/timekeep/internal/database"
+	"github.com/jms-guy/timekeep/internal/programs"
+	"github.com/jms-guy/timekeep/internal/report"
+	"github.com/jms-guy/timekeep/internal/tags"
 )
 
-// Adds programs into the database, and sends communication to service to being tracking them
-func (s *CLIService) AddPrograms(ctx context.Context, args []string, category, project string) error {
+// Adds programs into the database, and sends communication to service to being tracking them. If exe or argsContains are
+// given, the single program argument is a logical name for processes matched by their command line instead
+func (s *CLIService) AddPrograms(ctx context.Context, args []string, category, project, match, exe, argsContains, scope string) error {
+	if match == "" {
+		match = "exact"
+	}
+	if scope == "" {
+		scope = "any"
+	}
+	if err := validateScope(scope); err != nil {
+		return err
+	}
+
+	if exe != "" || argsContains != "" {
+		if len(args) != 1 {
+			return fmt.Errorf("--exe/--args-contains require exactly one program name")
+		}
+		if match != "exact" {
+			return fmt.Errorf("--exe/--args-contains can't be combined with --match %s", match)
+		}
+	}
+
+	exeNull := sql.NullString{
+		String: strings.ToLower(exe),
+		Valid:  exe != "",
+	}
+
+	argsNull := sql.NullString{
+		String: argsContains,
+		Valid:  argsContains != "",
+	}
+
 	categoryNull := sql.NullString{
 		String: category,
 		Valid:  category != "",
@@ -23,10 +58,19 @@ func (s *CLIService) AddPrograms(ctx context.Context, args []string, category, p
 	}
 
 	for _, program := range args {
-		err := s.PrRepo.AddProgram(ctx, database.AddProgramParams{
-			Name:     strings.ToLower(program),
-			Category: categoryNull,
-			Project:  projectNull,
+		name, err := validateProgramPattern(program, match)
+		if err != nil {
+			return err
+		}
+
+		err = s.PrRepo.AddProgram(ctx, database.AddProgramParams{
+			Name:         name,
+			Category:     categoryNull,
+			Project:      projectNull,
+			MatchMode:    match,
+			Exe:          exeNull,
+			ArgsContains: argsNull,
+			Scope:        scope,
 		})
 		if err != nil {
 			return fmt.Errorf("error adding program %s: %w", program, err)
@@ -41,8 +85,8 @@ func (s *CLIService) AddPrograms(ctx context.Context, args []string, category, p
 	return nil
 }
 
-// Update program's category/project fields and notify service of change
-func (s *CLIService) UpdateProgram(ctx context.Context, args []string, category, project string) error {
+// Update program's category/project/scope fields and notify service of change
+func (s *CLIService) UpdateProgram(ctx context.Context, args []string, category, project, scope, minSession string) error {
 	program := args[0]
 
 	if category != "" {
@@ -65,6 +109,37 @@ func (s *CLIService) UpdateProgram(ctx context.Context, args []string, category,
 		}
 	}
 
+	if scope != "" {
+		if err := validateScope(scope); err != nil {
+			return err
+		}
+		err := s.PrRepo.UpdateScope(ctx, database.UpdateScopeParams{
+			Scope: scope,
+			Name:  program,
+		})
+		if err != nil {
+			return fmt.Errorf("error updating program scope: %w", err)
+		}
+	}
+
+	if minSession != "" {
+		minimum := sql.NullInt64{}
+		if minSession != "default" {
+			d, err := time.ParseDuration(minSession)
+			if err != nil || d < 0 || d%time.Second != 0 {
+				return fmt.Errorf("invalid minimum session length %q, expected a duration in whole seconds ex. 5s, or 'default'", minSession)
+			}
+			minimum = sql.NullInt64{Int64: int64(d.Seconds()), Valid: true}
+		}
+		err := s.PrRepo.UpdateMinSession(ctx, database.UpdateMinSessionParams{
+			MinSession: minimum,
+			Name:       program,
+		})
+		if err != nil {
+			return fmt.Errorf("error updating program minimum session length: %w", err)
+		}
+	}
+
 	err := s.ServiceCmd.WriteToService()
 	if err != nil {
 		return fmt.Errorf("programs updated but failed to notify service: %w", err)
@@ -73,12 +148,106 @@ func (s *CLIService) UpdateProgram(ctx context.Context, args []string, category,
 	return nil
 }
 
-// Removes programs from database, and tells service to stop tracking them
+// Adds exclusion rules for a program's helper processes, or clears existing ones, and notifies service of change
+func (s *CLIService) ExcludeProcesses(ctx context.Context, program string, argsRules, parentRules, pathRules []string, clear bool) error {
+	p, err := s.PrRepo.GetProgramByName(ctx, programs.Normalize(program))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("program %s is not being tracked", program)
+		}
+		return fmt.Errorf("error getting program %s: %w", program, err)
+	}
+
+	rules := make([]database.AddExclusionParams, 0, len(argsRules)+len(parentRules)+len(pathRules))
+	for _, r := range argsRules {
+		rules = append(rules, database.AddExclusionParams{ProgramName: p.Name, Kind: "args", Pattern: r})
+	}
+	for _, r := range parentRules {
+		rules = append(rules, database.AddExclusionParams{ProgramName: p.Name, Kind: "parent", Pattern: strings.ToLower(r)})
+	}
+	for _, r := range pathRules {
+		rules = append(rules, database.AddExclusionParams{ProgramName: p.Name, Kind: "path", Pattern: r})
+	}
+
+	for _, rule := range rules {
+		if rule.Pattern == "" {
+			return fmt.Errorf("empty %s exclusion pattern", rule.Kind)
+		}
+	}
+
+	err = s.withTx(ctx, func(tx *CLIService) error {
+		if clear {
+			err := tx.PrRepo.RemoveExclusionsForProgram(ctx, p.Name)
+			if err != nil {
+				return fmt.Errorf("error clearing exclusions for %s: %w", p.Name, err)
+			}
+		}
+
+		for _, rule := range rules {
+			err := tx.PrRepo.AddExclusion(ctx, rule)
+			if err != nil {
+				return fmt.Errorf("error adding %s exclusion %s: %w", rule.Kind, rule.Pattern, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.ServiceCmd.WriteToService()
+	if err != nil {
+		return fmt.Errorf("exclusions updated but failed to notify service: %w", err)
+	}
+
+	return nil
+}
+
+// Returns a program's helper process exclusion rules
+func (s *CLIService) GetExclusions(ctx context.Context, program string) (ExclusionList, error) {
+	p, err := s.PrRepo.GetProgramByName(ctx, programs.Normalize(program))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ExclusionList{}, fmt.Errorf("program %s is not being tracked", program)
+		}
+		return ExclusionList{}, fmt.Errorf("error getting program %s: %w", program, err)
+	}
+
+	exclusions, err := s.PrRepo.GetExclusionsForProgram(ctx, p.Name)
+	if err != nil {
+		return ExclusionList{}, fmt.Errorf("error getting exclusions for %s: %w", p.Name, err)
+	}
+
+	list := ExclusionList{Program: p.Name, Rules: make([]ExclusionRule, 0, len(exclusions))}
+	for _, ex := range exclusions {
+		list.Rules = append(list.Rules, ExclusionRule{Kind: ex.Kind, Pattern: ex.Pattern})
+	}
+
+	return list, nil
+}
+
+// Removes programs from database along with their exclusion rules and pauses, and tells service to stop tracking them
 func (s *CLIService) RemovePrograms(ctx context.Context, args []string, all bool) error {
 	if all {
-		err := s.PrRepo.RemoveAllPrograms(ctx)
+		err := s.withTx(ctx, func(tx *CLIService) error {
+			err := tx.PrRepo.RemoveAllExclusions(ctx)
+			if err != nil {
+				return fmt.Errorf("error removing all exclusions: %w", err)
+			}
+			err = tx.PrRepo.RemoveAllPauses(ctx)
+			if err != nil {
+				return fmt.Errorf("error removing all pauses: %w", err)
+			}
+			err = tx.PrRepo.RemoveAllPrograms(ctx)
+			if err != nil {
+				return fmt.Errorf("error removing all programs: %w", err)
+			}
+
+			return nil
+		})
 		if err != nil {
-			return fmt.Errorf("error removing all programs: %w", err)
+			return err
 		}
 
 		err = s.ServiceCmd.WriteToService()
@@ -93,14 +262,30 @@ func (s *CLIService) RemovePrograms(ctx context.Context, args []string, all bool
 		return fmt.Errorf("missing argument")
 	}
 
-	for _, program := range args {
-		err := s.PrRepo.RemoveProgram(ctx, strings.ToLower(program))
-		if err != nil {
-			return fmt.Errorf("error removing program %s: %w", program, err)
+	err := s.withTx(ctx, func(tx *CLIService) error {
+		for _, program := range args {
+			name := programs.Normalize(program)
+			err := tx.PrRepo.RemoveExclusionsForProgram(ctx, name)
+			if err != nil {
+				return fmt.Errorf("error removing exclusions for %s: %w", program, err)
+			}
+			err = tx.PrRepo.RemovePause(ctx, name)
+			if err != nil {
+				return fmt.Errorf("error removing pause for %s: %w", program, err)
+			}
+			err = tx.PrRepo.RemoveProgram(ctx, name)
+			if err != nil {
+				return fmt.Errorf("error removing program %s: %w", program, err)
+			}
 		}
+
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	err := s.ServiceCmd.WriteToService()
+	err = s.ServiceCmd.WriteToService()
 	if err != nil {
 		return fmt.Errorf("programs removed but failed to notify service: %w", err)
 	}
@@ -108,140 +293,272 @@ func (s *CLIService) RemovePrograms(ctx context.Context, args []string, all bool
 	return nil
 }
 
-// Prints a list of programs currently being tracked by service
-func (s *CLIService) GetList(ctx context.Context) error {
+// Returns the list of programs currently being tracked by service
+func (s *CLIService) GetList(ctx context.Context) (ProgramList, error) {
 	programs, err := s.PrRepo.GetAllProgramNames(ctx)
 	if err != nil {
-		return fmt.Errorf("error getting list of programs: %w", err)
-	}
-
-	if len(programs) == 0 {
-		return nil
+		return ProgramList{}, fmt.Errorf("error getting list of programs: %w", err)
 	}
-
-	for _, program := range programs {
-		fmt.Printf(" • %s\n", program)
+	if programs == nil {
+		programs = []string{}
 	}
 
-	return nil
+	return ProgramList{Programs: programs}, nil
 }
 
-// Return basic list of all programs being tracked and their current lifetime in minutes
-func (s *CLIService) GetAllInfo(ctx context.Context) error {
-	programs, err := s.PrRepo.GetAllPrograms(ctx)
+// Return basic stats of all programs being tracked, their current lifetime and session count. With a period, minimum
+// duration or tag, the stats only count sessions within the period, at least that long, or with that tag
+func (s *CLIService) GetAllInfo(ctx context.Context, date, start, end, minDuration, tag string) (ProgramSummaries, error) {
+	filter, err := newHistoryFilter("", date, start, end, "", minDuration, tag)
 	if err != nil {
-		return fmt.Errorf("error getting programs list: %w", err)
+		return ProgramSummaries{}, err
 	}
 
-	if len(programs) == 0 {
-		return nil
+	programs, err := s.PrRepo.GetAllPrograms(ctx)
+	if err != nil {
+		return ProgramSummaries{}, fmt.Errorf("error getting programs list: %w", err)
 	}
 
+	summaries := ProgramSummaries{Programs: make([]ProgramSummary, 0, len(programs)), filtered: filter.windowed() || filter.minDuration > 0 || filter.tag.Valid}
 	for _, program := range programs {
-		duration := time.Duration(program.LifetimeSeconds) * time.Second
+		filter.program = program.Name
+		stats, err := s.filteredStats(ctx, filter)
+		if err != nil {
+			return ProgramSummaries{}, err
+		}
 
-		if duration < time.Minute {
-			fmt.Printf("  %s: %d seconds\n", program.Name, int(duration.Seconds()))
-		} else if duration < time.Hour {
-			fmt.Printf("  %s: %d minutes\n", program.Name, int(duration.Minutes()))
-		} else {
-			hours := int(duration.Hours())
-			minutes := int(duration.Minutes()) % 60
-			fmt.Printf("  %s: %dh %dm\n", program.Name, hours, minutes)
+		summary := ProgramSummary{
+			Name:         program.Name,
+			TotalSeconds: program.LifetimeSeconds,
+			IdleSeconds:  program.IdleSeconds,
+			Sessions:     stats.Count,
+		}
+		if summaries.filtered {
+			summary.TotalSeconds, summary.IdleSeconds = stats.TotalSeconds, stats.IdleSeconds
 		}
+		summaries.Programs = append(summaries.Programs, summary)
 	}
 
-	return nil
+	return summaries, nil
 }
 
-// Get detailed stats for a single tracked program
-func (s *CLIService) GetInfo(ctx context.Context, args []string) error {
-	program, err := s.PrRepo.GetProgramByName(ctx, strings.ToLower(args[0]))
+// Get detailed stats for a single tracked program. With a period, minimum duration or tag, the session count and average
+// session length only count sessions within the period, at least that long, or with that tag
+func (s *CLIService) GetInfo(ctx context.Context, args []string, date, start, end, minDuration, tag string) (ProgramInfo, error) {
+	filter, err := newHistoryFilter("", date, start, end, "", minDuration, tag)
 	if err != nil {
-		return fmt.Errorf("error getting tracked program: %w", err)
+		return ProgramInfo{}, err
+	}
+
+	program, err := s.PrRepo.GetProgramByName(ctx, programs.Normalize(args[0]))
+	if err != nil {
+		return ProgramInfo{}, fmt.Errorf("error getting tracked program: %w", err)
 	}
 
-	duration := time.Duration(program.LifetimeSeconds) * time.Second
+	info := ProgramInfo{
+		Name:            program.Name,
+		Category:        program.Category.String,
+		Project:         program.Project.String,
+		LifetimeSeconds: program.LifetimeSeconds,
+		IdleSeconds:     program.IdleSeconds,
+	}
 
 	lastSession, err := s.HsRepo.GetLastSessionForProgram(ctx, program.Name)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			if program.Category.String != "" {
-				fmt.Printf(" • Category: %s\n", program.Category.String)
-			}
-			if program.Project.String != "" {
-				fmt.Printf(" • Project: %s\n", program.Project.String)
-			}
-			s.formatDuration(" • Current Lifetime: ", duration)
-			fmt.Printf(" • Total sessions to date: 0\n")
-			fmt.Printf(" • Last Session: None\n")
-			return nil
-		} else {
-			return fmt.Errorf("error getting last session for %s: %w", program.Name, err)
+			return info, nil
 		}
+		return ProgramInfo{}, fmt.Errorf("error getting last session for %s: %w", program.Name, err)
 	}
-
-	sessionCount, err := s.HsRepo.GetCountOfSessionsForProgram(ctx, program.Name)
+	lastTags, err := s.HsRepo.GetTagsForSession(ctx, lastSession.ID)
 	if err != nil {
-		return fmt.Errorf("error getting history count for %s: %w", program.Name, err)
+		return ProgramInfo{}, fmt.Errorf("error getting tags of session %d: %w", lastSession.ID, err)
 	}
+	last := newSessionRecord(lastSession, lastTags)
+	info.LastSession = &last
 
-	if program.Category.String != "" {
-		fmt.Printf(" • Category: %s\n", program.Category.String)
-	}
-	if program.Project.String != "" {
-		fmt.Printf(" • Project: %s\n", program.Project.String)
+	filter.program = program.Name
+	stats, err := s.filteredStats(ctx, filter)
+	if err != nil {
+		return ProgramInfo{}, err
 	}
-	s.formatDuration(" • Current Lifetime: ", duration)
-	fmt.Printf(" • Total sessions to date: %d\n", sessionCount)
-
-	lastDuration := time.Duration(lastSession.DurationSeconds) * time.Second
-	fmt.Printf(" • Last Session: %s - %s ",
-		lastSession.StartTime.Format("2006-01-02 15:04"),
-		lastSession.EndTime.Format("2006-01-02 15:04"))
-	s.formatDuration("(", lastDuration)
-	fmt.Printf(")\n")
 
-	if sessionCount > 0 {
-		avgSeconds := program.LifetimeSeconds / sessionCount
-		avgDuration := time.Duration(avgSeconds) * time.Second
-		s.formatDuration(" • Average session length: ", avgDuration)
+	info.Sessions = stats.Count
+	if stats.Count > 0 {
+		info.AverageSessionSeconds = stats.TotalSeconds / stats.Count
 	}
 
-	return nil
+	return info, nil
 }
 
-// Returns session history for a given program
-func (s *CLIService) GetSessionHistory(ctx context.Context, args []string, date, start, end string, limit int64) error {
+// Returns session history for a given program, optionally only sessions within a period, of the given user or with
+// the given tag
+func (s *CLIService) GetSessionHistory(ctx context.Context, args []string, date, start, end, user, minDuration, tag string, limit int64) (SessionList, error) {
 	programName := ""
 	if len(args) != 0 {
-		programName = args[0]
+		programName = programs.Normalize(args[0])
 	}
 
-	var history []database.SessionHistory
-	var err error
+	filter, err := newHistoryFilter(programName, date, start, end, user, minDuration, tag)
+	if err != nil {
+		return SessionList{}, err
+	}
+
+	history, err := s.filteredHistory(ctx, filter, limit)
+	if err != nil {
+		return SessionList{}, err
+	}
 
-	if programName == "" {
-		history, err = s.getSessionHistoryNoName(ctx, date, start, end, limit)
+	list := SessionList{Sessions: make([]SessionRecord, 0, len(history))}
+	for _, session := range history {
+		sessionTags, err := s.HsRepo.GetTagsForSession(ctx, session.ID)
 		if err != nil {
-			return err
+			return SessionList{}, fmt.Errorf("error getting tags of session %d: %w", session.ID, err)
 		}
-	} else {
-		history, err = s.getSessionHistoryNamed(ctx, programName, date, start, end, limit)
+		list.Sessions = append(list.Sessions, newSessionRecord(session, sessionTags))
+	}
+
+	// Sessions running past the edges of the filtered period only count the time inside it. The total counts every
+	// session in the period, including those past the limit
+	if filter.windowed() {
+		total, err := s.filteredTotal(ctx, filter)
 		if err != nil {
-			return err
+			return SessionList{}, err
 		}
+		list.TotalSeconds = &total
 	}
 
-	if len(history) == 0 {
-		return nil
+	return list, nil
+}
+
+// Returns the time tracked within a day, week or month (or custom range of days), grouped by groupBy. Sessions running
+// across the edges of the period only count the time inside it
+func (s *CLIService) GetReport(ctx context.Context, period, groupBy, date, start, end string) (Report, error) {
+	window, err := reportWindow(period, date, start, end)
+	if err != nil {
+		return Report{}, err
 	}
 
-	for _, session := range history {
-		printSession(session)
+	groups, err := s.reportGroups(ctx, groupBy, window)
+	if err != nil {
+		return Report{}, err
 	}
 
-	return nil
+	total, err := s.HsRepo.GetUsageTotal(ctx, database.GetUsageTotalParams{
+		WindowStart: window.Start.Unix(),
+		WindowEnd:   window.End.Unix(),
+	})
+	if err != nil {
+		return Report{}, fmt.Errorf("error getting time tracked in period: %w", err)
+	}
+
+	// The share of the period is taken of the time elapsed so far, for a period that hasn't ended yet
+	elapsed := window.End
+	if now := time.Now(); now.Before(elapsed) {
+		elapsed = now
+	}
+	elapsedSeconds := int64(elapsed.Sub(window.Start).Seconds())
+
+	for i := range groups {
+		groups[i].Share = percent(groups[i].TotalSeconds, total.TotalSeconds)
+		groups[i].PeriodShare = percent(groups[i].TotalSeconds, elapsedSeconds)
+	}
+
+	return Report{
+		Period:       period,
+		GroupBy:      groupBy,
+		Start:        window.Start,
+		End:          window.End,
+		TotalSeconds: total.TotalSeconds,
+		Sessions:     total.Sessions,
+		Groups:       groups,
+	}, nil
+}
+
+// Returns the daily totals of session history within a period, for the calendar chart. Without a period, covers the
+// year of weeks up to date (or today). Days after today are left out
+func (s *CLIService) GetCalendar(ctx context.Context, period, date, start, end string) (CalendarChart, error) {
+	window, err := chartWindow("calendar", period, date, start, end)
+	if err != nil {
+		return CalendarChart{}, err
+	}
+	if today := report.Day(time.Now()); today.End.Before(window.End) {
+		window.End = today.End
+	}
+
+	spans, err := s.HsRepo.GetSessionSpans(ctx, database.GetSessionSpansParams{
+		WindowStart: window.Start.Unix(),
+		WindowEnd:   window.End.Unix(),
+	})
+	if err != nil {
+		return CalendarChart{}, fmt.Errorf("error getting sessions in period: %w", err)
+	}
+
+	return CalendarChart{Start: window.Start, End: window.End, Days: dailyTotals(spans, window)}, nil
+}
+
+// Returns the time of session history within a period per hour of the week, for the hour by weekday chart. Without a
+// period, covers the month containing date (or today)
+func (s *CLIService) GetHourChart(ctx context.Context, period, date, start, end string) (HourChart, error) {
+	window, err := chartWindow("hours", period, date, start, end)
+	if err != nil {
+		return HourChart{}, err
+	}
+
+	spans, err := s.HsRepo.GetSessionSpans(ctx, database.GetSessionSpansParams{
+		WindowStart: window.Start.Unix(),
+		WindowEnd:   window.End.Unix(),
+	})
+	if err != nil {
+		return HourChart{}, fmt.Errorf("error getting sessions in period: %w", err)
+	}
+
+	return HourChart{Start: window.Start, End: window.End, Hours: hourTotals(spans)}, nil
+}
+
+// Merges each program's history sessions that start within gap of the previous session's end, the same rule the service
+// applies to new sessions with merge_gap set. The time between merged sessions counts as idle. Gap defaults to the
+// configured merge_gap, all programs are merged if none are given
+func (s *CLIService) MergeHistory(ctx context.Context, args []string, gap string) (MergeSummary, error) {
+	if gap == "" {
+		gap = s.Config.MergeGap
+	}
+	if gap == "" {
+		return MergeSummary{}, fmt.Errorf("no merge gap given, pass --gap or set one with 'timekeep config --merge_gap'")
+	}
+	mergeGap, err := time.ParseDuration(gap)
+	if err != nil || mergeGap <= 0 {
+		return MergeSummary{}, fmt.Errorf("invalid merge gap %q, expected a positive duration ex. 30s", gap)
+	}
+
+	names := args
+	if len(names) == 0 {
+		names, err = s.PrRepo.GetAllProgramNames(ctx)
+		if err != nil {
+			return MergeSummary{}, fmt.Errorf("error getting programs list: %w", err)
+		}
+	}
+
+	summary := MergeSummary{Programs: []MergedProgram{}}
+	for _, program := range names {
+		program = programs.Normalize(program)
+
+		merged := 0
+		err := s.withTx(ctx, func(tx *CLIService) error {
+			var err error
+			merged, err = tx.mergeProgramHistory(ctx, program, mergeGap)
+			return err
+		})
+		if err != nil {
+			return MergeSummary{}, err
+		}
+
+		if merged > 0 {
+			summary.Programs = append(summary.Programs, MergedProgram{Program: program, Merged: int64(merged)})
+		}
+	}
+
+	return summary, nil
 }
 
 // Reset tracked program session records
@@ -259,7 +576,7 @@ func (s *CLIService) ResetStats(ctx context.Context, args []string, all bool) er
 		}
 
 		for _, program := range args {
-			err := s.ResetDatabaseForProgram(ctx, strings.ToLower(program))
+			err := s.ResetDatabaseForProgram(ctx, programs.Normalize(program))
 			if err != nil {
 				return err
 			}
@@ -275,59 +592,385 @@ func (s *CLIService) ResetStats(ctx context.Context, args []string, all bool) er
 	return nil
 }
 
-// Removes active session and session records for all programs
+// Removes active session and session records for all programs, in a single transaction
 func (s *CLIService) ResetAllDatabase(ctx context.Context) error {
-	err := s.AsRepo.RemoveAllSessions(ctx)
+	return s.withTx(ctx, func(tx *CLIService) error {
+		err := tx.AsRepo.RemoveAllSessions(ctx)
+		if err != nil {
+			return fmt.Errorf("error removing all active sessions: %w", err)
+		}
+		err = tx.HsRepo.RemoveAllRecords(ctx)
+		if err != nil {
+			return fmt.Errorf("error removing all session records: %w", err)
+		}
+		err = tx.HsRepo.RemoveOrphanedTags(ctx)
+		if err != nil {
+			return fmt.Errorf("error removing session tags: %w", err)
+		}
+		err = tx.PrRepo.ResetAllLifetimes(ctx)
+		if err != nil {
+			return fmt.Errorf("error resetting lifetime values: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// Removes active session and session records for single program, in a single transaction
+func (s *CLIService) ResetDatabaseForProgram(ctx context.Context, program string) error {
+	program = programs.Normalize(program)
+
+	return s.withTx(ctx, func(tx *CLIService) error {
+		err := tx.AsRepo.RemoveActiveSession(ctx, program)
+		if err != nil {
+			return fmt.Errorf("error removing active session for %s: %w", program, err)
+		}
+		err = tx.HsRepo.RemoveRecordsForProgram(ctx, program)
+		if err != nil {
+			return fmt.Errorf("error removing session records for %s: %w", program, err)
+		}
+		err = tx.HsRepo.RemoveOrphanedTags(ctx)
+		if err != nil {
+			return fmt.Errorf("error removing session tags for %s: %w", program, err)
+		}
+		err = tx.PrRepo.ResetLifetimeForProgram(ctx, program)
+		if err != nil {
+			return fmt.Errorf("error resetting lifetime for %s: %w", program, err)
+		}
+
+		return nil
+	})
+}
+
+// Returns the currently active sessions being tracked by service, and the programs paused
+func (s *CLIService) GetActiveSessions(ctx context.Context) (ActiveList, error) {
+	pauses, err := s.activePauses(ctx)
 	if err != nil {
-		return fmt.Errorf("error removing all active sessions: %w", err)
+		return ActiveList{}, err
 	}
-	err = s.HsRepo.RemoveAllRecords(ctx)
+	activeSessions, err := s.AsRepo.GetAllActiveSessions(ctx)
 	if err != nil {
-		return fmt.Errorf("error removing all session records: %w", err)
+		return ActiveList{}, fmt.Errorf("error getting active sessions: %w", err)
+	}
+
+	list := ActiveList{Paused: make([]PauseRecord, 0, len(pauses)), Sessions: make([]ActiveRecord, 0, len(activeSessions))}
+	for _, pause := range pauses {
+		list.Paused = append(list.Paused, newPauseRecord(pause))
 	}
-	err = s.PrRepo.ResetAllLifetimes(ctx)
+	now := time.Now()
+	for _, session := range activeSessions {
+		list.Sessions = append(list.Sessions, newActiveRecord(session, now))
+	}
+
+	return list, nil
+}
+
+// Starts a manual timer for an activity that isn't a process, recorded by the service as a session under label
+func (s *CLIService) StartTimer(ctx context.Context, label, category, project string) error {
+	label = programs.Normalize(label)
+
+	if _, err := s.PrRepo.GetProgramByName(ctx, label); err == nil {
+		return fmt.Errorf("%s is a tracked program, timers need a label of their own", label)
+	}
+	if _, err := s.AsRepo.GetActiveSession(ctx, label); err == nil {
+		return fmt.Errorf("timer %s is already running", label)
+	}
+
+	err := s.ServiceCmd.SendCommand(Command{Action: "timer_start", ProcessName: label, Category: category, Project: project})
 	if err != nil {
-		return fmt.Errorf("error resetting lifetime values: %w", err)
+		return fmt.Errorf("failed to start timer: %w", err)
 	}
 
+	fmt.Printf("Timer %s started\n", label)
 	return nil
 }
 
-// Removes Removes active session and session records for single program
-func (s *CLIService) ResetDatabaseForProgram(ctx context.Context, program string) error {
-	program = strings.ToLower(program)
+// Stops the manual timer under label, or all running timers if no label is given
+func (s *CLIService) StopTimer(ctx context.Context, args []string) error {
+	activeSessions, err := s.AsRepo.GetAllActiveSessions(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting active sessions: %w", err)
+	}
+
+	label := ""
+	if len(args) != 0 {
+		label = programs.Normalize(args[0])
+	}
+
+	var timers []database.ActiveSession
+	for _, session := range activeSessions {
+		if session.Manual && (label == "" || session.ProgramName == label) {
+			timers = append(timers, session)
+		}
+	}
+	if len(timers) == 0 {
+		if label != "" {
+			return fmt.Errorf("no timer running for %s", label)
+		}
+		return fmt.Errorf("no timers running")
+	}
+
+	err = s.ServiceCmd.SendCommand(Command{Action: "timer_stop", ProcessName: label})
+	if err != nil {
+		return fmt.Errorf("failed to stop timer: %w", err)
+	}
+
+	for _, timer := range timers {
+		s.formatDuration(fmt.Sprintf("Timer %s stopped after ", timer.ProgramName), time.Since(timer.StartTime))
+	}
+
+	return nil
+}
+
+// Pauses tracking of the given programs, or of every program if none are given, for duration or until resumed if empty
+func (s *CLIService) PauseTracking(ctx context.Context, args []string, duration string) error {
+	if duration != "" {
+		d, err := time.ParseDuration(duration)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid pause duration %q, ex. 30m", duration)
+		}
+	}
+
+	names := make([]string, 0, len(args))
+	for _, arg := range args {
+		program := programs.Normalize(arg)
+		_, err := s.PrRepo.GetProgramByName(ctx, program)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("%s is not a tracked program", arg)
+			}
+			return fmt.Errorf("error getting program %s: %w", arg, err)
+		}
+		names = append(names, program)
+	}
 
-	err := s.AsRepo.RemoveActiveSession(ctx, program)
+	err := s.ServiceCmd.SendCommand(Command{Action: "pause", Programs: names, For: duration})
 	if err != nil {
-		return fmt.Errorf("error removing active session for %s: %w", program, err)
+		return fmt.Errorf("failed to pause tracking: %w", err)
+	}
+
+	if duration != "" {
+		fmt.Printf("Tracking of %s paused for %s\n", pauseTarget(names...), duration)
+	} else {
+		fmt.Printf("Tracking of %s paused until resumed\n", pauseTarget(names...))
 	}
-	err = s.HsRepo.RemoveRecordsForProgram(ctx, program)
+	return nil
+}
+
+// Resumes tracking of the given programs, or of everything paused if none are given
+func (s *CLIService) ResumeTracking(ctx context.Context, args []string) error {
+	pauses, err := s.activePauses(ctx)
 	if err != nil {
-		return fmt.Errorf("error removing session records for %s: %w", program, err)
+		return err
+	}
+	if len(pauses) == 0 {
+		return fmt.Errorf("tracking isn't paused")
+	}
+
+	paused := make(map[string]struct{}, len(pauses))
+	for _, pause := range pauses {
+		paused[pause.ProgramName] = struct{}{}
+	}
+
+	names := make([]string, 0, len(args))
+	for _, arg := range args {
+		program := programs.Normalize(arg)
+		if _, ok := paused[program]; !ok {
+			if _, all := paused[""]; all {
+				return fmt.Errorf("tracking of all programs is paused, resume it with: timekeep resume")
+			}
+			return fmt.Errorf("tracking of %s isn't paused", program)
+		}
+		names = append(names, program)
 	}
-	err = s.PrRepo.ResetLifetimeForProgram(ctx, program)
+
+	err = s.ServiceCmd.SendCommand(Command{Action: "resume", Programs: names})
 	if err != nil {
-		return fmt.Errorf("error resetting lifetime for %s: %w", program, err)
+		return fmt.Errorf("failed to resume tracking: %w", err)
 	}
 
+	if len(names) == 0 {
+		fmt.Println("Tracking resumed")
+	} else {
+		fmt.Printf("Tracking of %s resumed\n", pauseTarget(names...))
+	}
 	return nil
 }
 
-// Prints a list of currently active sessions being tracked by service
-func (s *CLIService) GetActiveSessions(ctx context.Context) error {
-	activeSessions, err := s.AsRepo.GetAllActiveSessions(ctx)
+// Adds a session to a program's history by hand, flagged manual, for time the service didn't track
+func (s *CLIService) AddSession(ctx context.Context, program, start, end, project string) error {
+	program = programs.Normalize(program)
+
+	if _, err := s.PrRepo.GetProgramByName(ctx, program); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("program %s is not being tracked", program)
+		}
+		return fmt.Errorf("error getting tracked program: %w", err)
+	}
+
+	if start == "" || end == "" {
+		return fmt.Errorf("session start and end times are required")
+	}
+	startTime, err := parseSessionTime(start)
 	if err != nil {
-		return fmt.Errorf("error getting active sessions: %w", err)
+		return err
+	}
+	endTime, err := parseSessionTime(end)
+	if err != nil {
+		return err
+	}
+
+	err = s.withTx(ctx, func(tx *CLIService) error {
+		if err := tx.checkSession(ctx, program, startTime, endTime, 0); err != nil {
+			return err
+		}
+
+		_, err := tx.HsRepo.AddToSessionHistory(ctx, database.AddToSessionHistoryParams{
+			ProgramName:     program,
+			StartTime:       startTime,
+			EndTime:         endTime,
+			DurationSeconds: int64(endTime.Sub(startTime).Seconds()),
+			Project:         sql.NullString{String: project, Valid: project != ""},
+			Manual:          true,
+		})
+		if err != nil {
+			return fmt.Errorf("error adding session for %s: %w", program, err)
+		}
+
+		return tx.recomputeLifetime(ctx, program)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Session added for %s\n", program)
+	return nil
+}
+
+// Changes the start, end or project of a history session, keeping its program's lifetime in step
+func (s *CLIService) EditSession(ctx context.Context, id int64, start, end, project string) error {
+	return s.withTx(ctx, func(tx *CLIService) error {
+		session, err := tx.getSessionRecord(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if start != "" {
+			if session.StartTime, err = parseSessionTime(start); err != nil {
+				return err
+			}
+		}
+		if end != "" {
+			if session.EndTime, err = parseSessionTime(end); err != nil {
+				return err
+			}
+		}
+		if project != "" {
+			session.Project = sql.NullString{String: project, Valid: project != "none"}
+		}
+
+		if err := tx.checkSession(ctx, session.ProgramName, session.StartTime, session.EndTime, session.ID); err != nil {
+			return err
+		}
+
+		duration := int64(session.EndTime.Sub(session.StartTime).Seconds())
+		err = tx.HsRepo.EditSessionRecord(ctx, database.EditSessionRecordParams{
+			StartTime:       session.StartTime,
+			EndTime:         session.EndTime,
+			DurationSeconds: duration,
+			IdleSeconds:     min(session.IdleSeconds, duration),
+			Project:         session.Project,
+			ID:              session.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("error editing session %d: %w", id, err)
+		}
+
+		return tx.recomputeLifetime(ctx, session.ProgramName)
+	})
+}
+
+// Removes a session from history, taking its time off its program's lifetime
+func (s *CLIService) RemoveSession(ctx context.Context, id int64) error {
+	return s.withTx(ctx, func(tx *CLIService) error {
+		session, err := tx.getSessionRecord(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.HsRepo.RemoveTagsForSession(ctx, session.ID); err != nil {
+			return fmt.Errorf("error removing tags of session %d: %w", id, err)
+		}
+		if err := tx.HsRepo.RemoveSessionRecord(ctx, session.ID); err != nil {
+			return fmt.Errorf("error removing session %d: %w", id, err)
+		}
+
+		return tx.recomputeLifetime(ctx, session.ProgramName)
+	})
+}
+
+// Adds tags to, or removes them from, a history session by ID or a program's active session by name
+func (s *CLIService) TagSession(ctx context.Context, target string, tagArgs []string, remove bool) error {
+	sessionTags := make([]string, 0, len(tagArgs))
+	for _, arg := range tagArgs {
+		tag, ok := tags.Normalize(arg)
+		if !ok {
+			return fmt.Errorf("invalid tag %q, tags may not contain spaces or commas", arg)
+		}
+		sessionTags = append(sessionTags, tag)
+	}
+
+	id, program, active, err := s.sessionTarget(ctx, target)
+	if err != nil {
+		return err
 	}
-	if len(activeSessions) == 0 {
+
+	if program != "" {
+		current := tags.Parse(active.Tags)
+		if remove {
+			current = slices.DeleteFunc(current, func(tag string) bool { return slices.Contains(sessionTags, tag) })
+		} else {
+			current = append(current, sessionTags...)
+		}
+
+		err := s.AsRepo.UpdateActiveTags(ctx, database.UpdateActiveTagsParams{Tags: tags.Join(current), ProgramName: program})
+		if err != nil {
+			return fmt.Errorf("error updating tags of active session for %s: %w", program, err)
+		}
 		return nil
 	}
 
-	for _, session := range activeSessions {
-		duration := time.Since(session.StartTime)
-		sessionDetails := fmt.Sprintf(" • %s - ", session.ProgramName)
+	return s.withTx(ctx, func(tx *CLIService) error {
+		for _, tag := range sessionTags {
+			var err error
+			if remove {
+				err = tx.HsRepo.RemoveSessionTag(ctx, database.RemoveSessionTagParams{SessionID: id, Tag: tag})
+			} else {
+				err = tx.HsRepo.AddSessionTag(ctx, database.AddSessionTagParams{SessionID: id, Tag: tag})
+			}
+			if err != nil {
+				return fmt.Errorf("error updating tags of session %d: %w", id, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Sets the notes of a history session by ID or a program's active session by name, empty notes clear them
+func (s *CLIService) AnnotateSession(ctx context.Context, target, notes string) error {
+	id, program, _, err := s.sessionTarget(ctx, target)
+	if err != nil {
+		return err
+	}
 
-		s.formatDuration(sessionDetails, duration)
+	value := sql.NullString{String: notes, Valid: notes != ""}
+	if program != "" {
+		err = s.AsRepo.UpdateActiveNotes(ctx, database.UpdateActiveNotesParams{Notes: value, ProgramName: program})
+	} else {
+		err = s.HsRepo.UpdateSessionNotes(ctx, database.UpdateSessionNotesParams{Notes: value, ID: id})
+	}
+	if err != nil {
+		return fmt.Errorf("error updating session notes: %w", err)
 	}
 
 	return nil
@@ -431,23 +1074,105 @@ func (s *CLIService) DisableWakapi() error {
 	return nil
 }
 
+// Config values to set, as given to the config command's flags. Empty fields, and a nil poll grace, are left unchanged
+type configUpdate struct {
+	cliPath       string
+	server        string
+	project       string
+	interval      string
+	pollGrace     *int
+	backend       string
+	detectProject string
+	idleTimeout   string
+	idleInput     string
+	mergeGap      string
+	minSession    string
+	splitDays     string
+}
+
 // Set various config values
-func (s *CLIService) SetConfig(cliPath, server, project, interval string, grace int) error {
-	if cliPath != "" {
-		s.Config.WakaTime.CLIPath = cliPath
+func (s *CLIService) SetConfig(u configUpdate) error {
+	if u.cliPath != "" {
+		s.Config.WakaTime.CLIPath = u.cliPath
 	}
-	if server != "" {
-		s.Config.Wakapi.Server = server
+	if u.server != "" {
+		s.Config.Wakapi.Server = u.server
 	}
-	if project != "" {
-		s.Config.WakaTime.GlobalProject = project
-		s.Config.Wakapi.GlobalProject = project
+	if u.project != "" {
+		s.Config.WakaTime.GlobalProject = u.project
+		s.Config.Wakapi.GlobalProject = u.project
+	}
+	if u.interval != "" {
+		s.Config.PollInterval = u.interval
+	}
+	if u.backend != "" {
+		if u.backend != "poll" && u.backend != "netlink" {
+			return fmt.Errorf("invalid monitor backend %q, expected poll or netlink", u.backend)
+		}
+		s.Config.MonitorBackend = u.backend
+	}
+	if u.detectProject != "" {
+		detect, err := strconv.ParseBool(u.detectProject)
+		if err != nil {
+			return fmt.Errorf("invalid detect_project value %q, expected true or false", u.detectProject)
+		}
+		s.Config.DetectProject = detect
+	}
+	if u.idleTimeout != "" {
+		if u.idleTimeout == "off" {
+			s.Config.IdleTimeout = ""
+		} else {
+			d, err := time.ParseDuration(u.idleTimeout)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("invalid idle_timeout %q, expected a positive duration ex. 5m", u.idleTimeout)
+			}
+			s.Config.IdleTimeout = u.idleTimeout
+		}
+	}
+	if u.idleInput != "" {
+		switch u.idleInput {
+		case "none":
+			s.Config.IdleInput = ""
+		case "interrupts", "logind":
+			s.Config.IdleInput = u.idleInput
+		default:
+			return fmt.Errorf("invalid idle_input %q, expected interrupts, logind or none", u.idleInput)
+		}
+	}
+	if u.mergeGap != "" {
+		if u.mergeGap == "off" {
+			s.Config.MergeGap = ""
+		} else {
+			d, err := time.ParseDuration(u.mergeGap)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("invalid merge_gap %q, expected a positive duration ex. 30s", u.mergeGap)
+			}
+			s.Config.MergeGap = u.mergeGap
+		}
+	}
+	if u.minSession != "" {
+		if u.minSession == "off" {
+			s.Config.MinSession = ""
+		} else {
+			d, err := time.ParseDuration(u.minSession)
+			if err != nil || d <= 0 || d%time.Second != 0 {
+				return fmt.Errorf("invalid min_session %q, expected a positive duration in whole seconds ex. 5s", u.minSession)
+			}
+			s.Config.MinSession = u.minSession
+		}
 	}
-	if interval != "" {
-		s.Config.PollInterval = interval
+	if u.splitDays != "" {
+		split, err := strconv.ParseBool(u.splitDays)
+		if err != nil {
+			return fmt.Errorf("invalid split_days value %q, expected true or false", u.splitDays)
+		}
+		s.Config.SplitDays = split
 	}
-	if grace != 3 && grace >= 0 {
-		s.Config.PollGrace = grace
+	if u.pollGrace != nil {
+		if *u.pollGrace < 0 {
+			return fmt.Errorf("invalid poll_grace %d, expected zero or more", *u.pollGrace)
+		}
+		s.Config.PollGrace = *u.pollGrace
 	}
 
 	if err := s.saveAndNotify(); err != nil {
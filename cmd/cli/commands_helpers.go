@@ -2,144 +2,588 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"math"
+	"os/user"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jms-guy/timekeep/internal/database"
+	"github.com/jms-guy/timekeep/internal/programs"
+	"github.com/jms-guy/timekeep/internal/report"
+	"github.com/jms-guy/timekeep/internal/repository"
+	"github.com/jms-guy/timekeep/internal/tags"
 )
 
-// Determine which SQL query to execute to return session history, no program name given
-func (s *CLIService) getSessionHistoryNoName(ctx context.Context, date, start, end string, limit int64) ([]database.SessionHistory, error) {
-	var history []database.SessionHistory
-	var err error
-	var dateTime time.Time
-	var startDate time.Time
-	var endDate time.Time = time.Now()
+// Filters selecting sessions from history, set by the filter flags of history and info
+type historyFilter struct {
+	program     string        // Empty for every program
+	window      report.Window // Zero to not filter by time
+	uid         sql.NullInt64
+	minDuration int64 // In seconds
+	tag         sql.NullString
+}
+
+// Builds the filter of history's flags. date, or start and end, select a period of time in any form report.ParseRange
+// accepts
+func newHistoryFilter(program, date, start, end, user, minDuration, tag string) (historyFilter, error) {
+	window, err := filterWindow(date, start, end, time.Now())
+	if err != nil {
+		return historyFilter{}, err
+	}
+	uid, err := resolveUser(user)
+	if err != nil {
+		return historyFilter{}, err
+	}
+	minSeconds, err := parseMinDuration(minDuration)
+	if err != nil {
+		return historyFilter{}, err
+	}
+	tagFilter, err := parseTagFilter(tag)
+	if err != nil {
+		return historyFilter{}, err
+	}
+
+	return historyFilter{program: program, window: window, uid: uid, minDuration: minSeconds, tag: tagFilter}, nil
+}
+
+// Reports whether the filter selects a period of time
+func (f historyFilter) windowed() bool {
+	return !f.window.End.IsZero()
+}
+
+// Returns the filtered period in Unix seconds, zero if there's none
+func (f historyFilter) bounds() (int64, int64) {
+	if !f.windowed() {
+		return 0, 0
+	}
+	return f.window.Start.Unix(), f.window.End.Unix()
+}
+
+// Returns the latest sessions passing the filter, up to limit, oldest first. Sessions overlapping the filtered period
+// are included whole
+func (s *CLIService) filteredHistory(ctx context.Context, f historyFilter, limit int64) ([]database.SessionHistory, error) {
+	start, end := f.bounds()
+
+	history, err := s.HsRepo.GetFilteredSessionHistory(ctx, database.GetFilteredSessionHistoryParams{
+		ProgramName: f.program,
+		WindowEnd:   end,
+		WindowStart: start,
+		Uid:         f.uid,
+		MinDuration: f.minDuration,
+		Tag:         f.tag,
+		Limit:       limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting session history: %w", err)
+	}
+
+	return history, nil
+}
+
+// Returns the time of every session passing the filter inside the filtered period, however many history lists
+func (s *CLIService) filteredTotal(ctx context.Context, f historyFilter) (int64, error) {
+	start, end := f.bounds()
+
+	total, err := s.HsRepo.GetFilteredSessionTotal(ctx, database.GetFilteredSessionTotalParams{
+		WindowEnd:   end,
+		WindowStart: start,
+		ProgramName: f.program,
+		Uid:         f.uid,
+		MinDuration: f.minDuration,
+		Tag:         f.tag,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error getting total session time: %w", err)
+	}
+
+	return total, nil
+}
+
+// Returns the count and time of the filtered program's sessions passing the filter, only counting the time inside the
+// filtered period. Idle time of sessions crossing the period's bounds is prorated to the part inside it
+func (s *CLIService) filteredStats(ctx context.Context, f historyFilter) (database.GetSessionStatsForProgramRow, error) {
+	start, end := f.bounds()
+
+	stats, err := s.HsRepo.GetSessionStatsForProgram(ctx, database.GetSessionStatsForProgramParams{
+		WindowEnd:   end,
+		WindowStart: start,
+		ProgramName: f.program,
+		MinDuration: f.minDuration,
+		Tag:         f.tag,
+	})
+	if err != nil {
+		return database.GetSessionStatsForProgramRow{}, fmt.Errorf("error getting session stats for %s: %w", f.program, err)
+	}
+
+	return stats, nil
+}
 
+// Returns the period of time the filter flags select: the period date names (the day of date if it's a time), or from
+// the start of start through the end of end (or now, if not given). Returns a zero window if no period is filtered on
+func filterWindow(date, start, end string, now time.Time) (report.Window, error) {
 	if date != "" {
-		dateTime, err = time.Parse("2006-01-02", date)
+		if start != "" || end != "" {
+			return report.Window{}, fmt.Errorf("--date can't be combined with --start or --end")
+		}
+		window, err := report.ParseRange(date, now)
 		if err != nil {
-			return history, err
+			return report.Window{}, err
 		}
-		startOfDay := time.Date(dateTime.Year(), dateTime.Month(), dateTime.Day(), 0, 0, 0, 0, dateTime.Location())
-		endOfDay := startOfDay.Add(24 * time.Hour)
+		if window.Start.Equal(window.End) {
+			window = report.Day(window.Start)
+		}
+		return window, nil
+	}
 
-		history, err = s.HsRepo.GetAllSessionHistoryByDate(ctx, database.GetAllSessionHistoryByDateParams{
-			StartTime: endOfDay,
-			EndTime:   startOfDay,
-			Limit:     limit,
-		})
+	if start == "" {
+		if end != "" {
+			return report.Window{}, fmt.Errorf("--end requires --start")
+		}
+		return report.Window{}, nil
+	}
+
+	return rangeWindow(start, end, now, now)
+}
 
-	} else if start != "" {
-		startDate, err = time.Parse("2006-01-02", start)
+// Returns the period from the start of start through the end of end, or through last if end isn't given
+func rangeWindow(start, end string, last, now time.Time) (report.Window, error) {
+	from, err := report.ParseStart(start, now)
+	if err != nil {
+		return report.Window{}, err
+	}
+	to := last
+	if end != "" {
+		to, err = report.ParseEnd(end, now)
 		if err != nil {
-			return history, err
+			return report.Window{}, err
 		}
-		startOfDay := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, startDate.Location())
+	}
 
-		if end != "" {
-			endDate, err = time.Parse("2006-01-02", end)
-			if err != nil {
-				return history, err
-			}
-			endDate = time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 999999999, endDate.Location())
+	if !to.After(from) {
+		if end == "" {
+			return report.Window{}, fmt.Errorf("start %q is in the future", start)
 		}
+		return report.Window{}, fmt.Errorf("end %q is before start %q", end, start)
+	}
 
-		history, err = s.HsRepo.GetAllSessionHistoryByRange(ctx, database.GetAllSessionHistoryByRangeParams{
-			StartTime: endDate,
-			EndTime:   startOfDay,
-			Limit:     limit,
-		})
-	} else {
-		history, err = s.HsRepo.GetAllSessionHistory(ctx, limit)
+	return report.Window{Start: from, End: to}, nil
+}
+
+// Returns the time within the day, week or month a report or chart covers, the start of the period date names, or now
+// if not given
+func reportDay(date string, now time.Time) (time.Time, error) {
+	if date == "" {
+		return now, nil
 	}
 
-	return history, err
+	window, err := report.ParseRange(date, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return window.Start, nil
 }
 
-// Determine which SQL query to execute to return session history, program name given
-func (s *CLIService) getSessionHistoryNamed(ctx context.Context, programName, date, start, end string, limit int64) ([]database.SessionHistory, error) {
-	var history []database.SessionHistory
-	var err error
-	var dateTime time.Time
-	var startDate time.Time
-	var endDate time.Time = time.Now()
+// Returns the period of local time a report covers, the day, week or month containing date (today, if not given), or
+// the period from start through end (or today, if not given) for a custom period
+func reportWindow(period, date, start, end string) (report.Window, error) {
+	if period != "custom" && (start != "" || end != "") {
+		return report.Window{}, fmt.Errorf("--start and --end are only used with --period custom")
+	}
 
-	if date != "" {
-		dateTime, err = time.Parse("2006-01-02", date)
-		if err != nil {
-			return history, err
+	now := time.Now()
+	day, err := reportDay(date, now)
+	if err != nil {
+		return report.Window{}, err
+	}
+
+	switch period {
+	case "day":
+		return report.Day(day), nil
+	case "week":
+		return report.Week(day), nil
+	case "month":
+		return report.Month(day), nil
+	case "custom":
+		if start == "" {
+			return report.Window{}, fmt.Errorf("--period custom requires --start")
 		}
-		startOfDay := time.Date(dateTime.Year(), dateTime.Month(), dateTime.Day(), 0, 0, 0, 0, dateTime.Location())
-		endOfDay := startOfDay.Add(24 * time.Hour)
+		return rangeWindow(start, end, report.Day(now).End, now)
+	default:
+		return report.Window{}, fmt.Errorf("invalid period %q, expected day, week, month or custom", period)
+	}
+}
 
-		history, err = s.HsRepo.GetSessionHistoryByDate(ctx, database.GetSessionHistoryByDateParams{
-			ProgramName: programName,
-			StartTime:   endOfDay,
-			EndTime:     startOfDay,
-			Limit:       limit,
-		})
-	} else if start != "" {
-		startDate, err = time.Parse("2006-01-02", start)
+// Returns the time tracked within the window grouped by groupBy, largest first for program, category, project and tag,
+// and in clock order for weekday and hour. A session with several tags counts towards each of them
+func (s *CLIService) reportGroups(ctx context.Context, groupBy string, window report.Window) ([]ReportGroup, error) {
+	start, end := window.Start.Unix(), window.End.Unix()
+	groups := []ReportGroup{}
+
+	switch groupBy {
+	case "program":
+		rows, err := s.HsRepo.GetUsageByProgram(ctx, database.GetUsageByProgramParams{WindowStart: start, WindowEnd: end})
+		if err != nil {
+			return nil, fmt.Errorf("error getting time tracked by program: %w", err)
+		}
+		for _, row := range rows {
+			groups = append(groups, ReportGroup{Name: row.Name, Sessions: row.Sessions, TotalSeconds: row.TotalSeconds})
+		}
+	case "category":
+		rows, err := s.HsRepo.GetUsageByCategory(ctx, database.GetUsageByCategoryParams{WindowStart: start, WindowEnd: end})
+		if err != nil {
+			return nil, fmt.Errorf("error getting time tracked by category: %w", err)
+		}
+		for _, row := range rows {
+			groups = append(groups, ReportGroup{Name: row.Name, Sessions: row.Sessions, TotalSeconds: row.TotalSeconds})
+		}
+	case "project":
+		rows, err := s.HsRepo.GetUsageByProject(ctx, database.GetUsageByProjectParams{WindowStart: start, WindowEnd: end})
 		if err != nil {
-			return history, err
+			return nil, fmt.Errorf("error getting time tracked by project: %w", err)
 		}
-		startOfDay := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, startDate.Location())
+		for _, row := range rows {
+			groups = append(groups, ReportGroup{Name: row.Name, Sessions: row.Sessions, TotalSeconds: row.TotalSeconds})
+		}
+	case "tag":
+		rows, err := s.HsRepo.GetUsageByTag(ctx, database.GetUsageByTagParams{WindowStart: start, WindowEnd: end})
+		if err != nil {
+			return nil, fmt.Errorf("error getting time tracked by tag: %w", err)
+		}
+		for _, row := range rows {
+			groups = append(groups, ReportGroup{Name: row.Name, Sessions: row.Sessions, TotalSeconds: row.TotalSeconds})
+		}
+	case "weekday", "hour":
+		spans, err := s.HsRepo.GetSessionSpans(ctx, database.GetSessionSpansParams{WindowStart: start, WindowEnd: end})
+		if err != nil {
+			return nil, fmt.Errorf("error getting sessions in period: %w", err)
+		}
+		groups = clockGroups(groupBy, spans)
+	default:
+		return nil, fmt.Errorf("invalid group %q, expected program, category, project, tag, weekday or hour", groupBy)
+	}
 
-		if end != "" {
-			endDate, err = time.Parse("2006-01-02", end)
+	return groups, nil
+}
+
+// Totals sessions, already clipped to the report's period, by local weekday (Monday first) or hour of the day. Sessions
+// are split where they run into the next day or hour, so each part counts towards its own group
+func clockGroups(groupBy string, spans []database.GetSessionSpansRow) []ReportGroup {
+	var groups []ReportGroup
+	split, group := report.SplitDays, func(t time.Time) int { return (int(t.Weekday()) + 6) % 7 }
+	if groupBy == "hour" {
+		split, group = report.SplitHours, func(t time.Time) int { return t.Hour() }
+		for hour := 0; hour < 24; hour++ {
+			groups = append(groups, ReportGroup{Name: fmt.Sprintf("%02d:00", hour)})
+		}
+	} else {
+		for day := 1; day <= 7; day++ {
+			groups = append(groups, ReportGroup{Name: time.Weekday(day % 7).String()})
+		}
+	}
+
+	for _, span := range spans {
+		counted := make(map[int]bool)
+		for _, part := range split(time.Unix(span.StartUnix, 0), time.Unix(span.EndUnix, 0)) {
+			i := group(part.Start.In(time.Local))
+			groups[i].TotalSeconds += int64(part.End.Sub(part.Start).Seconds())
+			if !counted[i] {
+				groups[i].Sessions++
+				counted[i] = true
+			}
+		}
+	}
+
+	return groups
+}
+
+// Returns the period a heatmap covers. Without a period, the calendar covers the 52 weeks up to date (or today), and hours
+// the month containing it
+func chartWindow(kind, period, date, start, end string) (report.Window, error) {
+	if period == "" {
+		switch kind {
+		case "calendar":
+			if start != "" || end != "" {
+				return report.Window{}, fmt.Errorf("--start and --end are only used with --period custom")
+			}
+			day, err := reportDay(date, time.Now())
 			if err != nil {
-				return history, err
+				return report.Window{}, err
 			}
-			endDate = time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 999999999, endDate.Location())
+			return report.Window{Start: report.Week(day).Start.AddDate(0, 0, -7*51), End: report.Day(day).End}, nil
+		case "hours":
+			period = "month"
 		}
+	}
 
-		history, err = s.HsRepo.GetSessionHistoryByRange(ctx, database.GetSessionHistoryByRangeParams{
-			ProgramName: programName,
-			StartTime:   endDate,
-			EndTime:     startOfDay,
-			Limit:       limit,
-		})
-	} else {
-		history, err = s.HsRepo.GetSessionHistory(ctx, database.GetSessionHistoryParams{
-			ProgramName: programName,
-			Limit:       limit,
-		})
+	return reportWindow(period, date, start, end)
+}
+
+// Totals sessions, already clipped to the window, per local day of the window. Sessions are split at midnight, so each
+// part counts towards its own day
+func dailyTotals(spans []database.GetSessionSpansRow, window report.Window) []DayTotal {
+	var days []DayTotal
+	index := make(map[string]int)
+	for _, day := range report.SplitDays(window.Start, window.End) {
+		date := day.Start.Format(report.DateLayout)
+		index[date] = len(days)
+		days = append(days, DayTotal{Date: date})
+	}
+
+	for _, span := range spans {
+		for _, part := range report.SplitDays(time.Unix(span.StartUnix, 0), time.Unix(span.EndUnix, 0)) {
+			i, ok := index[part.Start.In(time.Local).Format(report.DateLayout)]
+			if !ok {
+				continue
+			}
+			days[i].TotalSeconds += int64(part.End.Sub(part.Start).Seconds())
+		}
+	}
+
+	return days
+}
+
+// Totals sessions, already clipped to the chart's period, per local hour of the week, Monday 00:00 first
+func hourTotals(spans []database.GetSessionSpansRow) []HourTotal {
+	hours := make([]HourTotal, 0, 7*24)
+	for day := 1; day <= 7; day++ {
+		for hour := 0; hour < 24; hour++ {
+			hours = append(hours, HourTotal{Weekday: time.Weekday(day % 7).String(), Hour: hour})
+		}
+	}
+
+	for _, span := range spans {
+		for _, part := range report.SplitHours(time.Unix(span.StartUnix, 0), time.Unix(span.EndUnix, 0)) {
+			local := part.Start.In(time.Local)
+			hours[(int(local.Weekday())+6)%7*24+local.Hour()].TotalSeconds += int64(part.End.Sub(part.Start).Seconds())
+		}
+	}
+
+	return hours
+}
+
+// Returns part as a percentage of whole, to one decimal place
+func percent(part, whole int64) float64 {
+	if whole <= 0 {
+		return 0
+	}
+	return math.Round(float64(part)*1000/float64(whole)) / 10
+}
+
+// Parses a minimum session duration given to filter sessions by, returning it in seconds. Empty means no minimum
+func parseMinDuration(minDuration string) (int64, error) {
+	if minDuration == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(minDuration)
+	if err != nil || d < 0 || d%time.Second != 0 {
+		return 0, fmt.Errorf("invalid minimum duration %q, expected a duration in whole seconds ex. 10s", minDuration)
+	}
+
+	return int64(d.Seconds()), nil
+}
+
+// Resolves a user name or UID given to filter session history by, to the UID stored on sessions
+func resolveUser(name string) (sql.NullInt64, error) {
+	if name == "" {
+		return sql.NullInt64{}, nil
+	}
+
+	if uid, err := strconv.ParseInt(name, 10, 64); err == nil {
+		return sql.NullInt64{Int64: uid, Valid: true}, nil
 	}
 
-	return history, err
+	u, err := user.Lookup(name)
+	if err != nil {
+		return sql.NullInt64{}, fmt.Errorf("unknown user %s: %w", name, err)
+	}
+	uid, err := strconv.ParseInt(u.Uid, 10, 64)
+	if err != nil {
+		return sql.NullInt64{}, fmt.Errorf("user %s has no numeric uid", name)
+	}
+
+	return sql.NullInt64{Int64: uid, Valid: true}, nil
+}
+
+// Checks a program name is valid for its match mode, returning the name to store. Names are normalized to lowercase like
+// every other lookup of them, regex patterns keep the case of their escapes and are matched case-insensitively by the service
+func validateProgramPattern(program, match string) (string, error) {
+	program = programs.Normalize(program)
+	switch match {
+	case "exact":
+		return program, nil
+	case "glob":
+		if _, err := path.Match(program, ""); err != nil {
+			return "", fmt.Errorf("invalid glob pattern %s: %w", program, err)
+		}
+		return program, nil
+	case "regex":
+		if _, err := regexp.Compile(program); err != nil {
+			return "", fmt.Errorf("invalid regex pattern %s: %w", program, err)
+		}
+		return program, nil
+	default:
+		return "", fmt.Errorf("invalid match mode %q, expected exact, glob or regex", match)
+	}
+}
+
+// Checks a program scope is one of the values understood by the service
+func validateScope(scope string) error {
+	switch scope {
+	case "any", "host", "container":
+		return nil
+	default:
+		return fmt.Errorf("invalid scope %q, expected host, container or any", scope)
+	}
 }
 
 // Formats a time.Duration value to display hours, minutes or seconds
 func (s *CLIService) formatDuration(prefix string, duration time.Duration) {
+	fmt.Printf("%s%s\n", prefix, durationString(duration))
+}
+
+// Formats a duration as seconds, minutes, or hours and minutes depending on its length
+func durationString(duration time.Duration) string {
 	if duration < time.Minute {
-		fmt.Printf("%s%d seconds\n", prefix, int(duration.Seconds()))
+		return fmt.Sprintf("%d seconds", int(duration.Seconds()))
 	} else if duration < time.Hour {
-		fmt.Printf("%s%d minutes\n", prefix, int(duration.Minutes()))
-	} else {
-		hours := int(duration.Hours())
-		minutes := int(duration.Minutes()) % 60
-		fmt.Printf("%s%dh %dm\n", prefix, hours, minutes)
+		return fmt.Sprintf("%d minutes", int(duration.Minutes()))
+	}
+
+	hours := int(duration.Hours())
+	minutes := int(duration.Minutes()) % 60
+	return fmt.Sprintf("%dh %dm", hours, minutes)
+}
+
+// Merges a program's history sessions starting within gap of the previous session's end and owned by the same user,
+// adjusting the program's lifetime by the time gained or lost. With split_days set, rows meeting at local midnight are the
+// per-day parts of one session and are kept apart. Returns the number of sessions merged away
+func (s *CLIService) mergeProgramHistory(ctx context.Context, program string, gap time.Duration) (int, error) {
+	history, err := s.HsRepo.GetAllSessionsForProgram(ctx, program)
+	if err != nil {
+		return 0, fmt.Errorf("error getting session history for %s: %w", program, err)
+	}
+	if len(history) < 2 {
+		return 0, nil
 	}
+
+	merged := 0
+	var lifetimeDelta, idleDelta int64
+	cur := history[0]
+	for _, next := range history[1:] {
+		between := next.StartTime.Sub(cur.EndTime)
+		if between > gap || next.Uid != cur.Uid || (s.Config.SplitDays && splitAtMidnight(cur, next)) {
+			cur = next
+			continue
+		}
+
+		end := cur.EndTime
+		if next.EndTime.After(end) {
+			end = next.EndTime
+		}
+		duration := int64(end.Sub(cur.StartTime).Seconds())
+		idle := min(cur.IdleSeconds+next.IdleSeconds+max(int64(between.Seconds()), 0), duration)
+
+		err := s.HsRepo.UpdateSessionRecord(ctx, database.UpdateSessionRecordParams{
+			EndTime:         end,
+			DurationSeconds: duration,
+			IdleSeconds:     idle,
+			ID:              cur.ID,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("error updating session record for %s: %w", program, err)
+		}
+		if err := s.moveSessionAnnotations(ctx, next, cur); err != nil {
+			return 0, err
+		}
+		if err := s.HsRepo.RemoveSessionRecord(ctx, next.ID); err != nil {
+			return 0, fmt.Errorf("error removing session record for %s: %w", program, err)
+		}
+		if !cur.Notes.Valid {
+			cur.Notes = next.Notes
+		}
+
+		lifetimeDelta += duration - cur.DurationSeconds - next.DurationSeconds
+		idleDelta += idle - cur.IdleSeconds - next.IdleSeconds
+		cur.EndTime, cur.DurationSeconds, cur.IdleSeconds = end, duration, idle
+		merged++
+	}
+
+	if merged > 0 {
+		err := s.PrRepo.UpdateLifetime(ctx, database.UpdateLifetimeParams{
+			Name:            program,
+			LifetimeSeconds: lifetimeDelta,
+			IdleSeconds:     idleDelta,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("error updating lifetime for %s: %w", program, err)
+		}
+	}
+
+	return merged, nil
 }
 
-// Basic helper for formatting sessions printed in "history" command
-func printSession(session database.SessionHistory) {
-	duration := time.Duration(session.DurationSeconds) * time.Second
-	fmt.Printf("  %s | %s - %s | Duration: ",
-		session.ProgramName,
-		session.StartTime.Format("2006-01-02 15:04"),
-		session.EndTime.Format("2006-01-02 15:04"))
+// Reports whether two history rows are consecutive days of a session split at local midnight
+func splitAtMidnight(prev, next database.SessionHistory) bool {
+	return prev.EndTime.Equal(next.StartTime) && report.Day(next.StartTime).Start.Equal(next.StartTime)
+}
 
-	if duration < time.Minute {
-		fmt.Printf("%d seconds\n", int(duration.Seconds()))
-	} else if duration < time.Hour {
-		fmt.Printf("%d minutes\n", int(duration.Minutes()))
-	} else {
-		hours := int(duration.Hours())
-		minutes := int(duration.Minutes()) % 60
-		fmt.Printf("%dh %dm\n", hours, minutes)
+// Moves the tags of a session being merged away onto the session it's merged into, along with its notes if the other
+// session has none
+func (s *CLIService) moveSessionAnnotations(ctx context.Context, from, to database.SessionHistory) error {
+	fromTags, err := s.HsRepo.GetTagsForSession(ctx, from.ID)
+	if err != nil {
+		return fmt.Errorf("error getting tags of session %d: %w", from.ID, err)
+	}
+	for _, tag := range fromTags {
+		if err := s.HsRepo.AddSessionTag(ctx, database.AddSessionTagParams{SessionID: to.ID, Tag: tag}); err != nil {
+			return fmt.Errorf("error tagging session %d: %w", to.ID, err)
+		}
 	}
+	if err := s.HsRepo.RemoveTagsForSession(ctx, from.ID); err != nil {
+		return fmt.Errorf("error removing tags of session %d: %w", from.ID, err)
+	}
+
+	if !to.Notes.Valid && from.Notes.Valid {
+		if err := s.HsRepo.UpdateSessionNotes(ctx, database.UpdateSessionNotesParams{Notes: from.Notes, ID: to.ID}); err != nil {
+			return fmt.Errorf("error updating notes of session %d: %w", to.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// Parses a tag filter, an empty tag filters nothing
+func parseTagFilter(tag string) (sql.NullString, error) {
+	if tag == "" {
+		return sql.NullString{}, nil
+	}
+	normalized, ok := tags.Normalize(tag)
+	if !ok {
+		return sql.NullString{}, fmt.Errorf("invalid tag %q, tags may not contain spaces or commas", tag)
+	}
+	return sql.NullString{String: normalized, Valid: true}, nil
+}
+
+// Runs fn against a copy of the service whose repositories are bound to a single transaction, so its changes are applied
+// all together or not at all. Repositories without transaction support are used directly
+func (s *CLIService) withTx(ctx context.Context, fn func(tx *CLIService) error) error {
+	store, ok := s.HsRepo.(repository.Store)
+	if !ok {
+		return fn(s)
+	}
+
+	return store.WithTx(ctx, func(tx repository.Store) error {
+		txService := *s
+		txService.PrRepo, txService.AsRepo, txService.HsRepo = tx, tx, tx
+		return fn(&txService)
+	})
 }
 
 // Helper to save config and send refresh command to service
@@ -152,3 +596,118 @@ func (s *CLIService) saveAndNotify() error {
 	}
 	return nil
 }
+
+// Layout of session start and end times, shown in history and given to the session commands, in local time
+const sessionTimeLayout = "2006-01-02 15:04"
+
+// Parses a session start or end time given in local time
+func parseSessionTime(value string) (time.Time, error) {
+	t, err := time.ParseInLocation(sessionTimeLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid session time %q, expected format %s", value, sessionTimeLayout)
+	}
+	return t, nil
+}
+
+// Returns a history session by ID
+func (s *CLIService) getSessionRecord(ctx context.Context, id int64) (database.SessionHistory, error) {
+	session, err := s.HsRepo.GetSessionRecord(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.SessionHistory{}, fmt.Errorf("no session with ID %d", id)
+	} else if err != nil {
+		return database.SessionHistory{}, fmt.Errorf("error getting session %d: %w", id, err)
+	}
+	return session, nil
+}
+
+// Resolves the session a tag or annotate command targets, a history session when given its ID, otherwise the active
+// session of the named program. Returns the history session ID, or the program name and its active session
+func (s *CLIService) sessionTarget(ctx context.Context, target string) (int64, string, database.GetActiveSessionRow, error) {
+	if id, err := strconv.ParseInt(target, 10, 64); err == nil {
+		session, err := s.getSessionRecord(ctx, id)
+		if err != nil {
+			return 0, "", database.GetActiveSessionRow{}, err
+		}
+		return session.ID, "", database.GetActiveSessionRow{}, nil
+	}
+
+	program := programs.Normalize(target)
+	active, err := s.AsRepo.GetActiveSession(ctx, program)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, "", database.GetActiveSessionRow{}, fmt.Errorf("no active session for %s", program)
+	} else if err != nil {
+		return 0, "", database.GetActiveSessionRow{}, fmt.Errorf("error getting active session for %s: %w", program, err)
+	}
+
+	return 0, program, active, nil
+}
+
+// Validates a session's times, which must be in order, in the past, and not overlap the program's other sessions (other
+// than the session with excludeID, when editing) or its active session
+func (s *CLIService) checkSession(ctx context.Context, program string, start, end time.Time, excludeID int64) error {
+	if !end.After(start) {
+		return fmt.Errorf("session end must be after its start")
+	}
+	if end.After(time.Now()) {
+		return fmt.Errorf("session can't end in the future")
+	}
+
+	overlapping, err := s.HsRepo.GetOverlappingSessions(ctx, database.GetOverlappingSessionsParams{
+		ProgramName: program,
+		EndUnix:     end.Unix(),
+		StartUnix:   start.Unix(),
+		ExcludeID:   excludeID,
+	})
+	if err != nil {
+		return fmt.Errorf("error checking for overlapping sessions: %w", err)
+	}
+	if len(overlapping) > 0 {
+		other := overlapping[0]
+		return fmt.Errorf("session overlaps session #%d of %s (%s - %s)", other.ID, program,
+			other.StartTime.Local().Format(sessionTimeLayout), other.EndTime.Local().Format(sessionTimeLayout))
+	}
+
+	active, err := s.AsRepo.GetActiveSession(ctx, program)
+	if err == nil && active.StartTime.Before(end) {
+		return fmt.Errorf("session overlaps the active session of %s", program)
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("error getting active session for %s: %w", program, err)
+	}
+
+	return nil
+}
+
+// Recomputes a program's lifetime and idle time from its history sessions, after sessions are changed by hand
+func (s *CLIService) recomputeLifetime(ctx context.Context, program string) error {
+	if err := s.PrRepo.RecomputeLifetime(ctx, program); err != nil {
+		return fmt.Errorf("error recomputing lifetime for %s: %w", program, err)
+	}
+	return nil
+}
+
+// Returns the saved pauses still in effect, leaving out those that ran out while the service wasn't running to resume them
+func (s *CLIService) activePauses(ctx context.Context) ([]database.TrackingPause, error) {
+	pauses, err := s.PrRepo.GetAllPauses(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting paused programs: %w", err)
+	}
+
+	now := time.Now()
+	active := make([]database.TrackingPause, 0, len(pauses))
+	for _, pause := range pauses {
+		if pause.ResumeAt.Valid && !pause.ResumeAt.Time.After(now) {
+			continue
+		}
+		active = append(active, pause)
+	}
+
+	return active, nil
+}
+
+// Describes the programs covered by a pause, the empty program name pausing all of them
+func pauseTarget(programs ...string) string {
+	if len(programs) == 0 || programs[0] == "" {
+		return "all programs"
+	}
+	return strings.Join(programs, ", ")
+}
@@ -1,12 +1,20 @@
 package main_test
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	cli "github.com/jms-guy/timekeep/cmd/cli"
 	"github.com/jms-guy/timekeep/internal/database"
+	"github.com/jms-guy/timekeep/internal/report"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -31,7 +39,7 @@ func setupTestServiceWithPrograms(t *testing.T, programNames ...string) (*cli.CL
 }
 
 func createTestRecords(s *cli.CLIService, programName string) error {
-	err := s.HsRepo.AddToSessionHistory(context.Background(), database.AddToSessionHistoryParams{
+	_, err := s.HsRepo.AddToSessionHistory(context.Background(), database.AddToSessionHistoryParams{
 		ProgramName:     programName,
 		StartTime:       time.Now(),
 		EndTime:         time.Now().Add(time.Hour),
@@ -48,7 +56,7 @@ func TestAddPrograms(t *testing.T) {
 	}
 
 	programsToAdd := []string{"notepad.exe", "code.exe"}
-	err = s.AddPrograms(t.Context(), programsToAdd, "", "")
+	err = s.AddPrograms(t.Context(), programsToAdd, "", "", "", "", "", "")
 	assert.Nil(t, err, "AddPrograms should not return error")
 
 	addedPrograms, err := s.PrRepo.GetAllProgramNames(t.Context())
@@ -58,6 +66,92 @@ func TestAddPrograms(t *testing.T) {
 	assert.Len(t, addedPrograms, len(programsToAdd), "The repository should have the correct number of programs")
 }
 
+func TestAddPrograms_Match(t *testing.T) {
+	s, err := setupTestServiceWithPrograms(t)
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+
+	err = s.AddPrograms(t.Context(), []string{"Python3.*"}, "", "", "glob", "", "", "")
+	assert.Nil(t, err, "AddPrograms should not return error for valid glob")
+
+	err = s.AddPrograms(t.Context(), []string{`^Electron\d+\S*$`}, "", "", "regex", "", "", "")
+	assert.Nil(t, err, "AddPrograms should not return error for valid regex")
+
+	program, err := s.PrRepo.GetProgramByName(t.Context(), "python3.*")
+	assert.Nil(t, err, "Glob pattern should be stored lowercased")
+	assert.Equal(t, "glob", program.MatchMode)
+
+	program, err = s.PrRepo.GetProgramByName(t.Context(), `^electron\d+\S*$`)
+	assert.Nil(t, err, "Regex pattern should be stored lowercased, keeping the case of its escapes")
+	assert.Equal(t, "regex", program.MatchMode)
+
+	err = s.PauseTracking(t.Context(), []string{`^ELECTRON\d+\S*$`}, "")
+	assert.Nil(t, err, "Regex program should be found whatever the case it's given in")
+	info, err := s.GetInfo(t.Context(), []string{`^Electron\d+\S*$`}, "", "", "", "", "")
+	assert.Nil(t, err, "Regex program stats should be found whatever the case it's given in")
+	assert.Equal(t, `^electron\d+\S*$`, info.Name)
+
+	err = s.AddPrograms(t.Context(), []string{"python3.(["}, "", "", "regex", "", "", "")
+	assert.NotNil(t, err, "AddPrograms should reject invalid regex")
+
+	err = s.AddPrograms(t.Context(), []string{"python3"}, "", "", "fuzzy", "", "", "")
+	assert.NotNil(t, err, "AddPrograms should reject unknown match mode")
+}
+
+func TestAddPrograms_Argv(t *testing.T) {
+	s, err := setupTestServiceWithPrograms(t)
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+
+	err = s.AddPrograms(t.Context(), []string{"mytool"}, "", "", "", "Python", "-m mytool", "")
+	assert.Nil(t, err, "AddPrograms should not return error")
+
+	program, err := s.PrRepo.GetProgramByName(t.Context(), "mytool")
+	assert.Nil(t, err, "Logical program should be stored")
+	assert.Equal(t, "python", program.Exe.String)
+	assert.Equal(t, "-m mytool", program.ArgsContains.String)
+
+	err = s.AddPrograms(t.Context(), []string{"tool1", "tool2"}, "", "", "", "python", "", "")
+	assert.NotNil(t, err, "AddPrograms should reject multiple names with argv matcher")
+}
+
+func TestExcludeProcesses(t *testing.T) {
+	s, err := setupTestServiceWithPrograms(t, "chrome")
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+
+	err = s.ExcludeProcesses(t.Context(), "Chrome", []string{"--type=renderer", "--type=gpu-process"}, []string{"Chrome"}, nil, false)
+	assert.Nil(t, err, "ExcludeProcesses should not return error")
+
+	exclusions, err := s.PrRepo.GetExclusionsForProgram(t.Context(), "chrome")
+	assert.Nil(t, err)
+	assert.Len(t, exclusions, 3, "Should have stored 3 exclusion rules")
+
+	err = s.ExcludeProcesses(t.Context(), "chrome", nil, nil, []string{"/opt/crashpad/"}, true)
+	assert.Nil(t, err, "ExcludeProcesses should not return error")
+
+	exclusions, err = s.PrRepo.GetExclusionsForProgram(t.Context(), "chrome")
+	assert.Nil(t, err)
+	assert.Len(t, exclusions, 1, "Clear should replace existing rules")
+
+	err = s.ExcludeProcesses(t.Context(), "chrome", []string{""}, nil, nil, true)
+	assert.NotNil(t, err, "ExcludeProcesses should reject empty pattern")
+
+	exclusions, err = s.PrRepo.GetExclusionsForProgram(t.Context(), "chrome")
+	assert.Nil(t, err)
+	assert.Len(t, exclusions, 1, "Rejected rules should leave existing rules in place")
+
+	err = s.ExcludeProcesses(t.Context(), "firefox", []string{"-contentproc"}, nil, nil, false)
+	assert.NotNil(t, err, "ExcludeProcesses should reject untracked program")
+
+	list, err := s.GetExclusions(t.Context(), "Chrome")
+	assert.Nil(t, err, "GetExclusions should not return error")
+	assert.Equal(t, cli.ExclusionList{Program: "chrome", Rules: []cli.ExclusionRule{{Kind: "path", Pattern: "/opt/crashpad/"}}}, list)
+}
+
 func TestRemoveProgram(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -86,12 +180,24 @@ func TestRemoveProgram(t *testing.T) {
 				t.Fatalf("Failed to setup test service: %v", err)
 			}
 
+			err = s.PrRepo.AddExclusion(t.Context(), database.AddExclusionParams{ProgramName: "notepad.exe", Kind: "args", Pattern: "/safe"})
+			assert.Nil(t, err)
+			err = s.PrRepo.AddPause(t.Context(), database.AddPauseParams{ProgramName: "notepad.exe", PausedAt: time.Now()})
+			assert.Nil(t, err)
+
 			programToRemove := []string{"notepad.exe"}
 			err = s.RemovePrograms(t.Context(), programToRemove, tt.all)
 			assert.Nil(t, err, "RemovePrograms should not return err")
 
 			remainingPrograms, _ := s.PrRepo.GetAllProgramNames(t.Context())
 			assert.ElementsMatch(t, tt.expected, remainingPrograms, tt.expectedMsg)
+
+			exclusions, err := s.PrRepo.GetExclusionsForProgram(t.Context(), "notepad.exe")
+			assert.Nil(t, err)
+			assert.Empty(t, exclusions, "Removed program's exclusions should be removed")
+			pauses, err := s.PrRepo.GetAllPauses(t.Context())
+			assert.Nil(t, err)
+			assert.Empty(t, pauses, "Removed program's pause should be removed")
 		})
 	}
 }
@@ -102,8 +208,9 @@ func TestGetList(t *testing.T) {
 		t.Fatalf("Failed to setup test service: %v", err)
 	}
 
-	err = s.GetList(t.Context())
+	list, err := s.GetList(t.Context())
 	assert.Nil(t, err, "GetList should not return err")
+	assert.ElementsMatch(t, []string{"notepad.exe", "code.exe"}, list.Programs)
 }
 
 func TestGetList_Empty(t *testing.T) {
@@ -112,8 +219,10 @@ func TestGetList_Empty(t *testing.T) {
 		t.Fatalf("Failed to setup test service: %v", err)
 	}
 
-	err = s.GetList(t.Context())
+	list, err := s.GetList(t.Context())
 	assert.Nil(t, err, "GetList should not return err")
+	assert.NotNil(t, list.Programs, "Empty list should still be a list")
+	assert.Len(t, list.Programs, 0)
 }
 
 func TestGetAllStats(t *testing.T) {
@@ -122,8 +231,11 @@ func TestGetAllStats(t *testing.T) {
 		t.Fatalf("Failed to setup test service: %v", err)
 	}
 
-	err = s.GetAllInfo(t.Context())
+	summaries, err := s.GetAllInfo(t.Context(), "", "", "", "", "")
 	assert.Nil(t, err, "GetAllStats should not err")
+	if assert.Len(t, summaries.Programs, 2) {
+		assert.Equal(t, int64(1), summaries.Programs[0].Sessions)
+	}
 }
 
 func TestGetAllStats_Empty(t *testing.T) {
@@ -132,8 +244,9 @@ func TestGetAllStats_Empty(t *testing.T) {
 		t.Fatalf("Failed to setup test service: %v", err)
 	}
 
-	err = s.GetAllInfo(t.Context())
+	summaries, err := s.GetAllInfo(t.Context(), "", "", "", "", "")
 	assert.Nil(t, err, "GetAllStats should not err")
+	assert.Len(t, summaries.Programs, 0)
 }
 
 func TestGetStats(t *testing.T) {
@@ -142,8 +255,12 @@ func TestGetStats(t *testing.T) {
 		t.Fatalf("Failed to setup test service: %v", err)
 	}
 
-	err = s.GetInfo(t.Context(), []string{"notepad.exe"})
+	info, err := s.GetInfo(t.Context(), []string{"notepad.exe"}, "", "", "", "", "")
 	assert.Nil(t, err, "GetStats should not err")
+	assert.Equal(t, "notepad.exe", info.Name)
+	assert.Equal(t, int64(1), info.Sessions)
+	assert.Equal(t, int64(3600), info.AverageSessionSeconds)
+	assert.NotNil(t, info.LastSession)
 }
 
 func TestGetSessionHistory(t *testing.T) {
@@ -152,8 +269,165 @@ func TestGetSessionHistory(t *testing.T) {
 		t.Fatalf("Failed to setup test service: %v", err)
 	}
 
-	err = s.GetSessionHistory(t.Context(), []string{"code.exe"}, "", "", "", 25)
+	history, err := s.GetSessionHistory(t.Context(), []string{"code.exe"}, "", "", "", "", "", "", 25)
+	assert.Nil(t, err, "GetSessionHistory should not err")
+	if assert.Len(t, history.Sessions, 1) {
+		assert.Equal(t, "code.exe", history.Sessions[0].Program)
+		assert.Equal(t, int64(3600), history.Sessions[0].DurationSeconds)
+	}
+	assert.Nil(t, history.TotalSeconds, "Total should only be given for a date filter")
+}
+
+func TestGetSessionHistory_MinDuration(t *testing.T) {
+	s, err := setupTestServiceWithPrograms(t, "code.exe")
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+
+	_, err = s.HsRepo.AddToSessionHistory(t.Context(), database.AddToSessionHistoryParams{
+		ProgramName:     "code.exe",
+		StartTime:       time.Now().Add(-2 * time.Second),
+		EndTime:         time.Now(),
+		DurationSeconds: 2,
+	})
+	assert.Nil(t, err)
+
+	history, err := s.GetSessionHistory(t.Context(), []string{"code.exe"}, "", "", "", "", "10s", "", 25)
+	assert.Nil(t, err, "GetSessionHistory should not err")
+	assert.Len(t, history.Sessions, 1, "Short session should be filtered out")
+	info, err := s.GetInfo(t.Context(), []string{"code.exe"}, "", "", "", "10s", "")
+	assert.Nil(t, err, "GetInfo should not err")
+	assert.Equal(t, int64(1), info.Sessions, "Info should only count sessions at least the minimum")
+	_, err = s.GetSessionHistory(t.Context(), []string{"code.exe"}, "", "", "", "", "ten", "", 25)
+	assert.NotNil(t, err, "Invalid minimum duration should err")
+	_, err = s.GetSessionHistory(t.Context(), []string{"code.exe"}, "", "", "", "", "1.5s", "", 25)
+	assert.NotNil(t, err, "Minimum duration with a fraction of a second should err")
+
+	stats, _ := s.HsRepo.GetSessionStatsForProgram(t.Context(), database.GetSessionStatsForProgramParams{ProgramName: "code.exe", MinDuration: 10})
+	assert.Equal(t, int64(1), stats.Count, "Stats should only count sessions at least the minimum")
+	assert.Equal(t, int64(3600), stats.TotalSeconds)
+}
+
+func TestGetSessionHistory_TotalPastLimit(t *testing.T) {
+	s, err := setupTestServiceWithPrograms(t, "code.exe")
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+
+	yesterday := report.Day(time.Now()).Start.AddDate(0, 0, -1)
+	for i := range 10 {
+		start := yesterday.Add(time.Duration(i) * time.Hour)
+		_, err = s.HsRepo.AddToSessionHistory(t.Context(), database.AddToSessionHistoryParams{
+			ProgramName:     "code.exe",
+			StartTime:       start,
+			EndTime:         start.Add(30 * time.Minute),
+			DurationSeconds: 1800,
+		})
+		assert.Nil(t, err)
+	}
+
+	history, err := s.GetSessionHistory(t.Context(), nil, "yesterday", "", "", "", "", "", 3)
 	assert.Nil(t, err, "GetSessionHistory should not err")
+	assert.Len(t, history.Sessions, 3)
+	if assert.NotNil(t, history.TotalSeconds) {
+		assert.Equal(t, int64(18000), *history.TotalSeconds, "Total should count sessions past the limit")
+	}
+
+	history, err = s.GetSessionHistory(t.Context(), []string{"code.exe"}, "yesterday", "", "", "", "31m", "", 3)
+	assert.Nil(t, err, "GetSessionHistory should not err")
+	if assert.NotNil(t, history.TotalSeconds) {
+		assert.Equal(t, int64(0), *history.TotalSeconds, "Total should only count sessions passing the filters")
+	}
+}
+
+func TestGetSessionHistory_Period(t *testing.T) {
+	s, err := setupTestServiceWithPrograms(t)
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+	for _, name := range []string{"code.exe", "notepad.exe"} {
+		err = s.PrRepo.AddProgram(t.Context(), database.AddProgramParams{Name: name})
+		assert.Nil(t, err)
+	}
+
+	today := report.Day(time.Now()).Start
+	yesterday := today.AddDate(0, 0, -1)
+	tenDaysAgo := today.AddDate(0, 0, -10)
+	sessions := []database.AddToSessionHistoryParams{
+		{ProgramName: "code.exe", StartTime: tenDaysAgo.Add(10 * time.Hour), EndTime: tenDaysAgo.Add(12 * time.Hour), DurationSeconds: 7200},
+		{ProgramName: "code.exe", StartTime: yesterday.Add(10 * time.Hour), EndTime: yesterday.Add(11 * time.Hour), DurationSeconds: 3600},
+		{ProgramName: "notepad.exe", StartTime: yesterday.Add(23 * time.Hour), EndTime: today.Add(time.Hour), DurationSeconds: 7200, IdleSeconds: 1800}, // Runs past midnight
+	}
+	for _, session := range sessions {
+		_, err = s.HsRepo.AddToSessionHistory(t.Context(), session)
+		assert.Nil(t, err)
+	}
+
+	utc := func(t time.Time) string { return t.UTC().Format(time.RFC3339) }
+	tests := []struct {
+		name       string
+		program    string
+		date       string
+		start      string
+		end        string
+		sessions   int
+		total      int64
+		unfiltered bool
+	}{
+		{name: "All", sessions: 3, unfiltered: true},
+		{name: "Yesterday", date: "yesterday", sessions: 2, total: 7200},
+		{name: "Yesterday of a program", program: "notepad.exe", date: "Yesterday", sessions: 1, total: 3600},
+		{name: "Date", date: tenDaysAgo.Format(report.DateLayout), sessions: 1, total: 7200},
+		{name: "Relative", date: "-3d", sessions: 2},
+		{name: "Start", start: "-14d", sessions: 3},
+		{name: "Start and end", start: "-14d", end: "-5d", sessions: 1, total: 7200},
+		{name: "Timestamps with time zone", start: utc(yesterday.Add(10*time.Hour + 30*time.Minute)), end: utc(yesterday.Add(10*time.Hour + 45*time.Minute)), sessions: 1, total: 900},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args []string
+			if tt.program != "" {
+				args = []string{tt.program}
+			}
+
+			history, err := s.GetSessionHistory(t.Context(), args, tt.date, tt.start, tt.end, "", "", "", 25)
+			assert.Nil(t, err, "GetSessionHistory should not err")
+			assert.Len(t, history.Sessions, tt.sessions)
+			if tt.unfiltered {
+				assert.Nil(t, history.TotalSeconds)
+			} else if assert.NotNil(t, history.TotalSeconds) && tt.total != 0 {
+				assert.Equal(t, tt.total, *history.TotalSeconds, "Total should only count time inside the period")
+			}
+		})
+	}
+
+	info, err := s.GetInfo(t.Context(), []string{"notepad.exe"}, "today", "", "", "", "")
+	assert.Nil(t, err, "GetInfo should not err")
+	assert.Equal(t, int64(1), info.Sessions)
+	assert.Equal(t, int64(3600), info.AverageSessionSeconds, "Info should only count time inside the period")
+
+	summaries, err := s.GetAllInfo(t.Context(), "last week", "", "", "", "")
+	assert.Nil(t, err, "GetAllInfo should not err")
+	assert.Len(t, summaries.Programs, 2)
+
+	summaries, err = s.GetAllInfo(t.Context(), "today", "", "", "", "")
+	assert.Nil(t, err, "GetAllInfo should not err")
+	for _, summary := range summaries.Programs {
+		if summary.Name == "notepad.exe" {
+			assert.Equal(t, int64(3600), summary.TotalSeconds)
+			assert.Equal(t, int64(900), summary.IdleSeconds, "Idle time should be prorated to the time inside the period")
+		}
+	}
+
+	stats, err := s.HsRepo.GetSessionStatsForProgram(t.Context(), database.GetSessionStatsForProgramParams{ProgramName: "notepad.exe"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1800), stats.IdleSeconds, "Idle time without a period should be counted in full")
+
+	for _, filters := range [][3]string{{"today", "-1d", ""}, {"", "", "today"}, {"someday", "", ""}, {"", "today", "-3d"}} {
+		_, err = s.GetSessionHistory(t.Context(), nil, filters[0], filters[1], filters[2], "", "", "", 25)
+		assert.NotNil(t, err, "Filters %q should err", filters)
+	}
 }
 
 func TestResetStats(t *testing.T) {
@@ -198,7 +472,7 @@ func TestResetAllDatabase(t *testing.T) {
 	remainingPrograms, _ := s.PrRepo.GetAllProgramNames(t.Context())
 	assert.Len(t, remainingPrograms, 2, "after reset, programs should be unaffected")
 
-	allHistory, _ := s.HsRepo.GetSessionHistory(t.Context(), database.GetSessionHistoryParams{ProgramName: "notepad.exe", Limit: 25})
+	allHistory, _ := s.HsRepo.GetFilteredSessionHistory(t.Context(), database.GetFilteredSessionHistoryParams{ProgramName: "notepad.exe", Limit: 25})
 	assert.Len(t, allHistory, 0, "after reset, there should be no session history")
 }
 
@@ -211,10 +485,225 @@ func TestResetDatabaseForProgram(t *testing.T) {
 	err = s.ResetDatabaseForProgram(t.Context(), "code.exe")
 	assert.Nil(t, err, "ResetDatabaseForProgram should not err")
 
-	history, _ := s.HsRepo.GetSessionHistory(t.Context(), database.GetSessionHistoryParams{ProgramName: "code.exe", Limit: 25})
+	history, _ := s.HsRepo.GetFilteredSessionHistory(t.Context(), database.GetFilteredSessionHistoryParams{ProgramName: "code.exe", Limit: 25})
 	assert.Len(t, history, 0, "after reset, there should be no session history")
 }
 
+func TestMergeHistory(t *testing.T) {
+	s, err := setupTestServiceWithPrograms(t, "code.exe")
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+	err = s.ResetDatabaseForProgram(t.Context(), "code.exe")
+	assert.Nil(t, err)
+
+	base := time.Date(2025, 9, 30, 9, 0, 0, 0, time.Local)
+	for _, session := range [][2]time.Duration{{0, time.Hour}, {time.Hour + 10*time.Second, 2 * time.Hour}, {3 * time.Hour, 4 * time.Hour}} {
+		_, err := s.HsRepo.AddToSessionHistory(t.Context(), database.AddToSessionHistoryParams{
+			ProgramName:     "code.exe",
+			StartTime:       base.Add(session[0]),
+			EndTime:         base.Add(session[1]),
+			DurationSeconds: int64((session[1] - session[0]).Seconds()),
+		})
+		assert.Nil(t, err)
+		err = s.PrRepo.UpdateLifetime(t.Context(), database.UpdateLifetimeParams{Name: "code.exe", LifetimeSeconds: int64((session[1] - session[0]).Seconds())})
+		assert.Nil(t, err)
+	}
+
+	summary, err := s.MergeHistory(t.Context(), []string{"code.exe"}, "30s")
+	assert.Nil(t, err, "MergeHistory should not err")
+	assert.Equal(t, []cli.MergedProgram{{Program: "code.exe", Merged: 1}}, summary.Programs)
+
+	history, _ := s.HsRepo.GetAllSessionsForProgram(t.Context(), "code.exe")
+	if assert.Len(t, history, 2, "Sessions 10s apart should be merged, the hour gap kept") {
+		assert.Equal(t, int64(2*60*60), history[0].DurationSeconds, "Merged session should span both sessions")
+		assert.Equal(t, int64(10), history[0].IdleSeconds, "Gap should count as idle time")
+	}
+
+	program, _ := s.PrRepo.GetProgramByName(t.Context(), "code.exe")
+	assert.Equal(t, int64(3*60*60), program.LifetimeSeconds, "Lifetime should gain the merged gap")
+
+	_, err = s.MergeHistory(t.Context(), []string{"code.exe"}, "soon")
+	assert.NotNil(t, err, "Invalid gap should err")
+}
+
+func TestMergeHistory_SplitDays(t *testing.T) {
+	s, err := setupTestServiceWithPrograms(t, "code.exe")
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+	err = s.ResetDatabaseForProgram(t.Context(), "code.exe")
+	assert.Nil(t, err)
+
+	midnight := time.Date(2025, 10, 1, 0, 0, 0, 0, time.Local)
+	for _, session := range [][2]time.Time{
+		{midnight.Add(-time.Hour), midnight},                                    // Split at midnight by the service
+		{midnight, midnight.Add(time.Hour)},                                     // from one session
+		{midnight.Add(time.Hour + 10*time.Second), midnight.Add(2 * time.Hour)}, // Restarted 10s later
+	} {
+		_, err := s.HsRepo.AddToSessionHistory(t.Context(), database.AddToSessionHistoryParams{
+			ProgramName:     "code.exe",
+			StartTime:       session[0],
+			EndTime:         session[1],
+			DurationSeconds: int64(session[1].Sub(session[0]).Seconds()),
+		})
+		assert.Nil(t, err)
+	}
+
+	s.Config.SplitDays = true
+	_, err = s.MergeHistory(t.Context(), []string{"code.exe"}, "30s")
+	assert.Nil(t, err, "MergeHistory should not err")
+
+	history, _ := s.HsRepo.GetAllSessionsForProgram(t.Context(), "code.exe")
+	if assert.Len(t, history, 2, "Rows split at midnight should be kept apart, the restart merged") {
+		assert.True(t, midnight.Equal(history[0].EndTime), "Day before midnight should end at midnight")
+		assert.True(t, midnight.Equal(history[1].StartTime), "Day after midnight should start at midnight")
+		assert.True(t, midnight.Add(2*time.Hour).Equal(history[1].EndTime), "Restarted session should be merged into the day's row")
+	}
+
+	s.Config.SplitDays = false
+	_, err = s.MergeHistory(t.Context(), []string{"code.exe"}, "30s")
+	assert.Nil(t, err)
+
+	history, _ = s.HsRepo.GetAllSessionsForProgram(t.Context(), "code.exe")
+	assert.Len(t, history, 1, "Without split_days rows meeting at midnight should be merged")
+}
+
+func TestGetReport(t *testing.T) {
+	s, err := setupTestServiceWithPrograms(t)
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+	assert.Nil(t, s.PrRepo.AddProgram(t.Context(), database.AddProgramParams{Name: "code", Category: sql.NullString{String: "dev", Valid: true}}))
+	assert.Nil(t, s.PrRepo.AddProgram(t.Context(), database.AddProgramParams{Name: "firefox"}))
+
+	day := time.Date(2025, 9, 30, 0, 0, 0, 0, time.Local)
+	addSession := func(program string, start, end time.Time) int64 {
+		id, err := s.HsRepo.AddToSessionHistory(t.Context(), database.AddToSessionHistoryParams{
+			ProgramName:     program,
+			StartTime:       start,
+			EndTime:         end,
+			DurationSeconds: int64(end.Sub(start).Seconds()),
+		})
+		assert.Nil(t, err)
+		return id
+	}
+	overnight := addSession("code", day.Add(-2*time.Hour), day.Add(2*time.Hour)) // Half inside the day
+	assert.Nil(t, s.HsRepo.AddSessionTag(t.Context(), database.AddSessionTagParams{SessionID: overnight, Tag: "work"}))
+	elsewhere := time.FixedZone("UTC-5", -5*60*60) // Stored with another offset, the same instant
+	addSession("firefox", day.Add(10*time.Hour+30*time.Minute).In(elsewhere), day.Add(12*time.Hour).In(elsewhere))
+	addSession("code", day.AddDate(0, 0, 1).Add(9*time.Hour), day.AddDate(0, 0, 1).Add(10*time.Hour)) // Next day
+
+	result, err := s.GetReport(t.Context(), "day", "program", "2025-09-30", "", "")
+	assert.Nil(t, err, "GetReport should not err")
+	assert.Equal(t, day, result.Start)
+	assert.Equal(t, int64(2*60*60+90*60), result.TotalSeconds, "Only time inside the day should count")
+	assert.Equal(t, int64(2), result.Sessions)
+	if assert.Len(t, result.Groups, 2) {
+		assert.Equal(t, cli.ReportGroup{Name: "code", Sessions: 1, TotalSeconds: 2 * 60 * 60, Share: 57.1, PeriodShare: 8.3}, result.Groups[0])
+		assert.Equal(t, "firefox", result.Groups[1].Name)
+		assert.Equal(t, int64(90*60), result.Groups[1].TotalSeconds)
+	}
+
+	result, err = s.GetReport(t.Context(), "day", "category", "2025-09-30", "", "")
+	assert.Nil(t, err)
+	if assert.Len(t, result.Groups, 2) {
+		assert.Equal(t, "dev", result.Groups[0].Name, "Sessions should take their program's category")
+		assert.Equal(t, "", result.Groups[1].Name)
+	}
+
+	result, err = s.GetReport(t.Context(), "day", "tag", "2025-09-30", "", "")
+	assert.Nil(t, err)
+	if assert.Len(t, result.Groups, 2) {
+		assert.Equal(t, "work", result.Groups[0].Name)
+		assert.Equal(t, "", result.Groups[1].Name, "Untagged sessions should be grouped together")
+	}
+
+	result, err = s.GetReport(t.Context(), "day", "hour", "2025-09-30", "", "")
+	assert.Nil(t, err)
+	if assert.Len(t, result.Groups, 24) {
+		assert.Equal(t, int64(60*60), result.Groups[0].TotalSeconds)
+		assert.Equal(t, int64(60*60), result.Groups[1].TotalSeconds)
+		assert.Equal(t, int64(30*60), result.Groups[10].TotalSeconds, "Session should be split across the hours it runs into")
+		assert.Equal(t, int64(60*60), result.Groups[11].TotalSeconds)
+		assert.Equal(t, int64(1), result.Groups[11].Sessions)
+	}
+
+	result, err = s.GetReport(t.Context(), "week", "weekday", "2025-09-30", "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(6*60*60+30*60), result.TotalSeconds, "Overnight session should count in full within the week")
+	if assert.Len(t, result.Groups, 7) {
+		assert.Equal(t, cli.ReportGroup{Name: "Monday", Sessions: 1, TotalSeconds: 2 * 60 * 60, Share: 30.8, PeriodShare: 1.2}, result.Groups[0])
+		assert.Equal(t, int64(3*60*60+30*60), result.Groups[1].TotalSeconds)
+		assert.Equal(t, int64(60*60), result.Groups[2].TotalSeconds)
+		assert.Equal(t, "Sunday", result.Groups[6].Name)
+	}
+
+	result, err = s.GetReport(t.Context(), "custom", "program", "", "2025-10-01", "2025-10-01")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(60*60), result.TotalSeconds)
+
+	_, err = s.GetReport(t.Context(), "day", "colour", "", "", "")
+	assert.NotNil(t, err, "Invalid group should err")
+	_, err = s.GetReport(t.Context(), "year", "program", "", "", "")
+	assert.NotNil(t, err, "Invalid period should err")
+	_, err = s.GetReport(t.Context(), "custom", "program", "", "", "")
+	assert.NotNil(t, err, "Custom period without a start should err")
+	_, err = s.GetReport(t.Context(), "week", "program", "", "2025-10-01", "")
+	assert.NotNil(t, err, "Start date outside a custom period should err")
+}
+
+func TestChart(t *testing.T) {
+	s, err := setupTestServiceWithPrograms(t, "code.exe")
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+	t.Setenv("COLUMNS", "60")
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		cmd := s.RootCmd()
+		cmd.SetOut(&out)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(append([]string{"chart"}, args...))
+		err := cmd.ExecuteContext(t.Context())
+		return out.String(), err
+	}
+
+	out, err := run()
+	assert.Nil(t, err, "Bar chart should not err")
+	assert.Contains(t, out, "code.exe")
+	assert.Contains(t, out, "█")
+	assert.NotContains(t, out, "\x1b[", "Output to a non-terminal shouldn't be colored")
+	for _, line := range strings.Split(out, "\n") {
+		assert.LessOrEqual(t, len([]rune(line)), 60, "Chart should fit $COLUMNS")
+	}
+
+	out, err = run("calendar", "--ascii")
+	assert.Nil(t, err)
+	assert.Contains(t, out, "#", "Today's session should be shaded")
+	assert.NotContains(t, out, "·")
+
+	out, err = run("calendar", "--output", "json")
+	assert.Nil(t, err)
+	var calendar struct {
+		Days []struct {
+			Date         string `json:"date"`
+			TotalSeconds int64  `json:"total_seconds"`
+		} `json:"days"`
+	}
+	if assert.Nil(t, json.Unmarshal([]byte(out), &calendar)) && assert.NotEmpty(t, calendar.Days) {
+		assert.Equal(t, time.Now().Format("2006-01-02"), calendar.Days[len(calendar.Days)-1].Date, "Calendar should end today")
+	}
+
+	out, err = run("hours", "--period", "week", "--output", "tsv")
+	assert.Nil(t, err)
+	assert.Len(t, strings.Split(strings.TrimSpace(out), "\n"), 1+7*24, "Hour chart should hold every hour of the week")
+
+	_, err = run("pie")
+	assert.NotNil(t, err, "Unknown chart should err")
+}
+
 func TestPingService(t *testing.T) {
 	s, err := setupTestServiceWithPrograms(t, "notepad.exe", "code.exe")
 	if err != nil {
@@ -232,6 +721,355 @@ func TestGetActiveSessions(t *testing.T) {
 		t.Fatalf("Failed to setup test service: %v", err)
 	}
 
-	err = s.GetActiveSessions(t.Context())
+	active, err := s.GetActiveSessions(t.Context())
 	assert.Nil(t, err, "GetActiveSessions should not err")
+	assert.Len(t, active.Sessions, 0)
+}
+
+func TestStartTimer(t *testing.T) {
+	s, err := setupTestServiceWithPrograms(t, "code.exe")
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+
+	err = s.StartTimer(t.Context(), "Standup", "meeting", "")
+	assert.Nil(t, err, "StartTimer should not err")
+
+	err = s.StartTimer(t.Context(), "code.exe", "meeting", "")
+	assert.NotNil(t, err, "Timer labelled as a tracked program should err")
+
+	err = s.AsRepo.CreateActiveSession(t.Context(), database.CreateActiveSessionParams{ProgramName: "standup", StartTime: time.Now(), Manual: true})
+	assert.Nil(t, err)
+
+	err = s.StartTimer(t.Context(), "standup", "meeting", "")
+	assert.NotNil(t, err, "Starting a running timer should err")
+}
+
+func TestStopTimer(t *testing.T) {
+	s, err := setupTestServiceWithPrograms(t, "code.exe")
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+
+	err = s.StopTimer(t.Context(), nil)
+	assert.NotNil(t, err, "StopTimer should err with no timers running")
+
+	err = s.AsRepo.CreateActiveSession(t.Context(), database.CreateActiveSessionParams{ProgramName: "code.exe", StartTime: time.Now()})
+	assert.Nil(t, err)
+	err = s.StopTimer(t.Context(), []string{"code.exe"})
+	assert.NotNil(t, err, "Process sessions shouldn't be stopped as timers")
+
+	err = s.AsRepo.CreateActiveSession(t.Context(), database.CreateActiveSessionParams{ProgramName: "standup", StartTime: time.Now(), Manual: true})
+	assert.Nil(t, err)
+
+	err = s.StopTimer(t.Context(), []string{"Standup"})
+	assert.Nil(t, err, "StopTimer should not err for a running timer")
+	err = s.StopTimer(t.Context(), nil)
+	assert.Nil(t, err, "StopTimer should stop all timers with no label given")
+}
+
+func TestAddSession(t *testing.T) {
+	s, err := setupTestServiceWithPrograms(t, "code.exe")
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+
+	day := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	err = s.AddSession(t.Context(), "code.exe", day+" 10:00", day+" 11:30", "timekeep")
+	assert.Nil(t, err, "AddSession should not err")
+
+	history, _ := s.HsRepo.GetAllSessionsForProgram(t.Context(), "code.exe")
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, int64(90*60), history[0].DurationSeconds)
+		assert.True(t, history[0].Manual, "Added session should be flagged manual")
+	}
+
+	program, _ := s.PrRepo.GetProgramByName(t.Context(), "code.exe")
+	assert.Equal(t, int64(60*60+90*60), program.LifetimeSeconds, "Lifetime should be recomputed from history")
+
+	err = s.AddSession(t.Context(), "code.exe", day+" 11:00", day+" 12:00", "")
+	assert.NotNil(t, err, "Overlapping session should err")
+	err = s.AddSession(t.Context(), "code.exe", day+" 12:00", day+" 11:00", "")
+	assert.NotNil(t, err, "Session ending before its start should err")
+	err = s.AddSession(t.Context(), "code.exe", day, day+" 11:00", "")
+	assert.NotNil(t, err, "Malformed time should err")
+
+	err = s.AddSession(t.Context(), "typo.exe", day+" 13:00", day+" 14:00", "")
+	assert.ErrorContains(t, err, "not being tracked", "Untracked program should err")
+	history, _ = s.HsRepo.GetAllSessionsForProgram(t.Context(), "typo.exe")
+	assert.Empty(t, history, "No session should be added for an untracked program")
+}
+
+func TestEditSession(t *testing.T) {
+	s, err := setupTestServiceWithPrograms(t, "code.exe")
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+
+	day := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	err = s.AddSession(t.Context(), "code.exe", day+" 10:00", day+" 11:00", "")
+	assert.Nil(t, err)
+	err = s.AddSession(t.Context(), "code.exe", day+" 12:00", day+" 13:00", "")
+	assert.Nil(t, err)
+
+	history, _ := s.HsRepo.GetAllSessionsForProgram(t.Context(), "code.exe")
+	first, second := history[0], history[1]
+
+	err = s.EditSession(t.Context(), first.ID, "", day+" 12:30", "")
+	assert.NotNil(t, err, "Edit overlapping another session should err")
+
+	err = s.EditSession(t.Context(), first.ID, day+" 09:00", "", "timekeep")
+	assert.Nil(t, err, "EditSession should not err")
+	edited, _ := s.HsRepo.GetSessionRecord(t.Context(), first.ID)
+	assert.Equal(t, int64(2*60*60), edited.DurationSeconds, "Duration should follow the new start")
+	assert.Equal(t, "timekeep", edited.Project.String)
+
+	err = s.EditSession(t.Context(), second.ID, day+" 11:00", "", "")
+	assert.Nil(t, err, "Session may start where another ends")
+
+	program, _ := s.PrRepo.GetProgramByName(t.Context(), "code.exe")
+	assert.Equal(t, int64(60*60+2*60*60+2*60*60), program.LifetimeSeconds, "Lifetime should be recomputed from history")
+
+	err = s.EditSession(t.Context(), 999, day+" 11:00", "", "")
+	assert.NotNil(t, err, "Editing a missing session should err")
+}
+
+func TestRemoveSession(t *testing.T) {
+	s, err := setupTestServiceWithPrograms(t, "code.exe")
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+
+	history, _ := s.HsRepo.GetAllSessionsForProgram(t.Context(), "code.exe")
+	id := history[0].ID
+	err = s.RemoveSession(t.Context(), id)
+	assert.Nil(t, err, "RemoveSession should not err")
+
+	history, _ = s.HsRepo.GetAllSessionsForProgram(t.Context(), "code.exe")
+	assert.Len(t, history, 0)
+	program, _ := s.PrRepo.GetProgramByName(t.Context(), "code.exe")
+	assert.Equal(t, int64(0), program.LifetimeSeconds, "Lifetime should be recomputed from history")
+
+	err = s.RemoveSession(t.Context(), id)
+	assert.NotNil(t, err, "Removing a missing session should err")
+}
+
+func TestTagSession(t *testing.T) {
+	s, err := setupTestServiceWithPrograms(t, "code.exe")
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+
+	history, _ := s.HsRepo.GetAllSessionsForProgram(t.Context(), "code.exe")
+	id := strconv.FormatInt(history[0].ID, 10)
+
+	err = s.TagSession(t.Context(), id, []string{"Client-A", "review"}, false)
+	assert.Nil(t, err, "TagSession should not err")
+	sessionTags, _ := s.HsRepo.GetTagsForSession(t.Context(), history[0].ID)
+	assert.Equal(t, []string{"client-a", "review"}, sessionTags, "Tags should be lowercased")
+
+	err = s.TagSession(t.Context(), id, []string{"review"}, true)
+	assert.Nil(t, err, "TagSession should not err removing a tag")
+	sessionTags, _ = s.HsRepo.GetTagsForSession(t.Context(), history[0].ID)
+	assert.Equal(t, []string{"client-a"}, sessionTags)
+
+	tagged, _ := s.HsRepo.GetFilteredSessionHistory(t.Context(), database.GetFilteredSessionHistoryParams{
+		ProgramName: "code.exe",
+		Tag:         sql.NullString{String: "client-a", Valid: true},
+		Limit:       25,
+	})
+	assert.Len(t, tagged, 1, "History should filter by tag")
+	untagged, _ := s.HsRepo.GetFilteredSessionHistory(t.Context(), database.GetFilteredSessionHistoryParams{
+		ProgramName: "code.exe",
+		Tag:         sql.NullString{String: "review", Valid: true},
+		Limit:       25,
+	})
+	assert.Len(t, untagged, 0, "Removed tags shouldn't match")
+
+	list, err := s.GetSessionHistory(t.Context(), []string{"code.exe"}, "", "", "", "", "", "Client-A", 25)
+	assert.Nil(t, err, "GetSessionHistory should not err with a tag filter")
+	if assert.Len(t, list.Sessions, 1) {
+		assert.Equal(t, []string{"client-a"}, list.Sessions[0].Tags)
+	}
+	_, err = s.GetSessionHistory(t.Context(), []string{"code.exe"}, "", "", "", "", "", "two words", 25)
+	assert.NotNil(t, err, "Invalid tag filter should err")
+
+	err = s.AsRepo.CreateActiveSession(t.Context(), database.CreateActiveSessionParams{ProgramName: "code.exe", StartTime: time.Now()})
+	assert.Nil(t, err)
+	err = s.TagSession(t.Context(), "Code.exe", []string{"focus", "client-a", "focus"}, false)
+	assert.Nil(t, err, "TagSession should not err for an active session")
+	active, _ := s.AsRepo.GetActiveSession(t.Context(), "code.exe")
+	assert.Equal(t, "client-a,focus", active.Tags.String, "Active session tags should be sorted and unique")
+
+	err = s.TagSession(t.Context(), id, []string{"a,b"}, false)
+	assert.NotNil(t, err, "Tags with commas should err")
+	err = s.TagSession(t.Context(), "999", []string{"focus"}, false)
+	assert.NotNil(t, err, "Tagging a missing session should err")
+	err = s.TagSession(t.Context(), "notepad.exe", []string{"focus"}, false)
+	assert.NotNil(t, err, "Tagging a program with no active session should err")
+}
+
+func TestAnnotateSession(t *testing.T) {
+	s, err := setupTestServiceWithPrograms(t, "code.exe")
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+
+	history, _ := s.HsRepo.GetAllSessionsForProgram(t.Context(), "code.exe")
+	id := strconv.FormatInt(history[0].ID, 10)
+
+	err = s.AnnotateSession(t.Context(), id, "Fixed the login bug")
+	assert.Nil(t, err, "AnnotateSession should not err")
+	session, _ := s.HsRepo.GetSessionRecord(t.Context(), history[0].ID)
+	assert.Equal(t, "Fixed the login bug", session.Notes.String)
+
+	err = s.AnnotateSession(t.Context(), id, "")
+	assert.Nil(t, err)
+	session, _ = s.HsRepo.GetSessionRecord(t.Context(), history[0].ID)
+	assert.False(t, session.Notes.Valid, "Empty notes should clear them")
+
+	err = s.AsRepo.CreateActiveSession(t.Context(), database.CreateActiveSessionParams{ProgramName: "code.exe", StartTime: time.Now()})
+	assert.Nil(t, err)
+	err = s.AnnotateSession(t.Context(), "code.exe", "Pairing")
+	assert.Nil(t, err, "AnnotateSession should not err for an active session")
+	active, _ := s.AsRepo.GetActiveSession(t.Context(), "code.exe")
+	assert.Equal(t, "Pairing", active.Notes.String)
+}
+
+func TestPauseTracking(t *testing.T) {
+	s, err := setupTestServiceWithPrograms(t, "code.exe")
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+
+	err = s.PauseTracking(t.Context(), nil, "")
+	assert.Nil(t, err, "PauseTracking should not err for all programs")
+
+	err = s.PauseTracking(t.Context(), []string{"Code.exe"}, "30m")
+	assert.Nil(t, err, "PauseTracking should not err for a tracked program")
+
+	err = s.PauseTracking(t.Context(), []string{"notepad.exe"}, "")
+	assert.NotNil(t, err, "Pausing an untracked program should err")
+
+	err = s.PauseTracking(t.Context(), nil, "soon")
+	assert.NotNil(t, err, "Invalid duration should err")
+	err = s.PauseTracking(t.Context(), nil, "-5m")
+	assert.NotNil(t, err, "Negative duration should err")
+}
+
+func TestResumeTracking(t *testing.T) {
+	s, err := setupTestServiceWithPrograms(t, "code.exe", "notepad.exe")
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+
+	err = s.ResumeTracking(t.Context(), nil)
+	assert.NotNil(t, err, "ResumeTracking should err when nothing is paused")
+
+	err = s.PrRepo.AddPause(t.Context(), database.AddPauseParams{ProgramName: "code.exe", PausedAt: time.Now()})
+	assert.Nil(t, err)
+	err = s.PrRepo.AddPause(t.Context(), database.AddPauseParams{
+		ProgramName: "notepad.exe",
+		PausedAt:    time.Now().Add(-time.Hour),
+		ResumeAt:    sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true},
+	})
+	assert.Nil(t, err)
+
+	active, err := s.GetActiveSessions(t.Context())
+	assert.Nil(t, err, "GetActiveSessions should not err with paused programs")
+	if assert.Len(t, active.Paused, 1, "Pauses that ran out shouldn't be listed") {
+		assert.Equal(t, "code.exe", active.Paused[0].Program)
+		assert.Nil(t, active.Paused[0].ResumeAt)
+	}
+
+	err = s.ResumeTracking(t.Context(), []string{"notepad.exe"})
+	assert.NotNil(t, err, "Resuming a pause that already ran out should err")
+
+	err = s.ResumeTracking(t.Context(), []string{"Code.exe"})
+	assert.Nil(t, err, "ResumeTracking should not err for a paused program")
+
+	err = s.PrRepo.AddPause(t.Context(), database.AddPauseParams{ProgramName: "", PausedAt: time.Now()})
+	assert.Nil(t, err)
+	err = s.ResumeTracking(t.Context(), []string{"notepad.exe"})
+	assert.NotNil(t, err, "Programs paused through a pause of all programs can't be resumed alone")
+	err = s.ResumeTracking(t.Context(), nil)
+	assert.Nil(t, err, "ResumeTracking should resume everything with no programs given")
+}
+
+func TestOutputFormats(t *testing.T) {
+	s, err := setupTestServiceWithPrograms(t, "code.exe")
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		cmd := s.RootCmd()
+		cmd.SetOut(&out)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(args)
+		err := cmd.ExecuteContext(t.Context())
+		return out.String(), err
+	}
+
+	out, err := run("history", "--output", "json")
+	assert.Nil(t, err)
+	var history struct {
+		Sessions []struct {
+			ID              int64    `json:"id"`
+			Program         string   `json:"program"`
+			Start           string   `json:"start"`
+			DurationSeconds int64    `json:"duration_seconds"`
+			Tags            []string `json:"tags"`
+		} `json:"sessions"`
+	}
+	if assert.Nil(t, json.Unmarshal([]byte(out), &history), "JSON output should parse") && assert.Len(t, history.Sessions, 1) {
+		assert.Equal(t, "code.exe", history.Sessions[0].Program)
+		assert.Equal(t, int64(3600), history.Sessions[0].DurationSeconds)
+		assert.NotNil(t, history.Sessions[0].Tags, "Tags should be a list, even when empty")
+		_, err := time.Parse(time.RFC3339, history.Sessions[0].Start)
+		assert.Nil(t, err, "Times should be RFC 3339")
+	}
+
+	out, err = run("info", "code.exe", "--output", "csv")
+	assert.Nil(t, err)
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if assert.Nil(t, err, "CSV output should parse") && assert.Len(t, records, 2, "CSV should hold a header and a row") {
+		assert.Equal(t, "name", records[0][0])
+		assert.Equal(t, "code.exe", records[1][0])
+	}
+
+	out, err = run("report", "--period", "day", "--output", "csv")
+	assert.Nil(t, err)
+	records, err = csv.NewReader(strings.NewReader(out)).ReadAll()
+	if assert.Nil(t, err, "CSV output should parse") && assert.Len(t, records, 2) {
+		assert.Equal(t, []string{"program", "sessions", "total_seconds", "share", "period_share"}, records[0])
+		assert.Equal(t, "code.exe", records[1][0])
+		assert.Equal(t, "100.0", records[1][3])
+	}
+
+	out, err = run("ls", "--output", "tsv")
+	assert.Nil(t, err)
+	assert.Equal(t, "program\ncode.exe\n", out)
+
+	out, err = run("ls")
+	assert.Nil(t, err)
+	assert.Equal(t, " • code.exe\n", out, "Text should be the default output")
+
+	out, err = run("active", "--output", "json")
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"paused": [], "sessions": []}`, out)
+
+	_, err = run("exclude", "code.exe", "--args", "--type=renderer")
+	assert.Nil(t, err)
+	out, err = run("exclude", "code.exe", "--output", "csv")
+	assert.Nil(t, err)
+	assert.Equal(t, "program,kind,pattern\ncode.exe,args,--type=renderer\n", out, "Exclusion listing should follow --output")
+
+	out, err = run("history", "merge", "--gap", "30s", "--output", "json")
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"programs": []}`, out, "Merge output should follow --output")
+
+	_, err = run("ls", "--output", "yaml")
+	assert.NotNil(t, err, "Unknown output format should err")
 }
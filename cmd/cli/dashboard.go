@@ -0,0 +1,570 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Full screen dashboard of live tracking. It's drawn with plain ANSI escapes on the terminal's alternate screen, redrawn
+// every second so session durations tick, and reloaded from the database every interval. The service writes everything
+// shown to the database, so polling it sees the same state as the service without a connection to it
+
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l" // Switch to the alternate screen, hide the cursor
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+	clearLine   = "\x1b[K"
+
+	styleReset  = "\x1b[0m"
+	styleBold   = "\x1b[1m"
+	styleSelect = "\x1b[7m"
+	styleError  = "\x1b[31m"
+	styleGood   = "\x1b[32m"
+	styleDim    = "\x1b[2m"
+)
+
+type dashboardView int
+
+const (
+	mainView    dashboardView = iota
+	historyView               // History of a single program
+)
+
+type dashboard struct {
+	s       *CLIService
+	color   bool // Style with ANSI escapes, off with NO_COLOR
+	view    dashboardView
+	data    dashboardData
+	history SessionList // Sessions of the program shown in the history view
+	program string      // Program shown in the history view
+
+	selected int    // Index of the selected active session
+	scroll   int    // Lines scrolled up from the end of the history view
+	message  string // Outcome of the last key action, shown above the key help
+	failed   bool   // Last key action failed
+	pending  bool   // Service was sent a command, data should be reloaded once it's handled
+}
+
+// Everything shown on the main view, as of the last reload
+type dashboardData struct {
+	active     ActiveList
+	programs   Report            // Today's history by program
+	categories Report            // Today's history by category
+	categoryOf map[string]string // Category of each tracked program, for sessions still running
+	recent     SessionList
+	service    string    // Service state, ex. active
+	heartbeat  time.Time // Latest last-seen time the service recorded on an open session, zero if none
+	loadedAt   time.Time
+	err        error // First error hit loading data
+}
+
+// A row of the dashboard, styled once fitted to the screen's width
+type dashboardRow struct {
+	text  string
+	style string
+}
+
+// Runs the dashboard on the terminal until quit, reloading its data every interval
+func (s *CLIService) RunDashboard(ctx context.Context, in, out *os.File, interval string) error {
+	every, err := time.ParseDuration(interval)
+	if err != nil || every <= 0 {
+		return fmt.Errorf("invalid interval %q, expected a duration such as 2s", interval)
+	}
+	if _, _, ok := terminalSize(out); !ok {
+		return fmt.Errorf("dashboard needs a terminal, use timekeep active for a single snapshot")
+	}
+
+	restore, err := rawTerminal(in, out)
+	if err != nil {
+		return fmt.Errorf("failed to set up terminal: %w", err)
+	}
+	defer restore()
+
+	fmt.Fprint(out, enterScreen)
+	defer fmt.Fprint(out, leaveScreen)
+
+	d := &dashboard{s: s, color: os.Getenv("NO_COLOR") == ""}
+	d.load(ctx)
+
+	keys := readKeys(in)
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	reload := time.NewTicker(every)
+	defer reload.Stop()
+	var soon <-chan time.Time // Reload shortly after a command, once the service has handled it
+
+	for {
+		width, height, _ := terminalSize(out)
+		d.draw(out, width, height, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick.C:
+		case <-reload.C:
+			d.load(ctx)
+		case <-soon:
+			soon = nil
+			d.load(ctx)
+		case key, ok := <-keys:
+			if !ok || d.handleKey(ctx, key) {
+				return nil
+			}
+			if d.pending {
+				d.pending = false
+				soon = time.After(500 * time.Millisecond)
+			}
+		}
+	}
+}
+
+// Reloads the dashboard's data from the database, and the history of the program shown in the history view
+func (d *dashboard) load(ctx context.Context) {
+	data := dashboardData{loadedAt: time.Now(), categoryOf: make(map[string]string)}
+	fail := func(err error) {
+		if data.err == nil {
+			data.err = err
+		}
+	}
+
+	var err error
+	if data.active, err = d.s.GetActiveSessions(ctx); err != nil {
+		fail(err)
+	}
+	if data.programs, err = d.s.GetReport(ctx, "day", "program", "", "", ""); err != nil {
+		fail(err)
+	}
+	if data.categories, err = d.s.GetReport(ctx, "day", "category", "", "", ""); err != nil {
+		fail(err)
+	}
+	if data.recent, err = d.s.GetSessionHistory(ctx, nil, "", "", "", "", "", "", 10); err != nil {
+		fail(err)
+	}
+
+	programs, err := d.s.PrRepo.GetAllPrograms(ctx)
+	if err != nil {
+		fail(fmt.Errorf("error getting tracked programs: %w", err))
+	}
+	for _, program := range programs {
+		data.categoryOf[program.Name] = program.Category.String
+	}
+
+	sessions, err := d.s.AsRepo.GetAllActiveSessions(ctx)
+	if err != nil {
+		fail(fmt.Errorf("error getting active sessions: %w", err))
+	}
+	for _, session := range sessions {
+		if session.LastSeen.Valid && session.LastSeen.Time.After(data.heartbeat) {
+			data.heartbeat = session.LastSeen.Time
+		}
+	}
+
+	data.service, err = d.s.serviceState()
+	if err != nil {
+		data.service = "not running"
+	}
+
+	if d.view == historyView {
+		if d.history, err = d.s.GetSessionHistory(ctx, []string{d.program}, "", "", "", "", "", "", 200); err != nil {
+			fail(err)
+		}
+	}
+
+	d.data = data
+	d.selected = max(min(d.selected, len(data.active.Sessions)-1), 0)
+}
+
+// Handles a key press, returning true to quit
+func (d *dashboard) handleKey(ctx context.Context, key string) bool {
+	if key == "ctrl+c" {
+		return true
+	}
+
+	if d.view == historyView {
+		switch key {
+		case "q", "esc", "backspace", "h":
+			d.view = mainView
+		case "up", "k":
+			d.scroll++
+		case "down", "j":
+			d.scroll = max(d.scroll-1, 0)
+		case "r":
+			d.load(ctx)
+		}
+		return false
+	}
+
+	switch key {
+	case "q", "esc":
+		return true
+	case "up", "k":
+		d.selected = max(d.selected-1, 0)
+	case "down", "j":
+		d.selected = max(min(d.selected+1, len(d.data.active.Sessions)-1), 0)
+	case "enter", "h":
+		session, ok := d.selectedSession()
+		if !ok {
+			d.setMessage("No session selected", true)
+			return false
+		}
+		d.view, d.program, d.scroll = historyView, session.Program, 0
+		d.load(ctx)
+	case "p":
+		session, ok := d.selectedSession()
+		switch {
+		case !ok:
+			d.setMessage("No session selected", true)
+		case session.Timer:
+			d.setMessage("Manual timers aren't paused, stop them with: timekeep stop "+session.Program, true)
+		default:
+			d.send(Command{Action: "pause", Programs: []string{session.Program}}, "Paused tracking of "+session.Program)
+		}
+	case "P":
+		d.send(Command{Action: "pause"}, "Paused tracking of all programs")
+	case "u":
+		if len(d.data.active.Paused) == 0 {
+			d.setMessage("Tracking isn't paused", true)
+			return false
+		}
+		d.send(Command{Action: "resume"}, "Tracking resumed")
+	case "r":
+		d.load(ctx)
+	}
+
+	return false
+}
+
+// Sends a command to the service, showing message once it's sent
+func (d *dashboard) send(cmd Command, message string) {
+	if err := d.s.ServiceCmd.SendCommand(cmd); err != nil {
+		d.setMessage(fmt.Sprintf("Failed to %s tracking: %s", cmd.Action, err), true)
+		return
+	}
+	d.setMessage(message, false)
+	d.pending = true
+}
+
+func (d *dashboard) setMessage(message string, failed bool) {
+	d.message, d.failed = message, failed
+}
+
+func (d *dashboard) selectedSession() (ActiveRecord, bool) {
+	if d.selected >= len(d.data.active.Sessions) {
+		return ActiveRecord{}, false
+	}
+	return d.data.active.Sessions[d.selected], true
+}
+
+// Draws the dashboard over the whole screen
+func (d *dashboard) draw(w io.Writer, width, height int, now time.Time) {
+	rows := d.frame(width, height, now)
+
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		lines[i] = d.style(row.style, fit(row.text, width))
+	}
+	fmt.Fprint(w, "\x1b[H"+strings.Join(lines, clearLine+"\r\n")+clearLine+"\x1b[J")
+}
+
+// Returns the rows of the current view, at most height of them
+func (d *dashboard) frame(width, height int, now time.Time) []dashboardRow {
+	title := "timekeep dashboard"
+	if d.view == historyView {
+		title = "History of " + d.program
+	}
+	clock := now.Format("Mon 2006-01-02 15:04:05")
+	rows := []dashboardRow{
+		{text: title + strings.Repeat(" ", max(width-len(title)-len(clock), 1)) + clock, style: styleBold},
+		d.statusRow(now),
+		{},
+	}
+
+	help := "↑/↓ select  enter history  p pause  P pause all  u resume  r reload  q quit"
+	if d.view == historyView {
+		help = "↑/↓ scroll  esc back  r reload"
+	}
+	footer := []dashboardRow{{text: d.message}, {text: help, style: styleDim}}
+	switch {
+	case d.message != "" && d.failed:
+		footer[0].style = styleError
+	case d.message != "":
+		footer[0].style = styleGood
+	case d.data.err != nil:
+		footer[0] = dashboardRow{text: "Error: " + d.data.err.Error(), style: styleError}
+	}
+
+	space := max(height-len(rows)-len(footer), 0)
+	if d.view == historyView {
+		rows = append(rows, d.historyRows(space)...)
+	} else {
+		rows = append(rows, d.mainRows(space, now)...)
+	}
+
+	for len(rows) < height-len(footer) {
+		rows = append(rows, dashboardRow{})
+	}
+	rows = append(rows, footer...)
+	if len(rows) > height {
+		rows = rows[:max(height, 0)]
+	}
+
+	return rows
+}
+
+// Returns the row showing the service's state, how long ago it last recorded a heartbeat and what's paused
+func (d *dashboard) statusRow(now time.Time) dashboardRow {
+	parts := []string{"Service: " + d.data.service}
+	if !d.data.heartbeat.IsZero() {
+		parts = append(parts, "Last heartbeat "+durationString(now.Sub(d.data.heartbeat).Truncate(time.Second))+" ago")
+	}
+	for _, pause := range d.data.active.Paused {
+		target := pauseTarget(pause.Program)
+		if pause.ResumeAt != nil {
+			parts = append(parts, fmt.Sprintf("Paused: %s until %s", target, pause.ResumeAt.Format("15:04")))
+		} else {
+			parts = append(parts, "Paused: "+target)
+		}
+	}
+	parts = append(parts, "Updated "+d.data.loadedAt.Format("15:04:05"))
+
+	return dashboardRow{text: strings.Join(parts, " | ")}
+}
+
+// Returns the rows of the main view: active sessions, today's totals and recent history, in space rows
+func (d *dashboard) mainRows(space int, now time.Time) []dashboardRow {
+	var rows []dashboardRow
+
+	// Active sessions, scrolled to keep the selection in view
+	sessions := d.data.active.Sessions
+	shown := max(min(len(sessions), space/3), 1)
+	rows = append(rows, dashboardRow{text: fmt.Sprintf("Active sessions (%d)", len(sessions)), style: styleBold})
+	if len(sessions) == 0 {
+		rows = append(rows, dashboardRow{text: "  No active sessions"})
+	}
+	first := max(d.selected-shown+1, 0)
+	for i := first; i < len(sessions) && i < first+shown; i++ {
+		rows = append(rows, d.activeRow(sessions[i], i == d.selected, now))
+	}
+	rows = append(rows, dashboardRow{})
+
+	// Today's totals by program and category, side by side
+	programs, categories := d.todayTotals(now)
+	todayRows := min(max(len(programs), len(categories), 1), 8, max(space-len(rows)-3, 1))
+	rows = append(rows, dashboardRow{text: "Today", style: styleBold})
+	for i := range todayRows {
+		left, right := "", ""
+		if i < len(programs) {
+			left = programs[i]
+		} else if i == 0 {
+			left = "No time tracked today"
+		}
+		if i < len(categories) {
+			right = categories[i]
+		}
+		rows = append(rows, dashboardRow{text: fmt.Sprintf("  %-38s  %s", fit(left, 38), right)})
+	}
+	rows = append(rows, dashboardRow{})
+
+	// Recent history, newest first
+	rows = append(rows, dashboardRow{text: "Recent sessions", style: styleBold})
+	var recent []string
+	for i := len(d.data.recent.Sessions) - 1; i >= 0; i-- {
+		var b bytes.Buffer
+		writeSession(&b, d.data.recent.Sessions[i])
+		recent = append(recent, strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")...)
+	}
+	if len(recent) == 0 {
+		recent = []string{"  No sessions in history"}
+	}
+	for _, line := range recent[:max(min(len(recent), space-len(rows)), 0)] {
+		rows = append(rows, dashboardRow{text: line})
+	}
+
+	return rows
+}
+
+// Returns the row of an active session, its duration ticking from its start
+func (d *dashboard) activeRow(session ActiveRecord, selected bool, now time.Time) dashboardRow {
+	marker, style := "  ", ""
+	if selected {
+		marker = "> "
+		style = styleSelect
+	}
+
+	text := fmt.Sprintf("%s%-20s since %s  %9s", marker, fit(session.Program, 20), session.Start.Format("15:04"), clockString(now.Sub(session.Start)))
+	if session.IdleSeconds > 0 {
+		text += fmt.Sprintf("  idle %s", durationString(time.Duration(session.IdleSeconds)*time.Second))
+	}
+	if session.Project != "" {
+		text += "  project " + session.Project
+	}
+	if len(session.Tags) > 0 {
+		text += "  tags " + strings.Join(session.Tags, ", ")
+	}
+	if session.Timer {
+		text += "  (timer)"
+	}
+
+	return dashboardRow{text: text, style: style}
+}
+
+// Returns today's totals per program and per category, largest first, counting the time of sessions still running
+func (d *dashboard) todayTotals(now time.Time) ([]string, []string) {
+	programs := make(map[string]int64)
+	for _, group := range d.data.programs.Groups {
+		programs[group.Name] += group.TotalSeconds
+	}
+	categories := make(map[string]int64)
+	for _, group := range d.data.categories.Groups {
+		categories[group.Name] += group.TotalSeconds
+	}
+
+	midnight := d.data.programs.Start
+	for _, session := range d.data.active.Sessions {
+		start := session.Start
+		if start.Before(midnight) {
+			start = midnight
+		}
+		running := int64(now.Sub(start).Seconds())
+		if running <= 0 {
+			continue
+		}
+		programs[session.Program] += running
+
+		category := session.Category
+		if category == "" {
+			category = d.data.categoryOf[session.Program]
+		}
+		categories[category] += running
+	}
+
+	return totalRows(programs, "(none)"), totalRows(categories, "(none)")
+}
+
+// Formats totals by name largest first, with each one's share of their sum
+func totalRows(totals map[string]int64, unnamed string) []string {
+	var sum int64
+	names := make([]string, 0, len(totals))
+	for name, seconds := range totals {
+		sum += seconds
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if totals[a] != totals[b] {
+			return int(totals[b] - totals[a])
+		}
+		return strings.Compare(a, b)
+	})
+
+	rows := make([]string, 0, len(names))
+	for _, name := range names {
+		label := name
+		if label == "" {
+			label = unnamed
+		}
+		rows = append(rows, fmt.Sprintf("%-16s %9s %5.1f%%", fit(label, 16), durationString(time.Duration(totals[name])*time.Second), percent(totals[name], sum)))
+	}
+	return rows
+}
+
+// Returns the rows of the history view, the newest sessions that fit in space rows unless scrolled
+func (d *dashboard) historyRows(space int) []dashboardRow {
+	var b bytes.Buffer
+	d.history.WriteText(&b)
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(d.history.Sessions) == 0 {
+		lines = []string{"  No sessions in history"}
+	}
+
+	d.scroll = min(d.scroll, max(len(lines)-space, 0))
+	end := len(lines) - d.scroll
+	start := max(end-space, 0)
+
+	rows := make([]dashboardRow, 0, end-start)
+	for _, line := range lines[start:end] {
+		rows = append(rows, dashboardRow{text: line})
+	}
+	return rows
+}
+
+func (d *dashboard) style(style, text string) string {
+	if !d.color || style == "" {
+		return text
+	}
+	return style + text + styleReset
+}
+
+// Reads key presses from the terminal, sending each one's name until input ends
+func readKeys(in io.Reader) <-chan string {
+	keys := make(chan string)
+	go func() {
+		defer close(keys)
+		buf := make([]byte, 64)
+		for {
+			n, err := in.Read(buf)
+			for _, key := range parseKeys(buf[:n]) {
+				keys <- key
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return keys
+}
+
+// Splits bytes read from a raw terminal into key names, arrow keys given as up and down
+func parseKeys(b []byte) []string {
+	var keys []string
+	for len(b) > 0 {
+		switch {
+		case bytes.HasPrefix(b, []byte("\x1b[A")), bytes.HasPrefix(b, []byte("\x1bOA")):
+			keys, b = append(keys, "up"), b[3:]
+			continue
+		case bytes.HasPrefix(b, []byte("\x1b[B")), bytes.HasPrefix(b, []byte("\x1bOB")):
+			keys, b = append(keys, "down"), b[3:]
+			continue
+		case len(b) >= 3 && b[0] == 0x1b && (b[1] == '[' || b[1] == 'O'):
+			b = b[3:] // Other escape sequence, ex. left/right arrows
+			continue
+		}
+
+		switch b[0] {
+		case 0x1b:
+			keys = append(keys, "esc")
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case 0x7f, 0x08:
+			keys = append(keys, "backspace")
+		case 0x03:
+			keys = append(keys, "ctrl+c")
+		default:
+			keys = append(keys, string(b[0]))
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+// Formats a duration as a clock, ex. 1:02:03
+func clockString(duration time.Duration) string {
+	duration = max(duration, 0).Truncate(time.Second)
+	hours := int(duration.Hours())
+	minutes := int(duration.Minutes()) % 60
+	seconds := int(duration.Seconds()) % 60
+	return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds)
+}
+
+// Cuts text down to width columns
+func fit(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) <= width {
+		return text
+	}
+	return string(runes[:max(width, 0)])
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jms-guy/timekeep/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+// Records the commands sent to the service
+type recordingCommander struct {
+	commands []Command
+}
+
+func (r *recordingCommander) WriteToService() error {
+	return nil
+}
+
+func (r *recordingCommander) SendCommand(cmd Command) error {
+	r.commands = append(r.commands, cmd)
+	return nil
+}
+
+// Returns the text of a dashboard frame, one line per row
+func frameText(d *dashboard, width, height int, now time.Time) string {
+	var lines []string
+	for _, row := range d.frame(width, height, now) {
+		lines = append(lines, fit(row.text, width))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		keys  []string
+	}{
+		{name: "Letters", input: "jkq", keys: []string{"j", "k", "q"}},
+		{name: "Arrows", input: "\x1b[A\x1b[B\x1bOA", keys: []string{"up", "down", "up"}},
+		{name: "Other escape sequences", input: "\x1b[Cp", keys: []string{"p"}},
+		{name: "Escape", input: "\x1b", keys: []string{"esc"}},
+		{name: "Controls", input: "\r\x7f\x03", keys: []string{"enter", "backspace", "ctrl+c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.keys, parseKeys([]byte(tt.input)))
+		})
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	s, err := CLITestServiceSetup()
+	if err != nil {
+		t.Fatalf("Failed to setup test service: %v", err)
+	}
+	commander := &recordingCommander{}
+	s.ServiceCmd = commander
+
+	err = s.PrRepo.AddProgram(t.Context(), database.AddProgramParams{Name: "code.exe", Category: sql.NullString{String: "dev", Valid: true}})
+	assert.Nil(t, err)
+
+	start := time.Now().Add(-90 * time.Minute).Truncate(time.Second)
+	err = s.AsRepo.CreateActiveSession(t.Context(), database.CreateActiveSessionParams{ProgramName: "code.exe", StartTime: start})
+	assert.Nil(t, err)
+	err = s.AsRepo.CreateActiveSession(t.Context(), database.CreateActiveSessionParams{ProgramName: "standup", StartTime: start.Add(time.Hour), Manual: true})
+	assert.Nil(t, err)
+
+	d := &dashboard{s: s}
+	d.load(t.Context())
+	assert.Nil(t, d.data.err, "Loading dashboard data should not err")
+	assert.Len(t, d.data.active.Sessions, 2)
+
+	frame := frameText(d, 100, 30, start.Add(90*time.Minute+5*time.Second))
+	assert.Contains(t, frame, "Active sessions (2)")
+	assert.Contains(t, frame, "> code.exe", "First session should be selected")
+	assert.Contains(t, frame, "1:30:05", "Duration should tick from the session's start")
+	assert.Contains(t, frame, "0:30:05")
+	assert.Contains(t, frame, "(timer)")
+	assert.Contains(t, frame, "dev", "Today's totals should include the category of running sessions")
+	assert.Len(t, strings.Split(frame, "\n"), 30, "Frame should fill the screen")
+
+	for _, line := range strings.Split(frameText(d, 40, 10, time.Now()), "\n") {
+		assert.LessOrEqual(t, len([]rune(line)), 40, "Rows should fit the screen's width")
+	}
+
+	t.Run("Selection", func(t *testing.T) {
+		d.handleKey(t.Context(), "down")
+		d.handleKey(t.Context(), "down")
+		assert.Equal(t, 1, d.selected, "Selection should stop at the last session")
+
+		d.handleKey(t.Context(), "p")
+		assert.Empty(t, commander.commands, "Timers should not be paused")
+		assert.True(t, d.failed)
+
+		d.handleKey(t.Context(), "k")
+		assert.Equal(t, 0, d.selected)
+	})
+
+	t.Run("Pause and resume", func(t *testing.T) {
+		d.handleKey(t.Context(), "p")
+		d.handleKey(t.Context(), "P")
+		assert.Equal(t, []Command{{Action: "pause", Programs: []string{"code.exe"}}, {Action: "pause"}}, commander.commands)
+		assert.True(t, d.pending, "Data should be reloaded after a command")
+
+		d.handleKey(t.Context(), "u")
+		assert.Len(t, commander.commands, 2, "Resume should not be sent when nothing is paused")
+
+		err = s.PrRepo.AddPause(t.Context(), database.AddPauseParams{ProgramName: "", PausedAt: time.Now()})
+		assert.Nil(t, err)
+		d.handleKey(t.Context(), "r")
+		assert.Contains(t, frameText(d, 100, 30, time.Now()), "Paused: all programs")
+
+		d.handleKey(t.Context(), "u")
+		assert.Equal(t, Command{Action: "resume"}, commander.commands[2])
+	})
+
+	t.Run("History", func(t *testing.T) {
+		_, err := s.HsRepo.AddToSessionHistory(t.Context(), database.AddToSessionHistoryParams{
+			ProgramName:     "code.exe",
+			StartTime:       start.Add(-2 * time.Hour),
+			EndTime:         start.Add(-time.Hour),
+			DurationSeconds: 3600,
+		})
+		assert.Nil(t, err)
+
+		d.handleKey(t.Context(), "enter")
+		assert.Equal(t, historyView, d.view)
+		assert.Equal(t, "code.exe", d.program)
+		assert.Len(t, d.history.Sessions, 1)
+		assert.Contains(t, frameText(d, 100, 30, time.Now()), "History of code.exe")
+
+		assert.False(t, d.handleKey(t.Context(), "q"), "q should leave the history view without quitting")
+		assert.Equal(t, mainView, d.view)
+		assert.True(t, d.handleKey(t.Context(), "q"))
+	})
+}
+
+func TestClockString(t *testing.T) {
+	assert.Equal(t, "0:00:00", clockString(-time.Second))
+	assert.Equal(t, "0:02:03", clockString(2*time.Minute+3*time.Second+500*time.Millisecond))
+	assert.Equal(t, "26:00:01", clockString(26*time.Hour+time.Second))
+}
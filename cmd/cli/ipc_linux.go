@@ -9,13 +9,14 @@ import (
 )
 
 func (r *realServiceCommander) WriteToService() error {
+	return r.SendCommand(Command{Action: "refresh"})
+}
+
+// Connects to Unix socket opened by main service, to communicate an action to the service
+func (r *realServiceCommander) SendCommand(msg Command) error {
 	socketDir := "/var/run/timekeep"
 	socketName := socketDir + "/timekeep.sock"
 
-	msg := Command{
-		Action: "refresh",
-	}
-
 	conn, err := net.Dial("unix", socketName)
 	if err != nil {
 		return fmt.Errorf("failed to connect to socket: %v", err)
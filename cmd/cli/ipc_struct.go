@@ -6,15 +6,24 @@ type (
 )
 
 type Command struct {
-	Action      string `json:"action"`
-	ProcessName string `json:"name,omitempty"`
-	ProcessID   int    `json:"pid,omitempty"`
+	Action      string   `json:"action"`
+	ProcessName string   `json:"name,omitempty"` // Process name, or timer label for timer actions
+	ProcessID   int      `json:"pid,omitempty"`
+	Category    string   `json:"category,omitempty"` // Category of a started timer
+	Project     string   `json:"project,omitempty"`  // Project of a started timer
+	Programs    []string `json:"programs,omitempty"` // Programs to pause or resume, all programs if empty
+	For         string   `json:"for,omitempty"`      // How long to pause for, until resumed if empty
 }
 
 type ServiceCommander interface {
-	WriteToService() error
+	WriteToService() error         // Asks the service to refresh its config and tracked programs
+	SendCommand(cmd Command) error // Sends a single command to the service
 }
 
 func (r *testServiceCommander) WriteToService() error {
 	return nil
 }
+
+func (r *testServiceCommander) SendCommand(cmd Command) error {
+	return nil
+}
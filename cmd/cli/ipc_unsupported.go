@@ -5,3 +5,7 @@ package main
 func (r *realServiceCommander) WriteToService() error {
 	return nil
 }
+
+func (r *realServiceCommander) SendCommand(cmd Command) error {
+	return nil
+}
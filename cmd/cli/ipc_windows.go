@@ -9,13 +9,13 @@ import (
 	"github.com/Microsoft/go-winio"
 )
 
-// Connects to named pipe opened by main service, to communicate an action to the service
 func (r *realServiceCommander) WriteToService() error {
-	pipeName := "\\\\.\\pipe\\Timekeep"
+	return r.SendCommand(Command{Action: "refresh"})
+}
 
-	msg := Command{
-		Action: "refresh",
-	}
+// Connects to named pipe opened by main service, to communicate an action to the service
+func (r *realServiceCommander) SendCommand(msg Command) error {
+	pipeName := "\\\\.\\pipe\\Timekeep"
 
 	conn, err := winio.DialPipe(pipeName, nil)
 	if err != nil {
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/jms-guy/timekeep/internal/chart"
+	"github.com/spf13/cobra"
+)
+
+// Result of a read command, rendered in the format chosen with the global --output flag
+type Result interface {
+	WriteText(w io.Writer)         // Writes the human readable output
+	Table() ([]string, [][]string) // Returns the header and rows written by csv/tsv output
+}
+
+// Renders command results in one output format
+type Formatter interface {
+	Format(w io.Writer, r Result) error
+}
+
+// Human readable output, the default
+type textFormatter struct{}
+
+func (textFormatter) Format(w io.Writer, r Result) error {
+	r.WriteText(w)
+	return nil
+}
+
+// Indented JSON of the result struct, field names documented in docs/output.md
+type jsonFormatter struct{}
+
+func (jsonFormatter) Format(w io.Writer, r Result) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// Header row followed by one row per record, separated by comma
+type delimitedFormatter struct {
+	comma rune
+}
+
+func (f delimitedFormatter) Format(w io.Writer, r Result) error {
+	header, rows := r.Table()
+
+	writer := csv.NewWriter(w)
+	writer.Comma = f.comma
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Formatters by --output value
+var formatters = map[string]Formatter{
+	"text": textFormatter{},
+	"json": jsonFormatter{},
+	"csv":  delimitedFormatter{comma: ','},
+	"tsv":  delimitedFormatter{comma: '\t'},
+}
+
+// Returns the formatter for an --output value
+func newFormatter(format string) (Formatter, error) {
+	f, ok := formatters[format]
+	if !ok {
+		return nil, fmt.Errorf("invalid output format %q, expected text, json, csv or tsv", format)
+	}
+	return f, nil
+}
+
+// Writes a command's result to the command's output, in the format chosen with --output
+func render(cmd *cobra.Command, r Result) error {
+	format, _ := cmd.Flags().GetString("output")
+	if format == "" { // Command run outside the root command, without the flag
+		format = "text"
+	}
+
+	f, err := newFormatter(format)
+	if err != nil {
+		return err
+	}
+
+	return f.Format(cmd.OutOrStdout(), r)
+}
+
+// Returns how charts are drawn to the command's output, fitted to the terminal's width ($COLUMNS or 80 columns when not
+// writing to a terminal) and colored only on a terminal with NO_COLOR unset
+func chartOptions(cmd *cobra.Command) chart.Options {
+	ascii, _ := cmd.Flags().GetBool("ascii")
+	opts := chart.Options{Width: 80, ASCII: ascii}
+
+	terminal := false
+	if f, ok := cmd.OutOrStdout().(*os.File); ok {
+		opts.Width, _, terminal = terminalSize(f)
+	}
+	if !terminal {
+		opts.Width = 80
+		if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+			opts.Width = columns
+		}
+	}
+	opts.Color = terminal && os.Getenv("NO_COLOR") == ""
+
+	return opts
+}
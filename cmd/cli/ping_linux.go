@@ -10,13 +10,11 @@ import (
 
 // Gets current service state for user
 func (s *CLIService) StatusService() error {
-	cmd := exec.Command("systemctl", "is-active", "timekeep.service")
-	output, err := cmd.Output()
+	status, err := s.serviceState()
 	if err != nil {
-		return fmt.Errorf("service not running: %v", err)
+		return err
 	}
 
-	status := strings.TrimSpace(string(output))
 	if status != "active" {
 		return fmt.Errorf("service is not active; Status: %s", status)
 	}
@@ -25,3 +23,14 @@ func (s *CLIService) StatusService() error {
 
 	return nil
 }
+
+// Returns the service's state as reported by systemd, ex. active
+func (s *CLIService) serviceState() (string, error) {
+	cmd := exec.Command("systemctl", "is-active", "timekeep.service")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("service not running: %v", err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
@@ -5,3 +5,7 @@ package main
 func (s *CLIService) StatusService() error {
 	return nil
 }
+
+func (s *CLIService) serviceState() (string, error) {
+	return "unknown", nil
+}
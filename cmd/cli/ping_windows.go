@@ -34,11 +34,23 @@ var stateName = map[ServiceState]string{
 
 // Gets current service state for user
 func (s *CLIService) StatusService() error {
-	stdoutResult, err := s.CmdExe.RunCommand(context.Background(), "sc.exe", "query", "Timekeep")
+	state, err := s.serviceState()
 	if err != nil {
 		return err
 	}
 
+	fmt.Printf("Service status: %s\n", state)
+
+	return nil
+}
+
+// Returns the service's state as reported by the service control manager, ex. Running
+func (s *CLIService) serviceState() (string, error) {
+	stdoutResult, err := s.CmdExe.RunCommand(context.Background(), "sc.exe", "query", "Timekeep")
+	if err != nil {
+		return "", err
+	}
+
 	stdoutLines := strings.Split(stdoutResult, "\n")
 
 	stateStr := ""
@@ -50,25 +62,22 @@ func (s *CLIService) StatusService() error {
 		}
 	}
 	if stateStr == "" {
-		return fmt.Errorf("missing service state value")
+		return "", fmt.Errorf("missing service state value")
 	}
 
 	parts := strings.Fields(stateStr)
 	if len(parts) < 3 {
-		return fmt.Errorf("malformed state line: %s", stateStr)
+		return "", fmt.Errorf("malformed state line: %s", stateStr)
 	}
 
 	stateValStr := parts[2]
 	stateNum, err := strconv.Atoi(stateValStr)
 	if err != nil {
-		return fmt.Errorf("error converting state number '%s' to integer: %w", stateValStr, err)
+		return "", fmt.Errorf("error converting state number '%s' to integer: %w", stateValStr, err)
 	}
 
 	if state, ok := stateName[ServiceState(stateNum)]; ok {
-		fmt.Printf("Service status: %s\n", state)
-	} else {
-		fmt.Printf("Service status: Unknown state (%d)\n", stateNum)
+		return state, nil
 	}
-
-	return nil
+	return fmt.Sprintf("Unknown state (%d)", stateNum), nil
 }
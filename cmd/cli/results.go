@@ -0,0 +1,595 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jms-guy/timekeep/internal/chart"
+	"github.com/jms-guy/timekeep/internal/database"
+	"github.com/jms-guy/timekeep/internal/report"
+	"github.com/jms-guy/timekeep/internal/tags"
+)
+
+// Typed results of the read commands. JSON field names are a contract for scripts, documented in docs/output.md, so
+// fields may be added but not renamed or removed. Times are RFC 3339 in local time, durations are whole seconds
+
+// Programs tracked by the service, from "ls"
+type ProgramList struct {
+	Programs []string `json:"programs"`
+}
+
+func (l ProgramList) WriteText(w io.Writer) {
+	for _, program := range l.Programs {
+		fmt.Fprintf(w, " • %s\n", program)
+	}
+}
+
+func (l ProgramList) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(l.Programs))
+	for _, program := range l.Programs {
+		rows = append(rows, []string{program})
+	}
+	return []string{"program"}, rows
+}
+
+// Helper process exclusion rules of a program, from "exclude <program>" without rules
+type ExclusionList struct {
+	Program string          `json:"program"`
+	Rules   []ExclusionRule `json:"rules"`
+}
+
+type ExclusionRule struct {
+	Kind    string `json:"kind"` // args, parent or path
+	Pattern string `json:"pattern"`
+}
+
+func (l ExclusionList) WriteText(w io.Writer) {
+	for _, rule := range l.Rules {
+		fmt.Fprintf(w, " • %s: %s\n", rule.Kind, rule.Pattern)
+	}
+}
+
+func (l ExclusionList) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(l.Rules))
+	for _, rule := range l.Rules {
+		rows = append(rows, []string{l.Program, rule.Kind, rule.Pattern})
+	}
+	return []string{"program", "kind", "pattern"}, rows
+}
+
+// Time tracked for every program, from "info" without a program
+type ProgramSummaries struct {
+	Programs []ProgramSummary `json:"programs"`
+	filtered bool             // Only sessions passing --date/--start/--end/--min-duration/--tag are counted
+}
+
+type ProgramSummary struct {
+	Name         string `json:"name"`
+	TotalSeconds int64  `json:"total_seconds"` // Lifetime of the program, or time of the sessions counted when filtered
+	IdleSeconds  int64  `json:"idle_seconds"`
+	Sessions     int64  `json:"sessions"`
+}
+
+func (p ProgramSummaries) WriteText(w io.Writer) {
+	for _, program := range p.Programs {
+		total := time.Duration(program.TotalSeconds) * time.Second
+		switch {
+		case p.filtered:
+			fmt.Fprintf(w, "  %s: %s (%d sessions)\n", program.Name, durationString(total), program.Sessions)
+		case program.IdleSeconds > 0:
+			active := time.Duration(program.TotalSeconds-program.IdleSeconds) * time.Second
+			fmt.Fprintf(w, "  %s: %s (active %s)\n", program.Name, durationString(total), durationString(active))
+		default:
+			fmt.Fprintf(w, "  %s: %s\n", program.Name, durationString(total))
+		}
+	}
+}
+
+func (p ProgramSummaries) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(p.Programs))
+	for _, program := range p.Programs {
+		rows = append(rows, []string{
+			program.Name,
+			formatInt(program.TotalSeconds),
+			formatInt(program.IdleSeconds),
+			formatInt(program.Sessions),
+		})
+	}
+	return []string{"name", "total_seconds", "idle_seconds", "sessions"}, rows
+}
+
+// Detailed stats of a single program, from "info <program>"
+type ProgramInfo struct {
+	Name                  string         `json:"name"`
+	Category              string         `json:"category,omitempty"`
+	Project               string         `json:"project,omitempty"`
+	LifetimeSeconds       int64          `json:"lifetime_seconds"`
+	IdleSeconds           int64          `json:"idle_seconds"`
+	Sessions              int64          `json:"sessions"`                // Sessions counted, passing the filter flags if given
+	AverageSessionSeconds int64          `json:"average_session_seconds"` // Average length of the sessions counted
+	LastSession           *SessionRecord `json:"last_session"`            // Null if the program has no history
+}
+
+func (i ProgramInfo) WriteText(w io.Writer) {
+	if i.Category != "" {
+		fmt.Fprintf(w, " • Category: %s\n", i.Category)
+	}
+	if i.Project != "" {
+		fmt.Fprintf(w, " • Project: %s\n", i.Project)
+	}
+	fmt.Fprintf(w, " • Current Lifetime: %s\n", durationString(time.Duration(i.LifetimeSeconds)*time.Second))
+	if i.IdleSeconds > 0 {
+		fmt.Fprintf(w, " • Active Lifetime: %s\n", durationString(time.Duration(i.LifetimeSeconds-i.IdleSeconds)*time.Second))
+	}
+	fmt.Fprintf(w, " • Total sessions to date: %d\n", i.Sessions)
+
+	if i.LastSession == nil {
+		fmt.Fprintf(w, " • Last Session: None\n")
+		return
+	}
+	fmt.Fprintf(w, " • Last Session: %s - %s (%s)\n",
+		i.LastSession.Start.Format(sessionTimeLayout),
+		i.LastSession.End.Format(sessionTimeLayout),
+		durationString(time.Duration(i.LastSession.DurationSeconds)*time.Second))
+
+	if i.Sessions > 0 {
+		fmt.Fprintf(w, " • Average session length: %s\n", durationString(time.Duration(i.AverageSessionSeconds)*time.Second))
+	}
+}
+
+func (i ProgramInfo) Table() ([]string, [][]string) {
+	header := []string{
+		"name", "category", "project", "lifetime_seconds", "idle_seconds", "sessions", "average_session_seconds",
+		"last_session_start", "last_session_end",
+	}
+	row := []string{
+		i.Name,
+		i.Category,
+		i.Project,
+		formatInt(i.LifetimeSeconds),
+		formatInt(i.IdleSeconds),
+		formatInt(i.Sessions),
+		formatInt(i.AverageSessionSeconds),
+		"",
+		"",
+	}
+	if i.LastSession != nil {
+		row[7], row[8] = formatTime(i.LastSession.Start), formatTime(i.LastSession.End)
+	}
+	return header, [][]string{row}
+}
+
+// Sessions from history, from "history"
+type SessionList struct {
+	Sessions     []SessionRecord `json:"sessions"`
+	TotalSeconds *int64          `json:"total_seconds,omitempty"` // Time inside the filtered period, with --date or --start
+}
+
+// Single session from history
+type SessionRecord struct {
+	ID              int64     `json:"id"`
+	Program         string    `json:"program"`
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	DurationSeconds int64     `json:"duration_seconds"`
+	IdleSeconds     int64     `json:"idle_seconds"`
+	Project         string    `json:"project,omitempty"`
+	Category        string    `json:"category,omitempty"`
+	Container       string    `json:"container,omitempty"`
+	UID             *int64    `json:"uid,omitempty"` // Owner of the process that opened the session, where known
+	Tags            []string  `json:"tags"`
+	Notes           string    `json:"notes,omitempty"`
+	Manual          bool      `json:"manual"`    // Recorded by a manual timer or added by hand
+	Recovered       bool      `json:"recovered"` // Closed after a service crash, ending at its last heartbeat
+}
+
+// Builds the result record of a history session
+func newSessionRecord(session database.SessionHistory, sessionTags []string) SessionRecord {
+	record := SessionRecord{
+		ID:              session.ID,
+		Program:         session.ProgramName,
+		Start:           session.StartTime.Local(),
+		End:             session.EndTime.Local(),
+		DurationSeconds: session.DurationSeconds,
+		IdleSeconds:     session.IdleSeconds,
+		Project:         session.Project.String,
+		Category:        session.Category.String,
+		Container:       session.Container.String,
+		Tags:            sessionTags,
+		Notes:           session.Notes.String,
+		Manual:          session.Manual,
+		Recovered:       session.Recovered,
+	}
+	if session.Uid.Valid {
+		record.UID = &session.Uid.Int64
+	}
+	if record.Tags == nil {
+		record.Tags = []string{}
+	}
+	return record
+}
+
+func (l SessionList) WriteText(w io.Writer) {
+	for _, session := range l.Sessions {
+		writeSession(w, session)
+	}
+	if l.TotalSeconds != nil {
+		fmt.Fprintf(w, "  Total: %s\n", durationString(time.Duration(*l.TotalSeconds)*time.Second))
+	}
+}
+
+func (l SessionList) Table() ([]string, [][]string) {
+	header := []string{
+		"id", "program", "start", "end", "duration_seconds", "idle_seconds", "project", "category", "container", "uid",
+		"tags", "notes", "manual", "recovered",
+	}
+	rows := make([][]string, 0, len(l.Sessions))
+	for _, session := range l.Sessions {
+		uid := ""
+		if session.UID != nil {
+			uid = formatInt(*session.UID)
+		}
+		rows = append(rows, []string{
+			formatInt(session.ID),
+			session.Program,
+			formatTime(session.Start),
+			formatTime(session.End),
+			formatInt(session.DurationSeconds),
+			formatInt(session.IdleSeconds),
+			session.Project,
+			session.Category,
+			session.Container,
+			uid,
+			strings.Join(session.Tags, ","),
+			session.Notes,
+			strconv.FormatBool(session.Manual),
+			strconv.FormatBool(session.Recovered),
+		})
+	}
+	return header, rows
+}
+
+// Sessions merged away by "history merge", for each program that had any
+type MergeSummary struct {
+	Programs []MergedProgram `json:"programs"`
+}
+
+type MergedProgram struct {
+	Program string `json:"program"`
+	Merged  int64  `json:"merged"`
+}
+
+func (m MergeSummary) WriteText(w io.Writer) {
+	for _, program := range m.Programs {
+		fmt.Fprintf(w, "Merged %d sessions for %s\n", program.Merged, program.Program)
+	}
+}
+
+func (m MergeSummary) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(m.Programs))
+	for _, program := range m.Programs {
+		rows = append(rows, []string{program.Program, formatInt(program.Merged)})
+	}
+	return []string{"program", "merged"}, rows
+}
+
+// Open sessions and paused programs, from "active"
+type ActiveList struct {
+	Paused   []PauseRecord  `json:"paused"`
+	Sessions []ActiveRecord `json:"sessions"`
+}
+
+// Pause of tracking for a program, or for every program
+type PauseRecord struct {
+	Program  string     `json:"program,omitempty"` // Omitted for a pause of every program
+	All      bool       `json:"all"`               // Pause covers every program
+	ResumeAt *time.Time `json:"resume_at"`         // Null if paused until resumed by hand
+}
+
+// Single open session
+type ActiveRecord struct {
+	Program        string    `json:"program"`
+	Start          time.Time `json:"start"`
+	ElapsedSeconds int64     `json:"elapsed_seconds"`
+	IdleSeconds    int64     `json:"idle_seconds"`
+	Project        string    `json:"project,omitempty"`
+	Category       string    `json:"category,omitempty"`
+	Tags           []string  `json:"tags"`
+	Notes          string    `json:"notes,omitempty"`
+	Timer          bool      `json:"timer"` // Manual timer rather than a process session
+}
+
+// Builds the result record of a saved pause
+func newPauseRecord(pause database.TrackingPause) PauseRecord {
+	record := PauseRecord{Program: pause.ProgramName, All: pause.ProgramName == ""}
+	if pause.ResumeAt.Valid {
+		resumeAt := pause.ResumeAt.Time.Local()
+		record.ResumeAt = &resumeAt
+	}
+	return record
+}
+
+// Builds the result record of an open session, elapsed up to now
+func newActiveRecord(session database.ActiveSession, now time.Time) ActiveRecord {
+	record := ActiveRecord{
+		Program:        session.ProgramName,
+		Start:          session.StartTime.Local(),
+		ElapsedSeconds: int64(now.Sub(session.StartTime).Seconds()),
+		IdleSeconds:    session.IdleSeconds,
+		Project:        session.Project.String,
+		Category:       session.Category.String,
+		Tags:           tags.Parse(session.Tags),
+		Notes:          session.Notes.String,
+		Timer:          session.Manual,
+	}
+	if record.Tags == nil {
+		record.Tags = []string{}
+	}
+	return record
+}
+
+func (l ActiveList) WriteText(w io.Writer) {
+	for _, pause := range l.Paused {
+		if pause.ResumeAt != nil {
+			fmt.Fprintf(w, "Tracking of %s paused until %s\n", pauseTarget(pause.Program), pause.ResumeAt.Format(sessionTimeLayout))
+		} else {
+			fmt.Fprintf(w, "Tracking of %s paused\n", pauseTarget(pause.Program))
+		}
+	}
+
+	for _, session := range l.Sessions {
+		label := session.Program
+		if session.Timer {
+			label += " (timer)"
+		}
+		fmt.Fprintf(w, " • %s - %s\n", label, durationString(time.Duration(session.ElapsedSeconds)*time.Second))
+	}
+}
+
+// Rows of the open sessions, paused programs are only part of text and JSON output
+func (l ActiveList) Table() ([]string, [][]string) {
+	header := []string{"program", "start", "elapsed_seconds", "idle_seconds", "project", "category", "tags", "notes", "timer"}
+	rows := make([][]string, 0, len(l.Sessions))
+	for _, session := range l.Sessions {
+		rows = append(rows, []string{
+			session.Program,
+			formatTime(session.Start),
+			formatInt(session.ElapsedSeconds),
+			formatInt(session.IdleSeconds),
+			session.Project,
+			session.Category,
+			strings.Join(session.Tags, ","),
+			session.Notes,
+			strconv.FormatBool(session.Timer),
+		})
+	}
+	return header, rows
+}
+
+// Time tracked within a period grouped by one field, from "report"
+type Report struct {
+	Period       string        `json:"period"`
+	GroupBy      string        `json:"group_by"`
+	Start        time.Time     `json:"start"`
+	End          time.Time     `json:"end"`           // Exclusive, midnight after the last day of the period
+	TotalSeconds int64         `json:"total_seconds"` // Time of sessions inside the period
+	Sessions     int64         `json:"sessions"`      // Sessions running within the period
+	Groups       []ReportGroup `json:"groups"`
+}
+
+type ReportGroup struct {
+	Name         string  `json:"name"` // Empty for sessions without a category, project or tag
+	Sessions     int64   `json:"sessions"`
+	TotalSeconds int64   `json:"total_seconds"`
+	Share        float64 `json:"share"`        // Percentage of the time tracked in the period
+	PeriodShare  float64 `json:"period_share"` // Percentage of the period, up to now for the current period
+}
+
+func (r Report) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Report for %s, by %s\n", periodString(r.Start, r.End), r.GroupBy)
+
+	if r.Sessions == 0 {
+		fmt.Fprintf(w, "  No sessions in period\n")
+		return
+	}
+
+	width := 0
+	for _, group := range r.Groups {
+		width = max(width, len(r.groupName(group)))
+	}
+	for _, group := range r.Groups {
+		fmt.Fprintf(w, "  %-*s  %9s  %5.1f%%  %5.1f%% of period  (%d sessions)\n",
+			width, r.groupName(group),
+			durationString(time.Duration(group.TotalSeconds)*time.Second),
+			group.Share,
+			group.PeriodShare,
+			group.Sessions)
+	}
+	fmt.Fprintf(w, "  Total: %s (%d sessions)\n", durationString(time.Duration(r.TotalSeconds)*time.Second), r.Sessions)
+}
+
+func (r Report) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(r.Groups))
+	for _, group := range r.Groups {
+		rows = append(rows, []string{
+			group.Name,
+			formatInt(group.Sessions),
+			formatInt(group.TotalSeconds),
+			formatPercent(group.Share),
+			formatPercent(group.PeriodShare),
+		})
+	}
+	return []string{r.GroupBy, "sessions", "total_seconds", "share", "period_share"}, rows
+}
+
+// Name of a group in text output, naming the group of sessions without a category, project or tag
+func (r Report) groupName(group ReportGroup) string {
+	switch {
+	case group.Name != "":
+		return group.Name
+	case r.GroupBy == "tag":
+		return "(untagged)"
+	default:
+		return "(none)"
+	}
+}
+
+// Bar chart of the time per program (or other group) within a period, from "chart bars". Holds the fields of "report"
+type BarChart struct {
+	Report
+	options chart.Options
+}
+
+func (c BarChart) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Time by %s, %s\n", c.GroupBy, periodString(c.Start, c.End))
+	if c.Sessions == 0 {
+		fmt.Fprintf(w, "  No sessions in period\n")
+		return
+	}
+
+	bars := make([]chart.Bar, 0, len(c.Groups))
+	for _, group := range c.Groups {
+		bars = append(bars, chart.Bar{
+			Label: c.groupName(group),
+			Value: group.TotalSeconds,
+			Text:  fmt.Sprintf("%s (%.1f%%)", durationString(time.Duration(group.TotalSeconds)*time.Second), group.Share),
+		})
+	}
+	chart.Bars(w, bars, c.options)
+	fmt.Fprintf(w, "  Total: %s (%d sessions)\n", durationString(time.Duration(c.TotalSeconds)*time.Second), c.Sessions)
+}
+
+// Calendar heatmap of daily totals, from "chart calendar"
+type CalendarChart struct {
+	Start   time.Time  `json:"start"`
+	End     time.Time  `json:"end"`
+	Days    []DayTotal `json:"days"` // Every day of the period up to today, oldest first
+	options chart.Options
+}
+
+type DayTotal struct {
+	Date         string `json:"date"` // Local date, formatted 2006-01-02
+	TotalSeconds int64  `json:"total_seconds"`
+}
+
+func (c CalendarChart) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Daily totals, %s\n", periodString(c.Start, c.End))
+
+	days := make([]chart.Day, 0, len(c.Days))
+	var total int64
+	busiest := DayTotal{}
+	for _, day := range c.Days {
+		date, _ := report.ParseDay(day.Date)
+		days = append(days, chart.Day{Date: date, Seconds: day.TotalSeconds})
+		total += day.TotalSeconds
+		if day.TotalSeconds > busiest.TotalSeconds {
+			busiest = day
+		}
+	}
+	chart.Calendar(w, days, c.options)
+
+	fmt.Fprintf(w, "  Total: %s", durationString(time.Duration(total)*time.Second))
+	if busiest.TotalSeconds > 0 {
+		fmt.Fprintf(w, ", busiest day %s (%s)", busiest.Date, durationString(time.Duration(busiest.TotalSeconds)*time.Second))
+	}
+	fmt.Fprintln(w)
+}
+
+func (c CalendarChart) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(c.Days))
+	for _, day := range c.Days {
+		rows = append(rows, []string{day.Date, formatInt(day.TotalSeconds)})
+	}
+	return []string{"date", "total_seconds"}, rows
+}
+
+// Heatmap of time by weekday and hour of the day, from "chart hours"
+type HourChart struct {
+	Start   time.Time   `json:"start"`
+	End     time.Time   `json:"end"`
+	Hours   []HourTotal `json:"hours"` // Every hour of the week, Monday 00:00 first
+	options chart.Options
+}
+
+type HourTotal struct {
+	Weekday      string `json:"weekday"`
+	Hour         int    `json:"hour"`
+	TotalSeconds int64  `json:"total_seconds"`
+}
+
+func (c HourChart) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Time by weekday and hour, %s\n", periodString(c.Start, c.End))
+
+	var grid [7][24]int64
+	for i, hour := range c.Hours {
+		grid[i/24][hour.Hour] = hour.TotalSeconds
+	}
+	chart.HourGrid(w, grid, c.options)
+}
+
+func (c HourChart) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(c.Hours))
+	for _, hour := range c.Hours {
+		rows = append(rows, []string{hour.Weekday, strconv.Itoa(hour.Hour), formatInt(hour.TotalSeconds)})
+	}
+	return []string{"weekday", "hour", "total_seconds"}, rows
+}
+
+// Describes the days from start through the day before end, the exclusive end of a period
+func periodString(start, end time.Time) string {
+	first, last := start.Format(report.DateLayout), end.AddDate(0, 0, -1).Format(report.DateLayout)
+	if first == last {
+		return first
+	}
+	return first + " - " + last
+}
+
+// Writes a session in "history" text output, prefixed by the ID used to edit or remove it
+func writeSession(w io.Writer, session SessionRecord) {
+	fmt.Fprintf(w, "  #%d %s | %s - %s | ",
+		session.ID,
+		session.Program,
+		session.Start.Format(sessionTimeLayout),
+		session.End.Format(sessionTimeLayout))
+
+	if session.Project != "" {
+		fmt.Fprintf(w, "Project: %s | ", session.Project)
+	}
+	if session.Container != "" {
+		fmt.Fprintf(w, "Container: %s | ", session.Container)
+	}
+	if len(session.Tags) > 0 {
+		fmt.Fprintf(w, "Tags: %s | ", strings.Join(session.Tags, ", "))
+	}
+
+	fmt.Fprintf(w, "Duration: %s", durationString(time.Duration(session.DurationSeconds)*time.Second))
+	if session.IdleSeconds > 0 { // Idle detection split the session, show the time the program was in use
+		active := time.Duration(session.DurationSeconds-session.IdleSeconds) * time.Second
+		fmt.Fprintf(w, " (active %s)", durationString(active))
+	}
+	if session.Recovered { // Closed after a service crash, end time is the last recorded heartbeat
+		fmt.Fprintf(w, " (recovered)")
+	}
+	if session.Manual { // Recorded by a manual timer rather than a process
+		fmt.Fprintf(w, " (manual)")
+	}
+	fmt.Fprintln(w)
+	if session.Notes != "" {
+		fmt.Fprintf(w, "      %s\n", session.Notes)
+	}
+}
+
+func formatInt(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+func formatPercent(f float64) string {
+	return strconv.FormatFloat(f, 'f', 1, 64)
+}
+
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
@@ -17,8 +17,15 @@ func (s *CLIService) RootCmd() *cobra.Command {
 		Short: "Timekeep is a process activity tracker",
 		Run: func(cmd *cobra.Command, args []string) {
 		},
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("output")
+			_, err := newFormatter(format)
+			return err
+		},
 	}
 
+	rootCmd.PersistentFlags().String("output", "text", "Output format of read commands (ls, info, history, active): text, json, csv or tsv")
+
 	wCmd := s.wakatimeIntegration()
 	wCmd.AddCommand(s.wakatimeStatus())
 	wCmd.AddCommand(s.wakatimeEnable())
@@ -29,18 +36,37 @@ func (s *CLIService) RootCmd() *cobra.Command {
 	wpCmd.AddCommand(s.wakapiEnable())
 	wpCmd.AddCommand(s.wakapiDisable())
 
+	hCmd := s.sessionHistoryCmd()
+	hCmd.AddCommand(s.mergeHistoryCmd())
+
+	sCmd := s.sessionCmd()
+	sCmd.AddCommand(s.addSessionCmd())
+	sCmd.AddCommand(s.editSessionCmd())
+	sCmd.AddCommand(s.removeSessionCmd())
+	sCmd.AddCommand(s.tagSessionCmd())
+	sCmd.AddCommand(s.annotateSessionCmd())
+
 	rootCmd.AddCommand(wCmd)
 	rootCmd.AddCommand(wpCmd)
 	rootCmd.AddCommand(s.addProgramsCmd())
 	rootCmd.AddCommand(s.updateCmd())
+	rootCmd.AddCommand(s.excludeCmd())
 	rootCmd.AddCommand(s.removeProgramsCmd())
 	rootCmd.AddCommand(s.getListcmd())
 	rootCmd.AddCommand(s.infoCmd())
-	rootCmd.AddCommand(s.sessionHistoryCmd())
+	rootCmd.AddCommand(hCmd)
+	rootCmd.AddCommand(s.reportCmd())
+	rootCmd.AddCommand(s.chartCmd())
+	rootCmd.AddCommand(sCmd)
 	rootCmd.AddCommand(s.refreshCmd())
 	rootCmd.AddCommand(s.resetStatsCmd())
 	rootCmd.AddCommand(s.statusServiceCmd())
 	rootCmd.AddCommand(s.getActiveSessionsCmd())
+	rootCmd.AddCommand(s.dashboardCmd())
+	rootCmd.AddCommand(s.startTimerCmd())
+	rootCmd.AddCommand(s.stopTimerCmd())
+	rootCmd.AddCommand(s.pauseCmd())
+	rootCmd.AddCommand(s.resumeCmd())
 	rootCmd.AddCommand(s.getVersionCmd())
 	rootCmd.AddCommand(s.setConfigCmd())
 
@@ -0,0 +1,16 @@
+//go:build !windows && !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+func terminalSize(f *os.File) (int, int, bool) {
+	return 0, 0, false
+}
+
+func rawTerminal(in, out *os.File) (func(), error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
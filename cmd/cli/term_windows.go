@@ -0,0 +1,46 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// Returns the size in columns and rows of the console f writes to, reporting false if f isn't a console
+func terminalSize(f *os.File) (int, int, bool) {
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(f.Fd()), &info); err != nil {
+		return 0, 0, false
+	}
+	return int(info.Window.Right-info.Window.Left) + 1, int(info.Window.Bottom-info.Window.Top) + 1, true
+}
+
+// Puts the console in raw mode, reading key presses one at a time without echoing them, with ANSI escapes enabled on
+// both input and output. Returns a function restoring the previous modes
+func rawTerminal(in, out *os.File) (func(), error) {
+	inHandle, outHandle := windows.Handle(in.Fd()), windows.Handle(out.Fd())
+
+	var inMode, outMode uint32
+	if err := windows.GetConsoleMode(inHandle, &inMode); err != nil {
+		return nil, err
+	}
+	if err := windows.GetConsoleMode(outHandle, &outMode); err != nil {
+		return nil, err
+	}
+
+	rawIn := inMode&^(windows.ENABLE_ECHO_INPUT|windows.ENABLE_PROCESSED_INPUT|windows.ENABLE_LINE_INPUT) | windows.ENABLE_VIRTUAL_TERMINAL_INPUT
+	if err := windows.SetConsoleMode(inHandle, rawIn); err != nil {
+		return nil, err
+	}
+	if err := windows.SetConsoleMode(outHandle, outMode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING); err != nil {
+		_ = windows.SetConsoleMode(inHandle, inMode)
+		return nil, err
+	}
+
+	return func() {
+		_ = windows.SetConsoleMode(inHandle, inMode)
+		_ = windows.SetConsoleMode(outHandle, outMode)
+	}, nil
+}
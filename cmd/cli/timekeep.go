@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"strconv"
 
 	"github.com/spf13/cobra"
 )
@@ -37,13 +39,21 @@ func (s *CLIService) addProgramsCmd() *cobra.Command {
 
 			category, _ := cmd.Flags().GetString("category")
 			project, _ := cmd.Flags().GetString("project")
+			match, _ := cmd.Flags().GetString("match")
+			exe, _ := cmd.Flags().GetString("exe")
+			argsContains, _ := cmd.Flags().GetString("args-contains")
+			scope, _ := cmd.Flags().GetString("scope")
 
-			return s.AddPrograms(ctx, args, category, project)
+			return s.AddPrograms(ctx, args, category, project, match, exe, argsContains, scope)
 		},
 	}
 
 	cmd.Flags().String("category", "", "Add category to tracked program(s). Category provided will be applied to all programs passed as arguments. (required for WakaTime integration)")
 	cmd.Flags().String("project", "", "Add project to tracked program(s). Project will be applied to all programs passed as arguments.")
+	cmd.Flags().String("match", "exact", "How program names are matched against process names: exact, glob or regex (glob/regex Linux only). All matching processes are tracked as one program")
+	cmd.Flags().String("exe", "", "Match processes of this executable (ex. python, java, node) by command line, tracking them under the given program name (Linux only)")
+	cmd.Flags().String("args-contains", "", "Match processes whose full command line contains this text (ex. \"-m mytool\"), tracking them under the given program name (Linux only)")
+	cmd.Flags().String("scope", "any", "Where processes are tracked: host (outside containers), container (inside Docker/Podman containers) or any (Linux only)")
 
 	return cmd
 }
@@ -59,13 +69,52 @@ func (s *CLIService) updateCmd() *cobra.Command {
 
 			category, _ := cmd.Flags().GetString("category")
 			project, _ := cmd.Flags().GetString("project")
+			scope, _ := cmd.Flags().GetString("scope")
+			minSession, _ := cmd.Flags().GetString("min-session")
 
-			return s.UpdateProgram(ctx, args, category, project)
+			return s.UpdateProgram(ctx, args, category, project, scope, minSession)
 		},
 	}
 
 	cmd.Flags().String("category", "", "Alter program's category field")
 	cmd.Flags().String("project", "", "Alter program's project field")
+	cmd.Flags().String("scope", "", "Alter program's scope: host, container or any (Linux only)")
+	cmd.Flags().String("min-session", "", "Alter program's minimum session length, shorter sessions are discarded, ex. '5s' ('default' to use the global min_session)")
+
+	return cmd
+}
+
+func (s *CLIService) excludeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "exclude",
+		Aliases: []string{"EXCLUDE"},
+		Short:   "Exclude a tracked program's helper processes from its sessions",
+		Long:    "Processes matching any exclusion rule aren't counted towards the program's sessions, ex. browser renderer or crashpad helpers. Flags may be repeated. With no flags, lists the program's current rules (Linux only)",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			argsRules, _ := cmd.Flags().GetStringSlice("args")
+			parentRules, _ := cmd.Flags().GetStringSlice("parent")
+			pathRules, _ := cmd.Flags().GetStringSlice("path")
+			clear, _ := cmd.Flags().GetBool("clear")
+
+			if !clear && len(argsRules)+len(parentRules)+len(pathRules) == 0 {
+				list, err := s.GetExclusions(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				return render(cmd, list)
+			}
+
+			return s.ExcludeProcesses(ctx, args[0], argsRules, parentRules, pathRules, clear)
+		},
+	}
+
+	cmd.Flags().StringSlice("args", nil, "Exclude processes whose full command line contains this text (ex. --type=renderer)")
+	cmd.Flags().StringSlice("parent", nil, "Exclude processes whose parent executable has this name (ex. chrome)")
+	cmd.Flags().StringSlice("path", nil, "Exclude processes whose executable path begins with this prefix (ex. /opt/google/chrome/chrome_crashpad_handler)")
+	cmd.Flags().Bool("clear", false, "Removes the program's existing exclusion rules, before adding any given")
 
 	return cmd
 }
@@ -100,13 +149,18 @@ func (s *CLIService) getListcmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 
-			return s.GetList(ctx)
+			list, err := s.GetList(ctx)
+			if err != nil {
+				return err
+			}
+
+			return render(cmd, list)
 		},
 	}
 }
 
 func (s *CLIService) infoCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:     "info",
 		Aliases: []string{"Info", "INFO"},
 		Short:   "Shows basic info for currently tracked programs",
@@ -115,13 +169,35 @@ func (s *CLIService) infoCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 
+			date, _ := cmd.Flags().GetString("date")
+			start, _ := cmd.Flags().GetString("start")
+			end, _ := cmd.Flags().GetString("end")
+			minDuration, _ := cmd.Flags().GetString("min-duration")
+			tag, _ := cmd.Flags().GetString("tag")
+
 			if len(args) == 0 {
-				return s.GetAllInfo(ctx)
-			} else {
-				return s.GetInfo(ctx, args)
+				summaries, err := s.GetAllInfo(ctx, date, start, end, minDuration, tag)
+				if err != nil {
+					return err
+				}
+				return render(cmd, summaries)
+			}
+
+			info, err := s.GetInfo(ctx, args, date, start, end, minDuration, tag)
+			if err != nil {
+				return err
 			}
+			return render(cmd, info)
 		},
 	}
+
+	cmd.Flags().String("date", "", "Only count sessions within a day or period in session stats, ex. 'today', 'last week', '-7d'")
+	cmd.Flags().String("start", "", "Only count sessions from this time on in session stats, ex. 'monday', '2025-09-30 13:00'")
+	cmd.Flags().String("end", "", "Only count sessions up to this time in session stats, defaults to now")
+	cmd.Flags().String("min-duration", "", "Only count sessions at least this long in session stats, ex. '10s'")
+	cmd.Flags().String("tag", "", "Only count sessions with the given tag in session stats")
+
+	return cmd
 }
 
 func (s *CLIService) sessionHistoryCmd() *cobra.Command {
@@ -137,20 +213,261 @@ func (s *CLIService) sessionHistoryCmd() *cobra.Command {
 			date, _ := cmd.Flags().GetString("date")
 			start, _ := cmd.Flags().GetString("start")
 			end, _ := cmd.Flags().GetString("end")
+			user, _ := cmd.Flags().GetString("user")
+			minDuration, _ := cmd.Flags().GetString("min-duration")
+			tag, _ := cmd.Flags().GetString("tag")
 			limit, _ := cmd.Flags().GetInt64("limit")
 
-			return s.GetSessionHistory(ctx, args, date, start, end, limit)
+			history, err := s.GetSessionHistory(ctx, args, date, start, end, user, minDuration, tag, limit)
+			if err != nil {
+				return err
+			}
+
+			return render(cmd, history)
 		},
 	}
 
-	cmd.Flags().String("date", "", "Filter session history by date")
-	cmd.Flags().String("start", "", "Filters session history by adding a starting date")
-	cmd.Flags().String("end", "", "Filters session history by adding an ending date")
+	cmd.Flags().String("date", "", "Filters session history by day or period, ex. 'today', 'yesterday', 'last week', '-7d', '2025-09-30'")
+	cmd.Flags().String("start", "", "Filters session history by adding a starting time, ex. 'monday', '-2h', '2025-09-30T13:00+02:00'")
+	cmd.Flags().String("end", "", "Filters session history by adding an ending time, defaults to now")
+	cmd.Flags().String("user", "", "Filters session history by the user (name or UID) owning the session's processes (Linux only)")
+	cmd.Flags().String("min-duration", "", "Filters out sessions shorter than the given duration, ex. '10s'")
+	cmd.Flags().String("tag", "", "Filters session history by tag")
 	cmd.Flags().Int64("limit", 25, "Adjusts number limit of sessions shown")
 
 	return cmd
 }
 
+func (s *CLIService) reportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "report",
+		Aliases: []string{"Report", "REPORT"},
+		Short:   "Shows time tracked within a day, week or month, grouped by program, category, project, tag, weekday or hour",
+		Long:    "Totals session history within the period containing --date (today if not given), or from --start through --end with --period custom. Sessions running across the edges of the period only count the time inside it. Each group shows its share of the time tracked in the period, and of the period itself",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			period, _ := cmd.Flags().GetString("period")
+			groupBy, _ := cmd.Flags().GetString("group-by")
+			date, _ := cmd.Flags().GetString("date")
+			start, _ := cmd.Flags().GetString("start")
+			end, _ := cmd.Flags().GetString("end")
+
+			result, err := s.GetReport(ctx, period, groupBy, date, start, end)
+			if err != nil {
+				return err
+			}
+
+			return render(cmd, result)
+		},
+	}
+
+	cmd.Flags().String("period", "week", "Period to report on: day, week, month or custom")
+	cmd.Flags().String("group-by", "program", "Group time by program, category, project, tag, weekday or hour")
+	cmd.Flags().String("date", "", "Date within the day, week or month to report on, ex. 'yesterday', 'last month', defaults to today")
+	cmd.Flags().String("start", "", "Start of a custom period, ex. '2025-09-01', '-14d'")
+	cmd.Flags().String("end", "", "End of a custom period, ex. 'yesterday', '-1d', defaults to the end of today")
+
+	return cmd
+}
+
+func (s *CLIService) chartCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:       "chart [bars|calendar|hours]",
+		Aliases:   []string{"Chart", "CHART"},
+		Short:     "Draws charts of session history: time per program, a calendar heatmap of daily totals, or an hour by weekday heatmap",
+		Long:      "Draws bars of the time per program (or --group-by) within a period (the default), a calendar heatmap of daily totals over the last year, or a heatmap of time by hour of the day and weekday over the month. Charts fit the terminal's width and are colored unless NO_COLOR is set or output isn't a terminal",
+		Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{"bars", "calendar", "hours"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			kind := "bars"
+			if len(args) != 0 {
+				kind = args[0]
+			}
+			period, _ := cmd.Flags().GetString("period")
+			groupBy, _ := cmd.Flags().GetString("group-by")
+			date, _ := cmd.Flags().GetString("date")
+			start, _ := cmd.Flags().GetString("start")
+			end, _ := cmd.Flags().GetString("end")
+			opts := chartOptions(cmd)
+
+			switch kind {
+			case "calendar":
+				calendar, err := s.GetCalendar(ctx, period, date, start, end)
+				if err != nil {
+					return err
+				}
+				calendar.options = opts
+				return render(cmd, calendar)
+			case "hours":
+				hours, err := s.GetHourChart(ctx, period, date, start, end)
+				if err != nil {
+					return err
+				}
+				hours.options = opts
+				return render(cmd, hours)
+			default:
+				if period == "" {
+					period = "week"
+				}
+				result, err := s.GetReport(ctx, period, groupBy, date, start, end)
+				if err != nil {
+					return err
+				}
+				return render(cmd, BarChart{Report: result, options: opts})
+			}
+		},
+	}
+
+	cmd.Flags().String("period", "", "Period to chart: day, week, month or custom (defaults to the week for bars, the last year for calendar and the month for hours)")
+	cmd.Flags().String("group-by", "program", "Group bars by program, category, project, tag, weekday or hour")
+	cmd.Flags().String("date", "", "Date within the period to chart, ex. 'last week', defaults to today")
+	cmd.Flags().String("start", "", "Start of a custom period, ex. '2025-09-01', '-14d'")
+	cmd.Flags().String("end", "", "End of a custom period, ex. 'yesterday', '-1d', defaults to the end of today")
+	cmd.Flags().Bool("ascii", false, "Draw with ASCII characters only")
+
+	return cmd
+}
+
+func (s *CLIService) mergeHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "merge",
+		Short: "Merge sessions separated by short gaps",
+		Long:  "Merges history sessions that started within the gap of the previous session's end, ex. after an app restart. Program names may be given to only merge their sessions, otherwise all programs are merged",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			gap, _ := cmd.Flags().GetString("gap")
+
+			summary, err := s.MergeHistory(cmd.Context(), args, gap)
+			if err != nil {
+				return err
+			}
+
+			return render(cmd, summary)
+		},
+	}
+
+	cmd.Flags().String("gap", "", "Largest gap between sessions to merge, ex. '30s' (defaults to the configured merge_gap)")
+
+	return cmd
+}
+
+func (s *CLIService) sessionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "session",
+		Aliases: []string{"Session", "SESSION"},
+		Short:   "Add, edit or remove history sessions",
+	}
+}
+
+func (s *CLIService) addSessionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add",
+		Short: "Add a session to a program's history",
+		Long:  "Adds a session for time the service didn't track, flagged manual. Times are in local time, formatted '2006-01-02 15:04'. The session may not overlap the program's other sessions",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			start, _ := cmd.Flags().GetString("start")
+			end, _ := cmd.Flags().GetString("end")
+			project, _ := cmd.Flags().GetString("project")
+
+			return s.AddSession(ctx, args[0], start, end, project)
+		},
+	}
+
+	cmd.Flags().String("start", "", "Start time of the session, ex. '2025-09-30 13:00'")
+	cmd.Flags().String("end", "", "End time of the session, ex. '2025-09-30 14:30'")
+	cmd.Flags().String("project", "", "Set project for the session")
+
+	return cmd
+}
+
+func (s *CLIService) editSessionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "edit",
+		Short: "Edit a history session",
+		Long:  "Changes the start, end or project of the history session with the given ID, as shown by 'timekeep history'. The program's lifetime is recomputed to match",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid session ID %q", args[0])
+			}
+
+			start, _ := cmd.Flags().GetString("start")
+			end, _ := cmd.Flags().GetString("end")
+			project, _ := cmd.Flags().GetString("project")
+
+			return s.EditSession(ctx, id, start, end, project)
+		},
+	}
+
+	cmd.Flags().String("start", "", "New start time of the session, ex. '2025-09-30 13:00'")
+	cmd.Flags().String("end", "", "New end time of the session, ex. '2025-09-30 14:30'")
+	cmd.Flags().String("project", "", "New project of the session ('none' to clear)")
+
+	return cmd
+}
+
+func (s *CLIService) removeSessionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rm",
+		Short: "Remove a history session",
+		Long:  "Removes the history session with the given ID, as shown by 'timekeep history'. The program's lifetime is recomputed to match",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid session ID %q", args[0])
+			}
+
+			return s.RemoveSession(ctx, id)
+		},
+	}
+}
+
+func (s *CLIService) tagSessionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tag",
+		Short: "Tag a session",
+		Long:  "Adds tags to a session, given either the ID of a history session or the name of a program (or timer label) to tag its active session. Tags of an active session are kept when it moves into history",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			remove, _ := cmd.Flags().GetBool("remove")
+
+			return s.TagSession(ctx, args[0], args[1:], remove)
+		},
+	}
+
+	cmd.Flags().Bool("remove", false, "Remove the given tags instead of adding them")
+
+	return cmd
+}
+
+func (s *CLIService) annotateSessionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "annotate",
+		Short: "Set the notes of a session",
+		Long:  "Sets the notes of a session, given either the ID of a history session or the name of a program (or timer label) to annotate its active session. Empty notes clear them",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			return s.AnnotateSession(ctx, args[0], args[1])
+		},
+	}
+}
+
 func (s *CLIService) refreshCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:     "refresh",
@@ -210,7 +527,108 @@ func (s *CLIService) getActiveSessionsCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 
-			return s.GetActiveSessions(ctx)
+			active, err := s.GetActiveSessions(ctx)
+			if err != nil {
+				return err
+			}
+
+			return render(cmd, active)
+		},
+	}
+}
+
+func (s *CLIService) dashboardCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "dashboard",
+		Aliases: []string{"dash", "Dashboard", "DASHBOARD"},
+		Short:   "Open a live full-screen dashboard of tracking",
+		Long:    "Shows active sessions with ticking durations, today's totals per program and category, recent history and the service's health, reloaded from the database every --interval. Keys: up/down (or j/k) select a session, enter (or h) shows the selected program's history, p pauses tracking of the selected program, P pauses all tracking, u resumes it, r reloads and q quits",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			interval, _ := cmd.Flags().GetString("interval")
+
+			out, ok := cmd.OutOrStdout().(*os.File)
+			if !ok {
+				return fmt.Errorf("dashboard needs a terminal, use timekeep active for a single snapshot")
+			}
+
+			return s.RunDashboard(cmd.Context(), os.Stdin, out, interval)
+		},
+	}
+
+	cmd.Flags().String("interval", "2s", "How often to reload data from the database, ex. '5s'")
+
+	return cmd
+}
+
+func (s *CLIService) startTimerCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "start",
+		Aliases: []string{"Start", "START"},
+		Short:   "Start a manual timer",
+		Long:    "Starts a manual timer for an activity that isn't a process, ex. a meeting. The timer is recorded as a session under the given label until stopped, and sent in WakaTime/Wakapi heartbeats if given a category",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			category, _ := cmd.Flags().GetString("category")
+			project, _ := cmd.Flags().GetString("project")
+
+			return s.StartTimer(ctx, args[0], category, project)
+		},
+	}
+
+	cmd.Flags().String("category", "", "Set category for the timer, required for WakaTime/Wakapi heartbeats (ex. 'meeting')")
+	cmd.Flags().String("project", "", "Set project for the timer")
+
+	return cmd
+}
+
+func (s *CLIService) stopTimerCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "stop",
+		Aliases: []string{"Stop", "STOP"},
+		Short:   "Stop a manual timer",
+		Long:    "Stops the manual timer with the given label, moving its session into history. Stops all running timers if no label is given",
+		Args:    cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			return s.StopTimer(ctx, args)
+		},
+	}
+}
+
+func (s *CLIService) pauseCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "pause",
+		Aliases: []string{"Pause", "PAUSE"},
+		Short:   "Pause tracking",
+		Long:    "Pauses tracking of the given programs, or of every program if none are given, ending their active sessions. Paused programs keep their config, and stay paused across service restarts until resumed or the --for duration runs out. Manual timers aren't paused",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			duration, _ := cmd.Flags().GetString("for")
+
+			return s.PauseTracking(ctx, args, duration)
+		},
+	}
+
+	cmd.Flags().String("for", "", "Resume tracking automatically after this long (ex. '30m')")
+
+	return cmd
+}
+
+func (s *CLIService) resumeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "resume",
+		Aliases: []string{"Resume", "RESUME"},
+		Short:   "Resume paused tracking",
+		Long:    "Resumes tracking of the given paused programs, or of everything paused if no programs are given",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			return s.ResumeTracking(ctx, args)
 		},
 	}
 }
@@ -337,13 +755,24 @@ func (s *CLIService) setConfigCmd() *cobra.Command {
 		Aliases: []string{"Config", "CONFIG"},
 		Short:   "Set various config values",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			cliPath, _ := cmd.Flags().GetString("cli_path")
-			server, _ := cmd.Flags().GetString("server")
-			project, _ := cmd.Flags().GetString("global_project")
-			interval, _ := cmd.Flags().GetString("poll_interval")
-			grace, _ := cmd.Flags().GetInt("poll_grace")
+			var u configUpdate
+			u.cliPath, _ = cmd.Flags().GetString("cli_path")
+			u.server, _ = cmd.Flags().GetString("server")
+			u.project, _ = cmd.Flags().GetString("global_project")
+			u.interval, _ = cmd.Flags().GetString("poll_interval")
+			u.backend, _ = cmd.Flags().GetString("monitor_backend")
+			u.detectProject, _ = cmd.Flags().GetString("detect_project")
+			u.idleTimeout, _ = cmd.Flags().GetString("idle_timeout")
+			u.idleInput, _ = cmd.Flags().GetString("idle_input")
+			u.mergeGap, _ = cmd.Flags().GetString("merge_gap")
+			u.minSession, _ = cmd.Flags().GetString("min_session")
+			u.splitDays, _ = cmd.Flags().GetString("split_days")
+			if cmd.Flags().Changed("poll_grace") {
+				grace, _ := cmd.Flags().GetInt("poll_grace")
+				u.pollGrace = &grace
+			}
 
-			return s.SetConfig(cliPath, server, project, interval, grace)
+			return s.SetConfig(u)
 		},
 	}
 
@@ -352,6 +781,13 @@ func (s *CLIService) setConfigCmd() *cobra.Command {
 	cmd.Flags().String("global_project", "", "Set global project variable for WakaTime/Wakapi data sorting")
 	cmd.Flags().String("poll_interval", "", "Set the polling interval for process monitoring for Linux version")
 	cmd.Flags().Int("poll_grace", 3, "Set grace period for PIDs missed via polling (process will only register as finished after 'poll_interval * poll_grace' ex. '1s * 3 = 3s')")
+	cmd.Flags().String("monitor_backend", "", "Set the process monitor backend for Linux version, 'poll' or 'netlink' (netlink falls back to polling if unavailable)")
+	cmd.Flags().String("detect_project", "", "Set 'true' to detect session projects from the working directory of tracked processes, for Linux version ('false' to disable)")
+	cmd.Flags().String("idle_timeout", "", "Set how long a running program may go unused before its session counts as idle, for Linux version ex. '5m' ('off' to disable)")
+	cmd.Flags().String("idle_input", "", "Set a system input source also used for idle detection, 'interrupts' or 'logind' ('none' to use process CPU time only)")
+	cmd.Flags().String("merge_gap", "", "Set how soon after a program's last session ends a new one extends it instead, ex. '30s' ('off' to disable)")
+	cmd.Flags().String("min_session", "", "Set the minimum session length, shorter sessions are discarded instead of moved to history, ex. '5s' ('off' to disable)")
+	cmd.Flags().String("split_days", "", "Set 'true' to store sessions crossing midnight as one history row per local day ('false' to disable)")
 
 	return cmd
 }
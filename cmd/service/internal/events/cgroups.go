@@ -0,0 +1,92 @@
+package events
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Program scopes stored in tracked_programs.scope
+const (
+	ScopeAny       = "any"       // Processes are tracked wherever they run
+	ScopeHost      = "host"      // Only processes running outside of containers are tracked
+	ScopeContainer = "container" // Only processes running inside containers are tracked
+)
+
+// Container runtime scope units, ex. docker-<id>.scope, libpod-<id>.scope, cri-containerd-<id>.scope
+var containerScopeRe = regexp.MustCompile(`^(?:docker|libpod|crio|cri-containerd|containerd)-([0-9a-f]{12,})\.scope$`)
+
+// Bare container IDs used as cgroup directory names by cgroup v1 runtimes, ex. /docker/<id>, /kubepods/.../<id>
+var containerIDRe = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Where a process runs, detected from its cgroup membership
+type cgroupInfo struct {
+	Container string // Short ID of the container the process runs in, empty on the host
+	Unit      string // Systemd service or scope unit the process runs under, empty if none
+}
+
+// Parses the lines of a /proc/{pid}/cgroup file (hierarchy-ID:controllers:path). The unified (v2) hierarchy, or the
+// name=systemd v1 hierarchy, is used for the unit, while a container ID found in any hierarchy marks a container process
+func parseCgroup(lines []string) cgroupInfo {
+	var info cgroupInfo
+	var unitPath string
+
+	for _, line := range lines {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		controllers, cgPath := parts[1], parts[2]
+
+		if info.Container == "" {
+			info.Container = containerFromPath(cgPath)
+		}
+		if controllers == "" || controllers == "name=systemd" {
+			unitPath = cgPath
+		}
+	}
+
+	segments := strings.Split(unitPath, "/")
+	for i := len(segments) - 1; i >= 0; i-- { // Deepest unit is the most specific
+		seg := segments[i]
+		if containerScopeRe.MatchString(seg) {
+			continue
+		}
+		if strings.HasSuffix(seg, ".service") || strings.HasSuffix(seg, ".scope") {
+			info.Unit = seg
+			break
+		}
+	}
+
+	return info
+}
+
+// Returns the short ID of the container a cgroup path belongs to, or an empty string if it isn't a container cgroup
+func containerFromPath(cgPath string) string {
+	for _, seg := range strings.Split(cgPath, "/") {
+		id := ""
+		if m := containerScopeRe.FindStringSubmatch(seg); m != nil {
+			id = m[1]
+		} else if containerIDRe.MatchString(seg) {
+			id = seg
+		}
+		if id != "" {
+			if len(id) > 12 {
+				id = id[:12]
+			}
+			return id
+		}
+	}
+	return ""
+}
+
+// Reports whether a process running in or outside a container satisfies a program's scope
+func scopeAllows(scope, container string) bool {
+	switch scope {
+	case ScopeHost:
+		return container == ""
+	case ScopeContainer:
+		return container != ""
+	default:
+		return true
+	}
+}
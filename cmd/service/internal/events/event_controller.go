@@ -4,9 +4,11 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"io/fs"
 	"log"
 	"net"
 	"net/http"
+	"os"
 	"os/exec"
 	"strings"
 	"sync"
@@ -15,6 +17,7 @@ import (
 	"github.com/jms-guy/timekeep/cmd/service/internal/sessions"
 	"github.com/jms-guy/timekeep/internal/config"
 	"github.com/jms-guy/timekeep/internal/database"
+	"github.com/jms-guy/timekeep/internal/programs"
 	"github.com/jms-guy/timekeep/internal/repository"
 )
 
@@ -22,23 +25,35 @@ var Version = "dev"
 
 // Command details communicated by pipe
 type Command struct {
-	Action      string `json:"action"`
-	ProcessName string `json:"name,omitempty"`
-	ProcessID   int    `json:"pid,omitempty"`
+	Action      string   `json:"action"`
+	ProcessName string   `json:"name,omitempty"` // Process name, or timer label for timer actions
+	ProcessID   int      `json:"pid,omitempty"`
+	Category    string   `json:"category,omitempty"` // Category of a started timer
+	Project     string   `json:"project,omitempty"`  // Project of a started timer
+	Programs    []string `json:"programs,omitempty"` // Programs to pause or resume, all programs if empty
+	For         string   `json:"for,omitempty"`      // How long to pause for, until resumed if empty
 }
 
 type EventController struct {
-	PsProcess  *exec.Cmd          // Powershell process for Windows event monitoring
-	mu         sync.Mutex         // Mutex for context cancellations
-	MonCancel  context.CancelFunc // Monitoring function cancel context
-	WakaCancel context.CancelFunc // WakaTime function cancel context
-	Config     *config.Config     // Struct built from config file
-	Client     *http.Client       // Http Client for Wakapi heartbeat requests
-	version    string             // Timekeep version
+	PsProcess     *exec.Cmd                              // Powershell process for Windows event monitoring
+	mu            sync.Mutex                             // Mutex for context cancellations
+	cmdMu         sync.Mutex                             // Runs service commands one at a time, from connections and pause timers
+	MonCancel     context.CancelFunc                     // Monitoring function cancel context
+	WakaCancel    context.CancelFunc                     // WakaTime function cancel context
+	Config        *config.Config                         // Struct built from config file
+	Client        *http.Client                           // Http Client for Wakapi heartbeat requests
+	Procs         ProcessLister                          // Source of process information for Linux process monitoring
+	Files         fs.FS                                  // Host filesystem searched for project markers when detecting projects
+	matchers      []*programMatcher                      // Compiled glob/regex patterns of tracked programs
+	scopes        map[string]string                      // Host/container scope of tracked programs
+	exclusions    map[string][]database.ProgramExclusion // Helper process exclusion rules, by tracked program
+	excludedProcs map[sessions.ProcKey]struct{}          // Processes matched by an exclusion rule, skipped until they exit
+	uids          map[int]struct{}                       // Users whose processes are tracked by the Linux monitor, nil for all users
+	version       string                                 // Timekeep version
 }
 
 func NewEventController() *EventController {
-	return &EventController{version: Version}
+	return &EventController{Procs: NewProcfsLister("/proc"), Files: os.DirFS("/"), version: Version}
 }
 
 // Handles service commands read from pipe/socket connection
@@ -58,23 +73,36 @@ func (e *EventController) HandleConnection(serviceCtx context.Context, logger *l
 		}
 
 		cmd.ProcessName = strings.ToLower(cmd.ProcessName)
+		for i, program := range cmd.Programs {
+			cmd.Programs[i] = programs.Normalize(program)
+		}
 
 		cmdCtx, cancel := context.WithTimeout(serviceCtx, 5*time.Second)
+		e.cmdMu.Lock()
 
 		switch cmd.Action {
 		case "process_start":
-			s.CreateSession(cmdCtx, logger, a, cmd.ProcessName, cmd.ProcessID)
+			s.CreateSession(cmdCtx, logger, pr, a, h, cmd.ProcessName, sessions.ProcKey{PID: cmd.ProcessID}, sessions.ProcInfo{}, time.Time{})
 			logger.Printf("INFO: Called createSession for %s (PID: %d)", cmd.ProcessName, cmd.ProcessID)
 		case "process_stop":
-			s.EndSession(cmdCtx, logger, pr, a, h, cmd.ProcessName, cmd.ProcessID)
+			s.EndSession(cmdCtx, logger, pr, a, h, cmd.ProcessName, sessions.ProcKey{PID: cmd.ProcessID})
 			logger.Printf("INFO: Called endSession for %s (PID: %d)", cmd.ProcessName, cmd.ProcessID)
+		case "timer_start":
+			s.StartTimer(cmdCtx, logger, a, cmd.ProcessName, cmd.Category, cmd.Project)
+		case "timer_stop":
+			s.StopTimer(cmdCtx, logger, pr, a, h, cmd.ProcessName)
+		case "pause":
+			e.PauseTracking(serviceCtx, logger, s, pr, a, h, cmd.Programs, cmd.For)
+		case "resume":
+			e.ResumeTracking(serviceCtx, logger, s, pr, a, h, cmd.Programs)
 		case "refresh":
-			e.RefreshProcessMonitor(serviceCtx, logger, s, pr, a, h)
+			e.refreshProcessMonitor(serviceCtx, logger, s, pr, a, h)
 			logger.Println("INFO: Called refreshProcessMonitor")
 		default:
 			logger.Printf("WARN: Received unknown command action: %s", cmd.Action)
 		}
 
+		e.cmdMu.Unlock()
 		cancel()
 	}
 
@@ -83,8 +111,17 @@ func (e *EventController) HandleConnection(serviceCtx context.Context, logger *l
 	}
 }
 
-// Stops the currently running process monitoring script, and starts a new one with updated program list
+// Stops the currently running process monitoring script, and starts a new one with updated program list. Waits for any
+// command being handled, as commands may refresh the monitor themselves
 func (e *EventController) RefreshProcessMonitor(serviceCtx context.Context, logger *log.Logger, sm *sessions.SessionManager, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository) {
+	e.cmdMu.Lock()
+	defer e.cmdMu.Unlock()
+
+	e.refreshProcessMonitor(serviceCtx, logger, sm, pr, a, h)
+}
+
+// Caller MUST hold e.cmdMu Lock
+func (e *EventController) refreshProcessMonitor(serviceCtx context.Context, logger *log.Logger, sm *sessions.SessionManager, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository) {
 	e.StopHeartbeats()
 	e.StopProcessMonitor()
 
@@ -102,6 +139,13 @@ func (e *EventController) RefreshProcessMonitor(serviceCtx context.Context, logg
 		return
 	}
 
+	exclusions, err := pr.GetAllExclusions(context.Background())
+	if err != nil {
+		logger.Printf("ERROR: Failed to get program exclusions: %s", err)
+	}
+
+	e.LoadMatchers(logger, programs, exclusions)
+
 	if len(programs) > 0 {
 		toTrack := updateSessionsMapOnRefresh(sm, programs)
 
@@ -115,6 +159,71 @@ func (e *EventController) RefreshProcessMonitor(serviceCtx context.Context, logg
 	logger.Printf("INFO: Process monitor refresh with %d programs", len(programs))
 }
 
+// Pauses tracking of the given programs, or of every program if none are given, for the given duration or until resumed if
+// empty. Tracking resumes on its own once the duration runs out
+func (e *EventController) PauseTracking(serviceCtx context.Context, logger *log.Logger, sm *sessions.SessionManager, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository, programs []string, duration string) {
+	var until time.Time
+	if duration != "" {
+		d, err := time.ParseDuration(duration)
+		if err != nil || d <= 0 {
+			logger.Printf("ERROR: Invalid pause duration %q", duration)
+			return
+		}
+		until = time.Now().Add(d)
+	}
+
+	sm.Pause(serviceCtx, logger, pr, a, h, programs, until, e.onResume(serviceCtx, logger, sm, pr, a, h))
+}
+
+// Resumes tracking of the given programs, or of everything paused if none are given
+// Caller MUST hold e.cmdMu Lock
+func (e *EventController) ResumeTracking(serviceCtx context.Context, logger *log.Logger, sm *sessions.SessionManager, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository, programs []string) {
+	if !sm.Resume(serviceCtx, logger, pr, programs) {
+		logger.Printf("INFO: No pause to resume for %v", programs)
+		return
+	}
+
+	e.refreshProcessMonitor(serviceCtx, logger, sm, pr, a, h)
+}
+
+// Restores the pauses saved by the previous service, before monitoring starts
+func (e *EventController) RecoverPauses(serviceCtx context.Context, logger *log.Logger, sm *sessions.SessionManager, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository) {
+	sm.RecoverPauses(serviceCtx, logger, pr, e.onResume(serviceCtx, logger, sm, pr, a, h))
+}
+
+// Returns the function run once a pause runs out, refreshing the process monitor so processes of resumed programs that
+// kept running through the pause are picked up again. It runs on the pause's timer, so it waits its turn with commands
+func (e *EventController) onResume(serviceCtx context.Context, logger *log.Logger, sm *sessions.SessionManager, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository) func() {
+	return func() {
+		e.RefreshProcessMonitor(serviceCtx, logger, sm, pr, a, h)
+	}
+}
+
+// Applies the session settings from config to the session manager
+func (e *EventController) configureSessions(sm *sessions.SessionManager) {
+	var gap time.Duration
+	if e.Config.MergeGap != "" {
+		d, err := time.ParseDuration(e.Config.MergeGap)
+		if err == nil && d > 0 {
+			gap = d
+		}
+	}
+
+	var minSession time.Duration
+	if e.Config.MinSession != "" {
+		d, err := time.ParseDuration(e.Config.MinSession)
+		if err == nil && d > 0 {
+			minSession = d
+		}
+	}
+
+	sm.Mu.Lock()
+	sm.MergeGap = gap
+	sm.Mu.Unlock()
+	sm.SetMinSession(minSession)
+	sm.SetSplitDays(e.Config.SplitDays)
+}
+
 // Takes list of programs from database, and updates session map by adding/removing/altering based on any changes from last database grab
 func updateSessionsMapOnRefresh(sm *sessions.SessionManager, programs []database.TrackedProgram) []string {
 	desired := make(map[string]struct{}, len(programs))
@@ -4,17 +4,16 @@ package events
 
 import (
 	"context"
+	"database/sql"
 	"errors"
-	"fmt"
 	"io/fs"
 	"log"
-	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/jms-guy/timekeep/cmd/service/internal/sessions"
+	"github.com/jms-guy/timekeep/internal/database"
 	"github.com/jms-guy/timekeep/internal/repository"
 )
 
@@ -30,6 +29,26 @@ func (e *EventController) StartMonitor(parent context.Context, logger *log.Logge
 	e.MonCancel = cancel
 	e.mu.Unlock()
 
+	e.configureSessions(sm)
+	e.loadUsers(logger)
+
+	if timeout := e.idleTimeout(); timeout > 0 {
+		input, err := newInputSource(e.Config.IdleInput)
+		if err != nil {
+			logger.Printf("WARNING: %s, using CPU activity only", err)
+		}
+		go e.MonitorIdle(ctx, logger, sm, a, newIdleState(input), timeout, e.pollTime())
+	}
+
+	if e.Config.MonitorBackend == "netlink" {
+		conn, err := openProcConnector()
+		if err == nil {
+			go e.MonitorProcEvents(ctx, logger, sm, pr, a, h, conn)
+			return
+		}
+		logger.Printf("WARNING: Failed to open netlink proc connector, falling back to /proc polling: %s", err)
+	}
+
 	go e.MonitorProcesses(ctx, logger, sm, pr, a, h, programs)
 }
 
@@ -53,56 +72,113 @@ func (e *EventController) MonitorProcesses(ctx context.Context, logger *log.Logg
 			logger.Println("INFO: Monitor context cancelled")
 			return
 		case <-ticker.C:
-			livePIDS := e.checkForProcessStartEvents(logger, sm, a)
+			livePIDS := e.checkForProcessStartEvents(logger, sm, pr, a, h)
 			e.checkForProcessStopEvents(logger, sm, pr, a, h, livePIDS, grace)
 		}
 	}
 }
 
 // Polls /proc and loops over PID entries, looking for any new PIDS belonging to tracked programs
-func (e *EventController) checkForProcessStartEvents(logger *log.Logger, sm *sessions.SessionManager, a repository.ActiveRepository) map[int]struct{} {
-	entries, err := os.ReadDir("/proc") // Read /proc
+func (e *EventController) checkForProcessStartEvents(logger *log.Logger, sm *sessions.SessionManager, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository) map[int]struct{} {
+	pids, err := e.Procs.PIDs() // Read /proc
 	if err != nil {
 		logger.Printf("ERROR: Couldn't read /proc: %s", err)
 		return nil
 	}
 
-	live := make(map[int]struct{})
-	for _, e := range entries { // Loop over PID entries
-		if !e.IsDir() {
-			continue
-		}
-		pid, ok := parsePID(e.Name())
-		if !ok {
-			continue
-		}
+	live := make(map[int]struct{}, len(pids))
+	for _, pid := range pids { // Loop over PID entries
 		live[pid] = struct{}{}
 
-		identity, err := getProgramIdentity(pid)
-		if err != nil {
-			continue
-		}
+		e.trackProcess(logger, sm, pr, a, h, pid)
+	}
+
+	return live
+}
+
+// Resolves the identity of a single PID, and starts or extends a session if it belongs to a tracked program
+func (e *EventController) trackProcess(logger *log.Logger, sm *sessions.SessionManager, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository, pid int) {
+	program, err := e.resolveProgram(sm, pid)
+	if err != nil || program == "" { // Is program being tracked?
+		return
+	}
+	if sm.IsPaused(program) { // Is tracking of program paused?
+		return
+	}
 
-		sm.Mu.Lock()
-		_, match := sm.Programs[identity] // Is program being tracked?
-		if !match {
+	stat, err := e.Procs.Stat(pid)
+	if err != nil {
+		return
+	}
+	key := sessions.ProcKey{PID: pid, StartTime: stat.StartTime}
+
+	uid, err := e.Procs.UID(pid)
+	if err != nil || !e.userTracked(uid) { // Is process owned by a tracked user?
+		return
+	}
+
+	var cg cgroupInfo
+	if lines, err := e.Procs.Cgroup(pid); err == nil {
+		cg = parseCgroup(lines)
+	}
+	e.mu.Lock()
+	scope := e.scopes[program]
+	e.mu.Unlock()
+	if !scopeAllows(scope, cg.Container) { // Is process running where the program is tracked?
+		return
+	}
+
+	if e.isExcluded(program, key, stat) { // Helper process excluded from program's sessions?
+		return
+	}
+
+	sm.Mu.Lock()
+	var reused *sessions.ProcKey
+	if t := sm.Programs[program]; t != nil {
+		if _, exists := t.PIDs[key]; exists {
+			t.LastSeen = time.Now()
 			sm.Mu.Unlock()
-			continue
+			return
 		}
+		if old, ok := t.KeyForPID(pid); ok { // Same PID with a different start time, the old process is gone
+			reused = &old
+		}
+	}
+	sm.Mu.Unlock()
 
-		if t := sm.Programs[identity]; t != nil {
-			if _, exists := t.PIDs[pid]; exists {
-				t.LastSeen = time.Now()
-				sm.Mu.Unlock()
-				continue
-			}
+	if reused != nil {
+		logger.Printf("INFO: PID %d reused for %s, ending previous process", pid, program)
+		sm.EndSession(context.Background(), logger, pr, a, h, program, *reused)
+	}
+
+	info := sessions.ProcInfo{
+		UID:       sql.NullInt64{Int64: int64(uid), Valid: true},
+		Container: sql.NullString{String: cg.Container, Valid: cg.Container != ""},
+		Unit:      sql.NullString{String: cg.Unit, Valid: cg.Unit != ""},
+	}
+	if e.Config.DetectProject && cg.Container == "" { // Container working directories aren't host paths
+		if cwd, err := e.Procs.Cwd(pid); err == nil {
+			project := detectProject(e.Files, cwd)
+			info.Project = sql.NullString{String: project, Valid: project != ""}
 		}
-		sm.Mu.Unlock()
+	}
+	sm.CreateSession(context.Background(), logger, pr, a, h, program, key, info, e.sessionStartTime(logger, stat))
+}
 
-		sm.CreateSession(context.Background(), logger, a, identity, pid)
+// Determine the time a session opened by a process should start at. Uses the kernel process start time, so processes already
+// running when first seen aren't truncated, unless the detected_start config value is set, then returns zero to start it now
+func (e *EventController) sessionStartTime(logger *log.Logger, stat ProcStat) time.Time {
+	if e.Config.DetectedStart {
+		return time.Time{}
 	}
 
-	return live
+	boot, err := e.Procs.BootTime()
+	if err != nil {
+		logger.Printf("WARNING: Couldn't read boot time, starting session now: %s", err)
+		return time.Time{}
+	}
+
+	return processStartTime(boot, stat.StartTime)
 }
 
 // Takes the PID entries found in the previous check function, and compares them against map of active PIDs, to determine if
@@ -111,40 +187,55 @@ func (e *EventController) checkForProcessStopEvents(logger *log.Logger, sm *sess
 	if livePIDs == nil {
 		livePIDs = map[int]struct{}{}
 	}
+	e.pruneExcluded(livePIDs)
 
 	sm.Mu.Lock()
 	type toEnd struct {
 		program string
-		pid     int
+		key     sessions.ProcKey
 	}
 	var ends []toEnd
 
 	now := time.Now()
 	// Loop tracked programs. For each PID currently being tracked, check if it exists in the live map. If it does, update last seen value,
-	// else schedule the PID to be removed from tracking
+	// else schedule the PID to be removed from tracking. A live PID whose start time changed has been recycled by the kernel, and
+	// is removed immediately
 	for program, t := range sm.Programs {
 		if t == nil {
 			continue
 		}
 
-		for pid := range t.PIDs {
-			if _, ok := livePIDs[pid]; ok {
+		for key := range t.PIDs {
+			if _, ok := livePIDs[key.PID]; ok {
+				if e.pidReused(key) {
+					ends = append(ends, toEnd{program, key})
+					continue
+				}
 				t.LastSeen = now
 				continue
 			}
 
 			if now.Sub(t.LastSeen) >= grace {
-				ends = append(ends, toEnd{program, pid})
+				ends = append(ends, toEnd{program, key})
 			}
 		}
 	}
 	sm.Mu.Unlock()
 
 	for _, eend := range ends {
-		sm.EndSession(context.Background(), logger, pr, a, h, eend.program, eend.pid)
+		sm.EndSession(context.Background(), logger, pr, a, h, eend.program, eend.key)
 	}
 }
 
+// Reports whether a tracked PID now belongs to a different process, by comparing its recorded start time against /proc
+func (e *EventController) pidReused(key sessions.ProcKey) bool {
+	stat, err := e.Procs.Stat(key.PID)
+	if err != nil {
+		return false
+	}
+	return stat.StartTime != key.StartTime
+}
+
 func (e *EventController) StopProcessMonitor() {
 	e.mu.Lock()
 	if e.MonCancel != nil {
@@ -167,64 +258,105 @@ func (e *EventController) pollTime() time.Duration {
 	return d
 }
 
-// Read process /proc/{pid}/exe path to get program name
-func readExePath(pid int) (string, error) {
-	p := fmt.Sprintf("/proc/%d/exe", pid)
-	target, err := os.Readlink(p)
+// Resolves a PID to the name of the tracked program it belongs to, or an empty string if it isn't tracked
+func (e *EventController) resolveProgram(sm *sessions.SessionManager, pid int) (string, error) {
+	identity, err := getProgramIdentity(e.Procs, pid)
 	if err != nil {
 		return "", err
 	}
 
-	real, err := filepath.EvalSymlinks(target)
-	if err != nil {
-		return target, nil
-	}
-
-	return real, nil
-}
-
-// Read process /proc/{pid}/cmdline path to get program name
-func readCmdline(pid int) (string, error) {
-	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
-	if err != nil {
-		return "", err
-	}
-
-	parts := strings.Split(string(b), "\x00")
-	if len(parts) == 0 || parts[0] == "" {
-		return "", fmt.Errorf("empty cmdline")
-	}
-
-	return parts[0], nil
+	program, _ := e.matchProgram(sm, identity, func() []string {
+		argv, _ := e.Procs.Cmdline(pid)
+		return argv
+	})
+	return program, nil
 }
 
 // Get identity of process by reading exe and cmdline paths
-func getProgramIdentity(pid int) (string, error) {
-	if exe, err := readExePath(pid); err == nil && exe != "" {
+func getProgramIdentity(procs ProcessLister, pid int) (string, error) {
+	if exe, err := procs.Exe(pid); err == nil && exe != "" {
 		return normalizeBase(exe), nil
 	} else if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
 		return "", err
 	}
-	if argv0, err := readCmdline(pid); err == nil && argv0 != "" {
-		return normalizeBase(argv0), nil
+	if argv, err := procs.Cmdline(pid); err == nil && argv[0] != "" {
+		return normalizeBase(argv[0]), nil
 	} else if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
 		return "", err
 	}
-	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
+	comm, err := procs.Comm(pid)
 	if err != nil {
 		return "", err
 	}
-	return normalizeBase(strings.TrimSpace(string(b))), nil
+	return normalizeBase(comm), nil
 }
 
 func normalizeBase(s string) string {
 	return strings.ToLower(filepath.Base(s))
 }
 
-func parsePID(name string) (int, bool) {
-	pid, err := strconv.Atoi(name)
-	if err != nil || pid <= 0 {
-		return 0, false
+// Reports whether a process attributed to a program matches one of the program's exclusion rules, such as browser/Electron
+// helper processes that shouldn't keep a session open. Matches are remembered for the life of the process, so a helper
+// reparented after its main process exits isn't picked up as a new session
+func (e *EventController) isExcluded(program string, key sessions.ProcKey, stat ProcStat) bool {
+	e.mu.Lock()
+	rules := e.exclusions[program]
+	_, seen := e.excludedProcs[key]
+	e.mu.Unlock()
+
+	if seen {
+		return true
+	}
+	if !e.matchesExclusion(rules, key.PID, stat) {
+		return false
+	}
+
+	e.mu.Lock()
+	if e.excludedProcs == nil {
+		e.excludedProcs = make(map[sessions.ProcKey]struct{})
+	}
+	e.excludedProcs[key] = struct{}{}
+	e.mu.Unlock()
+
+	return true
+}
+
+// Reports whether a process satisfies any of the given exclusion rules
+func (e *EventController) matchesExclusion(rules []database.ProgramExclusion, pid int, stat ProcStat) bool {
+	for _, rule := range rules {
+		switch rule.Kind {
+		case ExcludeArgs:
+			argv, err := e.Procs.Cmdline(pid)
+			if err == nil && strings.Contains(strings.Join(argv, " "), rule.Pattern) {
+				return true
+			}
+		case ExcludeParent:
+			if stat.PPID <= 0 {
+				continue
+			}
+			parent, err := getProgramIdentity(e.Procs, stat.PPID)
+			if err == nil && parent == strings.ToLower(rule.Pattern) {
+				return true
+			}
+		case ExcludePath:
+			exe, err := e.Procs.Exe(pid)
+			if err == nil && strings.HasPrefix(exe, rule.Pattern) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Forgets remembered excluded processes that are no longer running
+func (e *EventController) pruneExcluded(livePIDs map[int]struct{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for key := range e.excludedProcs {
+		if _, ok := livePIDs[key.PID]; !ok {
+			delete(e.excludedProcs, key)
+		}
 	}
-	return pid, true
 }
@@ -0,0 +1,835 @@
+//go:build linux
+
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jms-guy/timekeep/cmd/service/internal/logs"
+	"github.com/jms-guy/timekeep/cmd/service/internal/sessions"
+	"github.com/jms-guy/timekeep/internal/config"
+	"github.com/jms-guy/timekeep/internal/database"
+	"github.com/jms-guy/timekeep/internal/repository"
+	mysql "github.com/jms-guy/timekeep/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+type monitorTestEnv struct {
+	ctrl  *EventController
+	procs *FakeProcessLister
+	sm    *sessions.SessionManager
+	store repository.Store
+}
+
+// Setup monitor driven by a fake process table, with an in-memory database tracking given programs
+func setupMonitorTest(t *testing.T, programNames ...string) *monitorTestEnv {
+	db, err := mysql.OpenTestDatabase()
+	require.NoError(t, err, "Failed to open test database")
+
+	store := repository.NewSqliteStore(db)
+	sm := sessions.NewSessionManager()
+
+	procs := NewFakeProcessLister()
+	ctrl := NewEventController()
+	ctrl.Config = &config.Config{}
+	ctrl.Procs = procs
+
+	env := &monitorTestEnv{ctrl: ctrl, procs: procs, sm: sm, store: store}
+	for _, name := range programNames {
+		env.track(t, database.AddProgramParams{Name: name, MatchMode: MatchExact})
+	}
+
+	return env
+}
+
+// Adds a program to the database and session map, and reloads the monitor's pattern matchers
+func (env *monitorTestEnv) track(t *testing.T, program database.AddProgramParams) {
+	err := env.store.AddProgram(context.Background(), program)
+	require.NoError(t, err, "Failed to add program '%s'", program.Name)
+
+	env.sm.Mu.Lock()
+	env.sm.EnsureProgram(program.Name, "", "")
+	env.sm.Mu.Unlock()
+
+	env.reload(t)
+}
+
+// Reloads the monitor's pattern matchers and exclusion rules from the database
+func (env *monitorTestEnv) reload(t *testing.T) {
+	programs, err := env.store.GetAllPrograms(context.Background())
+	require.NoError(t, err)
+	exclusions, err := env.store.GetAllExclusions(context.Background())
+	require.NoError(t, err)
+
+	env.ctrl.LoadMatchers(logs.NewTestLogs().Logger, programs, exclusions)
+}
+
+// Runs a single polling pass of the monitor against the fake process table
+func (env *monitorTestEnv) poll(t *testing.T, grace time.Duration) {
+	logger := logs.NewTestLogs().Logger
+
+	live := env.ctrl.checkForProcessStartEvents(logger, env.sm, env.store, env.store, env.store)
+	env.ctrl.checkForProcessStopEvents(logger, env.sm, env.store, env.store, env.store, live, grace)
+}
+
+func (env *monitorTestEnv) trackedPIDs(program string) int {
+	env.sm.Mu.Lock()
+	defer env.sm.Mu.Unlock()
+
+	return len(env.sm.Programs[program].PIDs)
+}
+
+func TestMonitor_DetectsProcessStart(t *testing.T) {
+	env := setupMonitorTest(t, "code")
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code"})
+	env.procs.Start(200, FakeProcess{Exe: "/usr/bin/bash"})
+	env.poll(t, time.Hour)
+
+	assert.Equal(t, 1, env.trackedPIDs("code"), "Only the tracked program's PID should be added")
+
+	active, err := env.store.GetAllActiveSessions(t.Context())
+	require.NoError(t, err)
+	require.Len(t, active, 1, "An active session should be created for the tracked program")
+	assert.Equal(t, "code", active[0].ProgramName)
+}
+
+func TestMonitor_IdentityFallsBackToCmdline(t *testing.T) {
+	env := setupMonitorTest(t, "python3")
+
+	env.procs.Start(100, FakeProcess{Cmdline: []string{"/usr/bin/python3", "script.py"}})
+	env.poll(t, time.Hour)
+
+	assert.Equal(t, 1, env.trackedPIDs("python3"), "Process should be identified by argv[0] when exe is unreadable")
+}
+
+func TestMonitor_DetectsProcessStop(t *testing.T) {
+	env := setupMonitorTest(t, "code")
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code"})
+	env.poll(t, 0)
+
+	env.procs.Stop(100)
+	env.poll(t, 0)
+
+	assert.Equal(t, 0, env.trackedPIDs("code"), "Stopped PID should no longer be tracked")
+
+	active, err := env.store.GetAllActiveSessions(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, active, 0, "Active session should be ended")
+
+	count, err := env.store.GetCountOfSessionsForProgram(t.Context(), "code")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "Ended session should be moved to history")
+}
+
+func TestMonitor_GracePeriod(t *testing.T) {
+	env := setupMonitorTest(t, "code")
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code"})
+	env.poll(t, time.Hour)
+
+	env.procs.Stop(100)
+	env.poll(t, time.Hour)
+
+	assert.Equal(t, 1, env.trackedPIDs("code"), "Missed PID should be kept while within grace period")
+
+	env.poll(t, 0)
+
+	assert.Equal(t, 0, env.trackedPIDs("code"), "Missed PID should be removed once grace period expires")
+
+	count, err := env.store.GetCountOfSessionsForProgram(t.Context(), "code")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "Session should be moved to history after grace period")
+}
+
+func TestMonitor_MultiPIDSession(t *testing.T) {
+	env := setupMonitorTest(t, "code")
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code"})
+	env.procs.Start(101, FakeProcess{Exe: "/usr/share/code/code"})
+	env.poll(t, 0)
+
+	assert.Equal(t, 2, env.trackedPIDs("code"), "Both PIDs should belong to the same session")
+
+	env.procs.Stop(100)
+	env.procs.Start(102, FakeProcess{Exe: "/opt/google/chrome/chrome", Cmdline: []string{"chrome", "--type=gpu-process"}, PPID: 1}) // Reparented
+	env.poll(t, 0)
+
+	active, err := env.store.GetAllActiveSessions(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, active, 1, "Session should stay active while a PID remains")
+
+	env.procs.Stop(101)
+	env.poll(t, 0)
+
+	active, err = env.store.GetAllActiveSessions(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, active, 0, "Session should end with its last PID")
+
+	count, err := env.store.GetCountOfSessionsForProgram(t.Context(), "code")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "Multi-PID session should produce a single history row")
+}
+
+func TestMonitor_PIDReuseSameProgram(t *testing.T) {
+	env := setupMonitorTest(t, "code")
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code", StartTime: 1000})
+	env.poll(t, time.Hour)
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code", StartTime: 2000})
+	env.poll(t, time.Hour)
+
+	assert.Equal(t, 1, env.trackedPIDs("code"), "Recycled PID should replace the old process")
+
+	count, err := env.store.GetCountOfSessionsForProgram(t.Context(), "code")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "Old process session should be ended despite the PID staying live")
+
+	active, err := env.store.GetAllActiveSessions(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, active, 1, "New process should open a new session")
+}
+
+func TestMonitor_PIDReuseOtherProgram(t *testing.T) {
+	env := setupMonitorTest(t, "code", "firefox")
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code", StartTime: 1000})
+	env.poll(t, time.Hour)
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/lib/firefox/firefox", StartTime: 2000})
+	env.poll(t, time.Hour)
+
+	assert.Equal(t, 0, env.trackedPIDs("code"), "Recycled PID should no longer count towards the old program")
+	assert.Equal(t, 1, env.trackedPIDs("firefox"), "Recycled PID should be attributed to the new program")
+
+	count, err := env.store.GetCountOfSessionsForProgram(t.Context(), "code")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "Old program session should be moved to history without waiting for grace")
+}
+
+func TestParseStat(t *testing.T) {
+	stat, err := parseStat("1234 (my (weird) prog) S 1 1234 1234 0 -1 4194560 100 0 0 0 5 3 0 0 20 0 1 0 987654 1000000 200 0 0")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(987654), stat.StartTime, "Start time should be read from field 22")
+	assert.Equal(t, uint64(5), stat.UTime, "User time should be read from field 14")
+	assert.Equal(t, uint64(3), stat.STime, "System time should be read from field 15")
+
+	_, err = parseStat("1234 (prog) S 1")
+	assert.Error(t, err, "Truncated stat should error")
+}
+
+func TestMonitor_SessionStartsAtProcessStartTime(t *testing.T) {
+	env := setupMonitorTest(t, "code")
+	env.procs.Boot = time.Now().Add(-2 * time.Hour)
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code", StartTime: 60 * 60 * clockTicks})
+	env.poll(t, time.Hour)
+
+	active, err := env.store.GetActiveSession(t.Context(), "code")
+	require.NoError(t, err)
+	assert.WithinDuration(t, env.procs.Boot.Add(time.Hour), active.StartTime, time.Second, "Session should start when the process started")
+}
+
+func TestMonitor_ServiceRestartDoesNotRecountProcess(t *testing.T) {
+	env := setupMonitorTest(t, "code")
+	logger := logs.NewTestLogs().Logger
+	env.procs.Boot = time.Now().Add(-time.Hour)
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code", StartTime: 30 * 60 * clockTicks})
+	env.poll(t, time.Hour)
+
+	// Service stopped, archiving the session of the still running process, then restarted with fresh state
+	env.sm.Mu.Lock()
+	env.sm.MoveSessionToHistory(t.Context(), logger, env.store, env.store, env.store, "code")
+	env.sm.Mu.Unlock()
+	env.sm = sessions.NewSessionManager()
+	env.sm.Mu.Lock()
+	env.sm.EnsureProgram("code", "", "")
+	env.sm.Mu.Unlock()
+
+	env.poll(t, time.Hour)
+	env.procs.Stop(100)
+	env.poll(t, 0)
+
+	history, err := env.store.GetFilteredSessionHistory(t.Context(), database.GetFilteredSessionHistoryParams{ProgramName: "code", Limit: 25})
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.False(t, history[1].StartTime.Before(history[0].EndTime), "Session reopened after restart shouldn't overlap the archived one")
+
+	program, err := env.store.GetProgramByName(t.Context(), "code")
+	require.NoError(t, err)
+	assert.InDelta(t, 30*60, program.LifetimeSeconds, 2, "Lifetime shouldn't count the process' run twice")
+}
+
+func TestMonitor_SessionStartsWhenDetected(t *testing.T) {
+	env := setupMonitorTest(t, "code")
+	env.ctrl.Config.DetectedStart = true
+	env.procs.Boot = time.Now().Add(-2 * time.Hour)
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code", StartTime: 60 * 60 * clockTicks})
+	env.poll(t, time.Hour)
+
+	active, err := env.store.GetActiveSession(t.Context(), "code")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), active.StartTime, time.Second, "Session should start when the monitor saw the process")
+}
+
+func TestMonitor_GlobMatcherAggregatesVariants(t *testing.T) {
+	env := setupMonitorTest(t)
+	env.track(t, database.AddProgramParams{Name: "python3.*", MatchMode: MatchGlob})
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/bin/python3.11"})
+	env.procs.Start(101, FakeProcess{Exe: "/usr/bin/python3.12"})
+	env.procs.Start(102, FakeProcess{Exe: "/usr/bin/python2.7"})
+	env.poll(t, 0)
+
+	assert.Equal(t, 2, env.trackedPIDs("python3.*"), "All matching versions should join one session")
+
+	active, err := env.store.GetAllActiveSessions(t.Context())
+	require.NoError(t, err)
+	require.Len(t, active, 1, "Matching processes should share a single active session")
+	assert.Equal(t, "python3.*", active[0].ProgramName)
+}
+
+func TestMonitor_RegexMatcher(t *testing.T) {
+	env := setupMonitorTest(t)
+	env.track(t, database.AddProgramParams{Name: `^electron\d+$`, MatchMode: MatchRegex})
+
+	env.procs.Start(100, FakeProcess{Exe: "/opt/Electron25"})
+	env.procs.Start(101, FakeProcess{Exe: "/opt/electron-helper"})
+	env.poll(t, 0)
+
+	assert.Equal(t, 1, env.trackedPIDs(`^electron\d+$`), "Only processes matching the expression should be tracked")
+}
+
+func TestMonitor_ArgvMatcher(t *testing.T) {
+	env := setupMonitorTest(t, "python")
+	env.track(t, database.AddProgramParams{
+		Name:         "mytool",
+		MatchMode:    MatchExact,
+		Exe:          sql.NullString{String: "python", Valid: true},
+		ArgsContains: sql.NullString{String: "-m mytool", Valid: true},
+	})
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/bin/python", Cmdline: []string{"python", "-m", "mytool", "--serve"}})
+	env.procs.Start(101, FakeProcess{Exe: "/usr/bin/python", Cmdline: []string{"python", "script.py"}})
+	env.procs.Start(102, FakeProcess{Exe: "/usr/local/bin/mytool", Cmdline: []string{"mytool"}})
+	env.poll(t, 0)
+
+	assert.Equal(t, 1, env.trackedPIDs("mytool"), "Only the interpreter process running the tool should be attributed to it")
+	assert.Equal(t, 1, env.trackedPIDs("python"), "Other interpreter processes should stay with the plain program")
+}
+
+func TestMonitor_Exclusions(t *testing.T) {
+	env := setupMonitorTest(t, "chrome")
+	for _, ex := range []database.AddExclusionParams{
+		{ProgramName: "chrome", Kind: ExcludeArgs, Pattern: "--type=renderer"},
+		{ProgramName: "chrome", Kind: ExcludeParent, Pattern: "chrome"},
+		{ProgramName: "chrome", Kind: ExcludePath, Pattern: "/opt/crashpad/"},
+	} {
+		require.NoError(t, env.store.AddExclusion(t.Context(), ex))
+	}
+	env.reload(t)
+
+	env.procs.Start(100, FakeProcess{Exe: "/opt/google/chrome/chrome", Cmdline: []string{"chrome"}, PPID: 1})
+	env.procs.Start(101, FakeProcess{Exe: "/opt/google/chrome/chrome", Cmdline: []string{"chrome", "--type=renderer"}, PPID: 1})
+	env.procs.Start(102, FakeProcess{Exe: "/opt/google/chrome/chrome", Cmdline: []string{"chrome", "--type=gpu-process"}, PPID: 100})
+	env.procs.Start(103, FakeProcess{Exe: "/opt/crashpad/chrome", Cmdline: []string{"chrome"}, PPID: 1})
+	env.poll(t, 0)
+
+	assert.Equal(t, 1, env.trackedPIDs("chrome"), "Only the main process should be tracked")
+
+	env.procs.Stop(100)
+	env.procs.Start(102, FakeProcess{Exe: "/opt/google/chrome/chrome", Cmdline: []string{"chrome", "--type=gpu-process"}, PPID: 1}) // Reparented
+	env.poll(t, 0)
+
+	active, err := env.store.GetAllActiveSessions(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, active, 0, "Helper processes shouldn't keep the session open after the main process exits")
+}
+
+func TestMonitor_UserFilter(t *testing.T) {
+	env := setupMonitorTest(t, "code")
+	env.ctrl.Config.Users = []string{"1000"}
+	env.ctrl.loadUsers(logs.NewTestLogs().Logger)
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code", UID: 1001})
+	env.poll(t, time.Hour)
+	assert.Equal(t, 0, env.trackedPIDs("code"), "Other users' processes shouldn't be tracked")
+
+	env.procs.Start(101, FakeProcess{Exe: "/usr/share/code/code", UID: 1000})
+	env.poll(t, time.Hour)
+	assert.Equal(t, 1, env.trackedPIDs("code"), "Configured user's process should be tracked")
+
+	env.procs.Stop(101)
+	env.poll(t, 0)
+
+	history, err := env.store.GetFilteredSessionHistory(t.Context(), database.GetFilteredSessionHistoryParams{
+		Uid:   sql.NullInt64{Int64: 1000, Valid: true},
+		Limit: 25,
+	})
+	require.NoError(t, err)
+	require.Len(t, history, 1, "Session should be recorded under the owning user")
+	assert.Equal(t, int64(1000), history[0].Uid.Int64)
+}
+
+func TestResolveUsers(t *testing.T) {
+	uids, err := resolveUsers([]string{"root", "1000"})
+	require.NoError(t, err)
+	assert.Equal(t, map[int]struct{}{0: {}, 1000: {}}, uids)
+
+	uids, err = resolveUsers([]string{"1000", "*"})
+	require.NoError(t, err)
+	assert.Nil(t, uids, "Wildcard should track all users")
+
+	uids, err = resolveUsers(nil)
+	require.NoError(t, err)
+	assert.Contains(t, uids, invokingUID(), "Default should track the service user")
+}
+
+func TestParseCgroup(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  cgroupInfo
+	}{
+		{"host user scope", []string{"0::/user.slice/user-1000.slice/user@1000.service/app.slice/vte-spawn-1.scope"}, cgroupInfo{Unit: "vte-spawn-1.scope"}},
+		{"system service", []string{"0::/system.slice/nginx.service"}, cgroupInfo{Unit: "nginx.service"}},
+		{"docker v2", []string{"0::/system.slice/docker-0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef.scope"}, cgroupInfo{Container: "0123456789ab"}},
+		{"rootless podman", []string{"0::/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210.scope/container"}, cgroupInfo{Container: "fedcba987654", Unit: "user@1000.service"}},
+		{"docker v1", []string{"12:memory:/docker/0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", "1:name=systemd:/docker/0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"}, cgroupInfo{Container: "0123456789ab"}},
+		{"empty", nil, cgroupInfo{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseCgroup(tt.lines))
+		})
+	}
+}
+
+func TestMonitor_ContainerScope(t *testing.T) {
+	env := setupMonitorTest(t)
+	env.track(t, database.AddProgramParams{Name: "node", MatchMode: MatchExact, Scope: ScopeContainer})
+	env.track(t, database.AddProgramParams{Name: "code", MatchMode: MatchExact, Scope: ScopeHost})
+
+	container := []string{"0::/system.slice/docker-0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef.scope"}
+	env.procs.Start(100, FakeProcess{Exe: "/usr/bin/node"})
+	env.procs.Start(101, FakeProcess{Exe: "/usr/share/code/code", Cgroup: container})
+	env.poll(t, time.Hour)
+
+	assert.Equal(t, 0, env.trackedPIDs("node"), "Host process shouldn't be tracked for container scoped program")
+	assert.Equal(t, 0, env.trackedPIDs("code"), "Container process shouldn't be tracked for host scoped program")
+
+	env.procs.Start(102, FakeProcess{Exe: "/usr/bin/node", Cgroup: container})
+	env.poll(t, time.Hour)
+	assert.Equal(t, 1, env.trackedPIDs("node"))
+
+	active, err := env.store.GetActiveSession(t.Context(), "node")
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789ab", active.Container.String, "Session should record the container")
+}
+
+func TestDetectProject(t *testing.T) {
+	files := fstest.MapFS{
+		"home/dev/timekeep/.git/HEAD":                 {Data: []byte("ref: refs/heads/main")},
+		"home/dev/work/.timekeep-project":             {Data: []byte("client-site\n")},
+		"home/dev/work/frontend/.git":                 {Data: []byte("gitdir: ../.git/worktrees/frontend")},
+		"home/dev/notes/.timekeep-project":            {Data: []byte("")},
+		"home/dev/timekeep/cmd/service/internal/x.go": {Data: []byte("package x")},
+	}
+
+	assert.Equal(t, "timekeep", detectProject(files, "/home/dev/timekeep/cmd/service/internal"))
+	assert.Equal(t, "frontend", detectProject(files, "/home/dev/work/frontend/src"), "Nearest project root should win")
+	assert.Equal(t, "client-site", detectProject(files, "/home/dev/work"), "Marker contents should name the project")
+	assert.Equal(t, "notes", detectProject(files, "/home/dev/notes"), "Empty marker should use the directory name")
+	assert.Equal(t, "", detectProject(files, "/home/dev"))
+}
+
+func TestMonitor_DetectsProject(t *testing.T) {
+	env := setupMonitorTest(t, "code")
+	env.ctrl.Config.DetectProject = true
+	env.ctrl.Files = fstest.MapFS{"home/dev/timekeep/.git/HEAD": {Data: []byte("ref: refs/heads/main")}}
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code", Cwd: "/home/dev/timekeep/internal"})
+	env.poll(t, time.Hour)
+
+	active, err := env.store.GetActiveSession(t.Context(), "code")
+	require.NoError(t, err)
+	assert.Equal(t, "timekeep", active.Project.String, "Session should record the detected project")
+
+	env.procs.Stop(100)
+	env.poll(t, 0)
+
+	history, err := env.store.GetFilteredSessionHistory(t.Context(), database.GetFilteredSessionHistoryParams{ProgramName: "code", Limit: 25})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "timekeep", history[0].Project.String, "History should keep the detected project")
+}
+
+func TestMonitor_IdleSplitsSession(t *testing.T) {
+	env := setupMonitorTest(t, "code")
+	logger := logs.NewTestLogs().Logger
+	timeout := 5 * time.Minute
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code", CPUTime: 100})
+	env.poll(t, time.Hour)
+
+	st := newIdleState(nil)
+	base := time.Now().Add(-30 * time.Minute)
+	env.ctrl.checkIdle(t.Context(), logger, env.sm, env.store, st, timeout, time.Second, base) // New process counts as activity
+	env.ctrl.checkIdle(t.Context(), logger, env.sm, env.store, st, timeout, time.Second, base.Add(10*time.Minute))
+
+	env.sm.Mu.Lock()
+	idleSince := env.sm.Programs["code"].IdleSince
+	env.sm.Mu.Unlock()
+	assert.Equal(t, base, idleSince, "Idle segment should begin at the last activity")
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code", CPUTime: 10000})
+	env.ctrl.checkIdle(t.Context(), logger, env.sm, env.store, st, timeout, time.Second, base.Add(20*time.Minute))
+
+	active, err := env.store.GetActiveSession(t.Context(), "code")
+	require.NoError(t, err)
+	assert.Equal(t, int64(20*60), active.IdleSeconds, "Closed idle segment should be persisted")
+
+	env.procs.Stop(100)
+	env.poll(t, 0)
+
+	history, err := env.store.GetFilteredSessionHistory(t.Context(), database.GetFilteredSessionHistoryParams{ProgramName: "code", Limit: 25})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, int64(20*60), history[0].IdleSeconds, "History should keep the idle time")
+
+	program, err := env.store.GetProgramByName(t.Context(), "code")
+	require.NoError(t, err)
+	assert.Equal(t, int64(20*60), program.IdleSeconds, "Idle time should be added to the program's lifetime idle")
+}
+
+// Restarts the monitor while its idle loop is running, as a config change does. Run with -race to catch state shared
+// between the old and new idle loops
+func TestMonitor_IdleRestart(t *testing.T) {
+	env := setupMonitorTest(t, "code")
+	logger := logs.NewTestLogs().Logger
+	env.ctrl.Config.IdleTimeout = "1m"
+	env.ctrl.Config.PollInterval = "2ms"
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code", CPUTime: 100})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	for i := 0; i < 5; i++ {
+		env.ctrl.StartMonitor(ctx, logger, env.sm, env.store, env.store, env.store, []string{"code"})
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	time.Sleep(10 * time.Millisecond) // Let the loops observe cancellation before the database is closed
+
+	assert.Equal(t, 1, env.trackedPIDs("code"), "Monitor should keep tracking across restarts")
+}
+
+func TestCountInputInterrupts(t *testing.T) {
+	contents := `           CPU0       CPU1
+   1:         10          5  IR-IO-APIC    1-edge      i8042
+   8:          0          0  IR-IO-APIC    8-edge      rtc0
+ 125:        100        200  IR-PCI-MSI 327680-edge      xhci_hcd
+ 130:          7          0  IR-PCI-MSI 524288-edge      nvme0q0
+`
+	assert.Equal(t, uint64(315), countInputInterrupts(contents))
+}
+
+func TestMonitor_RecoversOrphanedSession(t *testing.T) {
+	env := setupMonitorTest(t, "code")
+	logger := logs.NewTestLogs().Logger
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code", StartTime: 30 * 60 * clockTicks})
+	env.poll(t, time.Hour)
+
+	lastSeen := env.procs.Boot.Add(50 * time.Minute)
+	err := env.store.UpdateActiveLastSeen(t.Context(), sql.NullTime{Time: lastSeen, Valid: true})
+	require.NoError(t, err)
+
+	// Service killed without closing its sessions, restart with fresh state
+	env.sm = sessions.NewSessionManager()
+	env.sm.Mu.Lock()
+	env.sm.EnsureProgram("code", "", "")
+	env.sm.Mu.Unlock()
+	env.sm.RecoverSessions(t.Context(), logger, env.store, env.store, env.store)
+
+	history, err := env.store.GetFilteredSessionHistory(t.Context(), database.GetFilteredSessionHistoryParams{ProgramName: "code", Limit: 25})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.True(t, history[0].Recovered, "Orphaned session should be marked recovered")
+	assert.Equal(t, int64(20*60), history[0].DurationSeconds, "Recovered session should end at its last heartbeat")
+
+	env.poll(t, time.Hour)
+	active, err := env.store.GetActiveSession(t.Context(), "code")
+	require.NoError(t, err, "Still running process should open a new session after recovery")
+	assert.WithinDuration(t, lastSeen, active.StartTime, time.Second, "New session should start where the recovered one ended")
+
+	env.procs.Stop(100)
+	env.poll(t, 0)
+
+	history, err = env.store.GetFilteredSessionHistory(t.Context(), database.GetFilteredSessionHistoryParams{ProgramName: "code", Limit: 25})
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.False(t, history[1].StartTime.Before(history[0].EndTime), "New session shouldn't overlap the recovered one")
+
+	program, err := env.store.GetProgramByName(t.Context(), "code")
+	require.NoError(t, err)
+	assert.InDelta(t, 30*60, program.LifetimeSeconds, 2, "Lifetime shouldn't count the recovered time twice")
+}
+
+func TestMonitor_MergesRestartedSession(t *testing.T) {
+	env := setupMonitorTest(t, "code")
+	env.sm.MergeGap = time.Minute
+	env.procs.Boot = time.Now().Add(-time.Hour)
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code"})
+	env.poll(t, time.Hour)
+	env.procs.Stop(100)
+	env.poll(t, 0)
+
+	env.procs.Start(101, FakeProcess{Exe: "/usr/share/code/code", StartTime: 60 * 60 * clockTicks})
+	env.poll(t, time.Hour)
+
+	history, err := env.store.GetFilteredSessionHistory(t.Context(), database.GetFilteredSessionHistoryParams{ProgramName: "code", Limit: 25})
+	require.NoError(t, err)
+	assert.Len(t, history, 0, "Restarted program should reopen its last session")
+
+	active, err := env.store.GetActiveSession(t.Context(), "code")
+	require.NoError(t, err)
+	assert.WithinDuration(t, env.procs.Boot, active.StartTime, time.Second, "Reopened session should keep its original start")
+
+	env.procs.Stop(101)
+	env.poll(t, 0)
+
+	history, err = env.store.GetFilteredSessionHistory(t.Context(), database.GetFilteredSessionHistoryParams{ProgramName: "code", Limit: 25})
+	require.NoError(t, err)
+	require.Len(t, history, 1, "Both runs should be kept as one session")
+
+	program, err := env.store.GetProgramByName(t.Context(), "code")
+	require.NoError(t, err)
+	assert.Equal(t, history[0].DurationSeconds, program.LifetimeSeconds, "Lifetime should only count the merged session")
+}
+
+func TestMonitor_DiscardsShortSessions(t *testing.T) {
+	env := setupMonitorTest(t, "code", "tool")
+	env.ctrl.Config.DetectedStart = true
+	env.sm.SetMinSession(time.Minute)
+	err := env.store.UpdateMinSession(t.Context(), database.UpdateMinSessionParams{MinSession: sql.NullInt64{Int64: 0, Valid: true}, Name: "tool"})
+	require.NoError(t, err)
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code"})
+	env.procs.Start(200, FakeProcess{Exe: "/usr/bin/tool"})
+	env.poll(t, time.Hour)
+	env.procs.Stop(100)
+	env.procs.Stop(200)
+	env.poll(t, 0)
+
+	history, err := env.store.GetFilteredSessionHistory(t.Context(), database.GetFilteredSessionHistoryParams{ProgramName: "code", Limit: 25})
+	require.NoError(t, err)
+	assert.Len(t, history, 0, "Session shorter than the global minimum should be discarded")
+	_, err = env.store.GetActiveSession(t.Context(), "code")
+	assert.ErrorIs(t, err, sql.ErrNoRows, "Discarded session should no longer be active")
+
+	history, err = env.store.GetFilteredSessionHistory(t.Context(), database.GetFilteredSessionHistoryParams{ProgramName: "tool", Limit: 25})
+	require.NoError(t, err)
+	assert.Len(t, history, 1, "Program minimum should override the global minimum")
+}
+
+func TestMonitor_SplitsSessionsAtMidnight(t *testing.T) {
+	env := setupMonitorTest(t, "code")
+	env.sm.SetSplitDays(true)
+
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	env.procs.Boot = midnight.Add(-2 * time.Hour)
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code", StartTime: 60 * 60 * clockTicks})
+	env.poll(t, time.Hour)
+	env.procs.Stop(100)
+	env.poll(t, 0)
+
+	history, err := env.store.GetFilteredSessionHistory(t.Context(), database.GetFilteredSessionHistoryParams{ProgramName: "code", Limit: 25})
+	require.NoError(t, err)
+	require.Len(t, history, 2, "Session crossing midnight should be stored as one row per day")
+
+	assert.WithinDuration(t, midnight, history[0].EndTime, time.Second, "First row should end at midnight")
+	assert.Equal(t, int64(60*60), history[0].DurationSeconds, "First row should hold the time before midnight")
+	assert.WithinDuration(t, midnight, history[1].StartTime, time.Second, "Second row should start at midnight")
+
+	program, err := env.store.GetProgramByName(t.Context(), "code")
+	require.NoError(t, err)
+	assert.Equal(t, history[0].DurationSeconds+history[1].DurationSeconds, program.LifetimeSeconds, "Lifetime should count the whole session once")
+}
+
+func TestManualTimer(t *testing.T) {
+	env := setupMonitorTest(t, "code")
+	logger := logs.NewTestLogs().Logger
+
+	env.sm.StartTimer(t.Context(), logger, env.store, "standup", "meeting", "team")
+	env.sm.StartTimer(t.Context(), logger, env.store, "code", "meeting", "")
+
+	active, err := env.store.GetActiveSession(t.Context(), "standup")
+	require.NoError(t, err)
+	assert.True(t, active.Manual, "Timer session should be flagged manual")
+	assert.Equal(t, "meeting", active.Category.String)
+	_, err = env.store.GetActiveSession(t.Context(), "code")
+	assert.ErrorIs(t, err, sql.ErrNoRows, "Timer labelled as a tracked program should be refused")
+
+	// Monitor passes shouldn't end a timer with no process behind it
+	env.poll(t, 0)
+
+	// Service restarted, timer should carry on rather than be recovered into history
+	env.sm = sessions.NewSessionManager()
+	env.sm.RecoverSessions(t.Context(), logger, env.store, env.store, env.store)
+	env.sm.Mu.Lock()
+	require.Contains(t, env.sm.Timers, "standup", "Timer should be resumed after a restart")
+	env.sm.Mu.Unlock()
+
+	env.sm.StopTimer(t.Context(), logger, env.store, env.store, env.store, "")
+
+	history, err := env.store.GetFilteredSessionHistory(t.Context(), database.GetFilteredSessionHistoryParams{ProgramName: "standup", Limit: 25})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.True(t, history[0].Manual, "Timer history should be flagged manual")
+	assert.Equal(t, "team", history[0].Project.String)
+	assert.False(t, history[0].Recovered)
+}
+
+func TestMonitor_ArchivesTagsAndNotes(t *testing.T) {
+	env := setupMonitorTest(t, "code")
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code"})
+	env.poll(t, 0)
+
+	err := env.store.UpdateActiveTags(t.Context(), database.UpdateActiveTagsParams{Tags: sql.NullString{String: "client-a,focus", Valid: true}, ProgramName: "code"})
+	require.NoError(t, err)
+	err = env.store.UpdateActiveNotes(t.Context(), database.UpdateActiveNotesParams{Notes: sql.NullString{String: "Release prep", Valid: true}, ProgramName: "code"})
+	require.NoError(t, err)
+
+	env.procs.Stop(100)
+	env.poll(t, 0)
+
+	history, err := env.store.GetFilteredSessionHistory(t.Context(), database.GetFilteredSessionHistoryParams{ProgramName: "code", Limit: 25})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "Release prep", history[0].Notes.String, "Notes should carry over to history")
+
+	sessionTags, err := env.store.GetTagsForSession(t.Context(), history[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"client-a", "focus"}, sessionTags, "Tags should carry over to history")
+}
+
+func TestMonitor_PauseAndResume(t *testing.T) {
+	env := setupMonitorTest(t, "code", "firefox")
+	logger := logs.NewTestLogs().Logger
+	env.procs.Boot = time.Now().Add(-time.Hour)
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code"})
+	env.procs.Start(200, FakeProcess{Exe: "/usr/lib/firefox/firefox"})
+	env.poll(t, 0)
+
+	env.sm.Pause(t.Context(), logger, env.store, env.store, env.store, []string{"code"}, time.Time{}, nil)
+
+	assert.True(t, env.sm.IsPaused("code"))
+	assert.False(t, env.sm.IsPaused("firefox"), "Other programs should keep tracking")
+	_, err := env.store.GetActiveSession(t.Context(), "code")
+	assert.ErrorIs(t, err, sql.ErrNoRows, "Paused program's session should be ended")
+	count, err := env.store.GetCountOfSessionsForProgram(t.Context(), "code")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "Ended session should be moved to history")
+
+	// Running process shouldn't be attributed to the paused program
+	env.poll(t, 0)
+	assert.Equal(t, 0, env.trackedPIDs("code"))
+	assert.Equal(t, 1, env.trackedPIDs("firefox"))
+
+	// Service restarted while paused, pause should be restored from the database
+	restarted := sessions.NewSessionManager()
+	restarted.RecoverPauses(t.Context(), logger, env.store, nil)
+	assert.True(t, restarted.IsPaused("code"), "Pause should persist across restarts")
+
+	resumedAt := time.Now()
+	assert.True(t, env.sm.Resume(t.Context(), logger, env.store, nil))
+	assert.False(t, env.sm.Resume(t.Context(), logger, env.store, nil), "Nothing left to resume")
+	pauses, err := env.store.GetAllPauses(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, pauses, 0, "Resumed pause should be removed from the database")
+
+	env.poll(t, 0)
+	active, err := env.store.GetActiveSession(t.Context(), "code")
+	require.NoError(t, err, "Process should be tracked again once resumed")
+	assert.False(t, active.StartTime.Before(resumedAt.Truncate(time.Second)), "Session shouldn't start before tracking resumed")
+}
+
+func TestMonitor_PauseAllExpires(t *testing.T) {
+	env := setupMonitorTest(t, "code")
+	logger := logs.NewTestLogs().Logger
+
+	env.procs.Start(100, FakeProcess{Exe: "/usr/share/code/code"})
+	env.poll(t, 0)
+
+	resumed := make(chan struct{})
+	env.sm.Pause(t.Context(), logger, env.store, env.store, env.store, nil, time.Now().Add(50*time.Millisecond), func() { close(resumed) })
+
+	assert.True(t, env.sm.IsPaused("code"), "Pause of all programs should cover every program")
+	assert.Equal(t, 0, env.trackedPIDs("code"))
+
+	select {
+	case <-resumed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Pause should run out on its own")
+	}
+
+	assert.False(t, env.sm.IsPaused("code"))
+	pauses, err := env.store.GetAllPauses(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, pauses, 0, "Expired pause should be removed from the database")
+
+	env.poll(t, 0)
+	assert.Equal(t, 1, env.trackedPIDs("code"), "Process should be tracked again once the pause runs out")
+}
+
+func TestPauseExpiryWaitsForCommands(t *testing.T) {
+	t.Setenv("HOME", t.TempDir()) // Refreshes reload the config file
+	env := setupMonitorTest(t)
+	logger := logs.NewTestLogs().Logger
+	resume := env.ctrl.onResume(t.Context(), logger, env.sm, env.store, env.store, env.store)
+
+	server, client := net.Pipe()
+	go func() {
+		enc := json.NewEncoder(client)
+		for range 20 {
+			if err := enc.Encode(Command{Action: "refresh"}); err != nil {
+				break
+			}
+		}
+		client.Close()
+	}()
+
+	// Pause timers run onResume on their own goroutine, while connections refresh the monitor
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 20 {
+			resume()
+		}
+	}()
+
+	env.ctrl.HandleConnection(t.Context(), logger, env.sm, env.store, env.store, env.store, server)
+	wg.Wait()
+
+	assert.NotNil(t, env.ctrl.Config, "Refreshes should leave a loaded config")
+}
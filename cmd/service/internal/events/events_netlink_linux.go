@@ -0,0 +1,261 @@
+//go:build linux
+
+package events
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jms-guy/timekeep/cmd/service/internal/sessions"
+	"github.com/jms-guy/timekeep/internal/repository"
+	"golang.org/x/sys/unix"
+)
+
+// Linux specific event functions, handling PID tracking through kernel fork/exec/exit notifications
+// delivered by the netlink proc connector (NETLINK_CONNECTOR)
+
+const (
+	cnIdxProc = 0x1 // CN_IDX_PROC
+	cnValProc = 0x1 // CN_VAL_PROC
+
+	procCnMcastListen = 1 // PROC_CN_MCAST_LISTEN
+	procCnMcastIgnore = 2 // PROC_CN_MCAST_IGNORE
+
+	procEventFork = 0x00000001 // PROC_EVENT_FORK
+	procEventExec = 0x00000002 // PROC_EVENT_EXEC
+	procEventExit = 0x80000000 // PROC_EVENT_EXIT
+
+	nlMsgHdrLen     = 16 // sizeof(struct nlmsghdr)
+	cnMsgLen        = 20 // sizeof(struct cn_msg)
+	procEventHdrLen = 16 // what, cpu, timestamp_ns of struct proc_event
+)
+
+// Open netlink socket subscribed to the kernel proc connector
+type procConnector struct {
+	fd int
+}
+
+// Single process event read from the proc connector, pid and tgid refer to the child process for fork events
+type procEvent struct {
+	what uint32
+	pid  int
+	tgid int
+}
+
+// Opens and binds a netlink connector socket, and asks the kernel to start multicasting process events to it.
+// Requires CAP_NET_ADMIN, returns an error if the socket can't be opened so the caller may fall back to polling
+func openProcConnector() (*procConnector, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.NETLINK_CONNECTOR)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create netlink socket: %w", err)
+	}
+
+	addr := &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: cnIdxProc}
+	if err := unix.Bind(fd, addr); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("failed to bind netlink socket: %w", err)
+	}
+
+	// Receive timeout so the monitor loop can observe context cancellation
+	tv := unix.NsecToTimeval(time.Second.Nanoseconds())
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("failed to set netlink receive timeout: %w", err)
+	}
+
+	p := &procConnector{fd: fd}
+	if err := p.setListen(procCnMcastListen); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Sends a PROC_CN_MCAST_LISTEN/IGNORE control message to the kernel
+func (p *procConnector) setListen(op uint32) error {
+	buf := make([]byte, nlMsgHdrLen+cnMsgLen+4)
+	ne := binary.NativeEndian
+
+	// struct nlmsghdr
+	ne.PutUint32(buf[0:4], uint32(len(buf)))
+	ne.PutUint16(buf[4:6], unix.NLMSG_DONE)
+
+	// struct cn_msg
+	cn := buf[nlMsgHdrLen:]
+	ne.PutUint32(cn[0:4], cnIdxProc)
+	ne.PutUint32(cn[4:8], cnValProc)
+	ne.PutUint16(cn[16:18], 4)
+
+	// enum proc_cn_mcast_op
+	ne.PutUint32(buf[nlMsgHdrLen+cnMsgLen:], op)
+
+	if err := unix.Sendto(p.fd, buf, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return fmt.Errorf("failed to send proc connector control message: %w", err)
+	}
+
+	return nil
+}
+
+// Blocks until process events are received, or the receive timeout expires
+func (p *procConnector) receive(buf []byte) ([]procEvent, error) {
+	n, _, err := unix.Recvfrom(p.fd, buf, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseProcEvents(buf[:n])
+}
+
+// Unsubscribes from process events and closes the socket
+func (p *procConnector) Close() error {
+	_ = p.setListen(procCnMcastIgnore)
+	return unix.Close(p.fd)
+}
+
+// Returned when a datagram from the proc connector is truncated or its lengths don't add up
+var errMalformedProcEvent = errors.New("malformed proc connector message")
+
+// Parses the netlink messages in a datagram, returning the fork/exec/exit events they carry. Events of other types are
+// skipped, a truncated message returns the events parsed before it along with an error
+func parseProcEvents(b []byte) ([]procEvent, error) {
+	ne := binary.NativeEndian
+	var events []procEvent
+
+	for len(b) > 0 {
+		if len(b) < nlMsgHdrLen {
+			return events, fmt.Errorf("%w: %d bytes left, shorter than a netlink header", errMalformedProcEvent, len(b))
+		}
+		msgLen := int(ne.Uint32(b[0:4]))
+		if msgLen < nlMsgHdrLen || msgLen > len(b) {
+			return events, fmt.Errorf("%w: netlink message length %d out of range for %d bytes", errMalformedProcEvent, msgLen, len(b))
+		}
+
+		data := b[nlMsgHdrLen:msgLen]
+		if len(data) < cnMsgLen+procEventHdrLen {
+			return events, fmt.Errorf("%w: %d byte payload, shorter than a proc event header", errMalformedProcEvent, len(data))
+		}
+		ev := data[cnMsgLen:]
+		what := ne.Uint32(ev[0:4])
+		body := ev[procEventHdrLen:]
+
+		switch what {
+		case procEventFork:
+			if len(body) < 16 {
+				return events, fmt.Errorf("%w: %d byte fork event", errMalformedProcEvent, len(body))
+			}
+			events = append(events, procEvent{what: what, pid: int(ne.Uint32(body[8:12])), tgid: int(ne.Uint32(body[12:16]))})
+		case procEventExec, procEventExit:
+			if len(body) < 8 {
+				return events, fmt.Errorf("%w: %d byte exec/exit event", errMalformedProcEvent, len(body))
+			}
+			events = append(events, procEvent{what: what, pid: int(ne.Uint32(body[0:4])), tgid: int(ne.Uint32(body[4:8]))})
+		}
+
+		// Messages are aligned to 4 bytes (NLMSG_ALIGN), the last one may not be padded
+		next := (msgLen + 3) &^ 3
+		if next > len(b) {
+			next = len(b)
+		}
+		b = b[next:]
+	}
+
+	return events, nil
+}
+
+// Event driven process monitoring function for Linux version, feeding kernel process notifications into the session manager
+func (e *EventController) MonitorProcEvents(ctx context.Context, logger *log.Logger, sm *sessions.SessionManager, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository, conn *procConnector) {
+	logger.Println("INFO: Executing netlink process monitor")
+	defer conn.Close()
+
+	// Pick up tracked programs already running, notifications only cover processes started from here on
+	live := e.checkForProcessStartEvents(logger, sm, pr, a, h)
+	e.checkForProcessStopEvents(logger, sm, pr, a, h, live, 0)
+
+	buf := make([]byte, 64*1024)
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Println("INFO: Monitor context cancelled")
+			return
+		default:
+		}
+
+		events, err := conn.receive(buf)
+		if err != nil {
+			switch {
+			case errors.Is(err, errMalformedProcEvent): // Keep the events parsed before the bad message
+				logger.Printf("WARNING: %s", err)
+			case errors.Is(err, unix.EAGAIN), errors.Is(err, unix.EINTR):
+				continue
+			case errors.Is(err, unix.ENOBUFS): // Kernel dropped events, resync against /proc
+				logger.Println("WARNING: Netlink receive buffer overrun, rescanning /proc")
+				live := e.checkForProcessStartEvents(logger, sm, pr, a, h)
+				e.checkForProcessStopEvents(logger, sm, pr, a, h, live, 0)
+				continue
+			default:
+				logger.Printf("ERROR: Netlink receive failed: %s", err)
+				return
+			}
+		}
+
+		for _, ev := range events {
+			switch ev.what {
+			case procEventFork:
+				if ev.pid == ev.tgid { // Ignore new threads
+					e.trackProcess(logger, sm, pr, a, h, ev.tgid)
+				}
+			case procEventExec: // Process image replaced, end tracking under its old identity if it changed
+				if program, key, ok := trackedProcessFor(sm, ev.tgid); ok {
+					if current, err := e.resolveProgram(sm, ev.tgid); err != nil || current != program {
+						sm.EndSession(context.Background(), logger, pr, a, h, program, key)
+					}
+				}
+				e.trackProcess(logger, sm, pr, a, h, ev.tgid)
+			case procEventExit:
+				if ev.pid == ev.tgid { // Ignore thread exits
+					e.untrackProcess(logger, sm, pr, a, h, ev.tgid)
+				}
+			}
+		}
+	}
+}
+
+// Removes PID from whichever tracked program it belongs to, ending the session if it was the last process
+func (e *EventController) untrackProcess(logger *log.Logger, sm *sessions.SessionManager, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository, pid int) {
+	e.mu.Lock()
+	for key := range e.excludedProcs {
+		if key.PID == pid {
+			delete(e.excludedProcs, key)
+		}
+	}
+	e.mu.Unlock()
+
+	program, key, ok := trackedProcessFor(sm, pid)
+	if !ok {
+		return
+	}
+
+	sm.EndSession(context.Background(), logger, pr, a, h, program, key)
+}
+
+// Returns the tracked program a PID is currently attributed to, along with its process key
+func trackedProcessFor(sm *sessions.SessionManager, pid int) (string, sessions.ProcKey, bool) {
+	sm.Mu.Lock()
+	defer sm.Mu.Unlock()
+
+	for name, t := range sm.Programs {
+		if t == nil {
+			continue
+		}
+		if key, ok := t.KeyForPID(pid); ok {
+			return name, key, true
+		}
+	}
+
+	return "", sessions.ProcKey{}, false
+}
@@ -0,0 +1,85 @@
+//go:build linux
+
+package events
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Builds a netlink message carrying a proc event of given type, with body as the event data following its header
+func procEventMessage(what uint32, body ...uint32) []byte {
+	ne := binary.NativeEndian
+	msg := make([]byte, nlMsgHdrLen+cnMsgLen+procEventHdrLen+4*len(body))
+
+	ne.PutUint32(msg[0:4], uint32(len(msg)))
+	ne.PutUint32(msg[nlMsgHdrLen:], cnIdxProc)
+	ne.PutUint32(msg[nlMsgHdrLen+4:], cnValProc)
+	ne.PutUint32(msg[nlMsgHdrLen+cnMsgLen:], what)
+	for i, v := range body {
+		ne.PutUint32(msg[nlMsgHdrLen+cnMsgLen+procEventHdrLen+4*i:], v)
+	}
+
+	return msg
+}
+
+// Cuts a message down to n bytes, with its header length matching
+func truncated(msg []byte, n int) []byte {
+	b := append([]byte(nil), msg[:n]...)
+	binary.NativeEndian.PutUint32(b[0:4], uint32(n))
+	return b
+}
+
+func join(msgs ...[]byte) []byte {
+	var b []byte
+	for _, msg := range msgs {
+		b = append(b, msg...)
+	}
+	return b
+}
+
+func TestParseProcEvents(t *testing.T) {
+	fork := procEventMessage(procEventFork, 100, 100, 200, 200) // parent pid/tgid, child pid/tgid
+	exec := procEventMessage(procEventExec, 200, 200)
+	exit := procEventMessage(procEventExit, 200, 200, 0, 17) // pid, tgid, exit code, signal
+	unknown := procEventMessage(0x00000040, 300, 300)        // PROC_EVENT_COMM
+
+	tests := []struct {
+		name    string
+		input   []byte
+		events  []procEvent
+		wantErr bool
+	}{
+		{name: "Empty", input: nil},
+		{name: "Fork", input: fork, events: []procEvent{{what: procEventFork, pid: 200, tgid: 200}}},
+		{name: "Exec", input: exec, events: []procEvent{{what: procEventExec, pid: 200, tgid: 200}}},
+		{name: "Exit", input: exit, events: []procEvent{{what: procEventExit, pid: 200, tgid: 200}}},
+		{name: "Unknown event type", input: unknown},
+		{
+			name:   "Several messages",
+			input:  join(fork, unknown, exec, exit),
+			events: []procEvent{{what: procEventFork, pid: 200, tgid: 200}, {what: procEventExec, pid: 200, tgid: 200}, {what: procEventExit, pid: 200, tgid: 200}},
+		},
+		{name: "Short netlink header", input: fork[:nlMsgHdrLen-1], wantErr: true},
+		{name: "Trailing bytes after message", input: join(exec, []byte{1, 2, 3, 4}), events: []procEvent{{what: procEventExec, pid: 200, tgid: 200}}, wantErr: true},
+		{name: "Message length past datagram", input: fork[:len(fork)-4], wantErr: true},
+		{name: "Message length below header", input: join([]byte{4, 0, 0, 0}, make([]byte, nlMsgHdrLen-4)), wantErr: true},
+		{name: "Missing proc event header", input: truncated(exec, nlMsgHdrLen+cnMsgLen), wantErr: true},
+		{name: "Truncated fork event", input: truncated(fork, len(fork)-8), wantErr: true},
+		{name: "Truncated exit event", input: truncated(exit, len(exit)-12), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := parseProcEvents(tt.input)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errMalformedProcEvent)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.events, events)
+		})
+	}
+}
@@ -72,7 +72,16 @@ func (e *EventController) sendHeartbeats(ctx context.Context, logger *log.Logger
 	sm.Mu.Lock()
 	for p, t := range sm.Programs {
 		if len(t.PIDs) > 0 && t.Category != "" {
-			items = append(items, item{p, t.Category, t.Project})
+			project := t.Project
+			if t.Detected != "" { // Project detected from working directory takes precedence
+				project = t.Detected
+			}
+			items = append(items, item{p, t.Category, project})
+		}
+	}
+	for label, t := range sm.Timers {
+		if t.Category != "" {
+			items = append(items, item{label, t.Category, t.Project})
 		}
 	}
 	sm.Mu.Unlock()
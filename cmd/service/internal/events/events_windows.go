@@ -35,6 +35,7 @@ func (e *EventController) StartMonitor(parent context.Context, logger *log.Logge
 	ctx, cancel := context.WithCancel(parent)
 	e.MonCancel = cancel
 	e.mu.Unlock()
+	e.configureSessions(s)
 	e.startProcessMonitor(ctx, logger, programs)
 }
 
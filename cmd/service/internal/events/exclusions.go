@@ -0,0 +1,21 @@
+package events
+
+import (
+	"github.com/jms-guy/timekeep/internal/database"
+)
+
+// Exclusion rule kinds stored in program_exclusions.kind
+const (
+	ExcludeArgs   = "args"   // Process' full command line contains pattern, ex. --type=renderer
+	ExcludeParent = "parent" // Process' parent executable basename equals pattern
+	ExcludePath   = "path"   // Process' executable path begins with pattern
+)
+
+// Groups exclusion rules by the program they belong to
+func groupExclusions(exclusions []database.ProgramExclusion) map[string][]database.ProgramExclusion {
+	grouped := make(map[string][]database.ProgramExclusion)
+	for _, ex := range exclusions {
+		grouped[ex.ProgramName] = append(grouped[ex.ProgramName], ex)
+	}
+	return grouped
+}
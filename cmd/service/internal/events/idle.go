@@ -0,0 +1,42 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/jms-guy/timekeep/cmd/service/internal/sessions"
+)
+
+// Share of one CPU a program's processes must use over a check to count as active
+const idleCPUThreshold = 0.01
+
+// Source of system-wide user input activity, used alongside process CPU time to decide if a program is idle
+type InputSource interface {
+	Idle(ctx context.Context, now time.Time, timeout time.Duration) (bool, error) // Reports whether the user has given no input for timeout
+}
+
+// Per-program activity state kept between idle checks. Each idle monitor owns its own state, so a monitor restarted with
+// new settings never shares it with the one it replaces
+type idleState struct {
+	input      InputSource                 // System input idle source, nil if none
+	cpu        map[sessions.ProcKey]uint64 // CPU ticks of each tracked process at the last check
+	lastActive map[string]time.Time        // Time each program was last seen active
+	lastCheck  time.Time
+}
+
+func newIdleState(input InputSource) *idleState {
+	return &idleState{input: input, cpu: make(map[sessions.ProcKey]uint64), lastActive: make(map[string]time.Time)}
+}
+
+// Returns the idle timeout from config, or zero if idle detection is disabled
+func (e *EventController) idleTimeout() time.Duration {
+	if e.Config.IdleTimeout == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(e.Config.IdleTimeout)
+	if err != nil || d <= 0 {
+		return 0
+	}
+
+	return d
+}
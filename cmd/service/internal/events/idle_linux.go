@@ -0,0 +1,194 @@
+//go:build linux
+
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jms-guy/timekeep/cmd/service/internal/sessions"
+	"github.com/jms-guy/timekeep/internal/repository"
+)
+
+// Linux specific idle detection, splitting sessions into active and idle segments based on the CPU time used by a program's
+// processes, and optionally on system-wide user input
+
+// Builds the input idle source named in config, nil if none is configured
+func newInputSource(name string) (InputSource, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case "interrupts":
+		return &interruptsSource{path: "/proc/interrupts"}, nil
+	case "logind":
+		return &logindSource{}, nil
+	default:
+		return nil, fmt.Errorf("unknown idle input source %q", name)
+	}
+}
+
+// Idle detection loop, checking tracked programs for activity every interval until context is cancelled. Settings are passed
+// in rather than read from the controller, which a monitor restart may change while this loop is still winding down
+func (e *EventController) MonitorIdle(ctx context.Context, logger *log.Logger, sm *sessions.SessionManager, a repository.ActiveRepository, st *idleState, timeout, interval time.Duration) {
+	logger.Printf("INFO: Executing idle monitor (timeout %s)", timeout)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			e.checkIdle(ctx, logger, sm, a, st, timeout, interval, now)
+		}
+	}
+}
+
+// Compares the CPU time of each tracked program's processes against the previous check. A program using CPU, while the
+// input source (if any) reports user input, is active; one inactive for timeout is marked idle from its last activity
+func (e *EventController) checkIdle(ctx context.Context, logger *log.Logger, sm *sessions.SessionManager, a repository.ActiveRepository, st *idleState, timeout, interval time.Duration, now time.Time) {
+	elapsed := now.Sub(st.lastCheck)
+	if st.lastCheck.IsZero() || elapsed <= 0 {
+		elapsed = interval
+	}
+	st.lastCheck = now
+	threshold := uint64(elapsed.Seconds() * clockTicks * idleCPUThreshold)
+
+	inputIdle := false
+	if st.input != nil {
+		idle, err := st.input.Idle(ctx, now, timeout)
+		if err != nil {
+			logger.Printf("WARNING: Failed to read input idle state: %s", err)
+		}
+		inputIdle = idle
+	}
+
+	sm.Mu.Lock()
+	running := make(map[string][]sessions.ProcKey, len(sm.Programs))
+	for program, t := range sm.Programs {
+		if t == nil || len(t.PIDs) == 0 {
+			continue
+		}
+		for key := range t.PIDs {
+			running[program] = append(running[program], key)
+		}
+	}
+	sm.Mu.Unlock()
+
+	seen := make(map[sessions.ProcKey]struct{})
+	for program, keys := range running {
+		active := false
+		for _, key := range keys {
+			seen[key] = struct{}{}
+
+			stat, err := e.Procs.Stat(key.PID)
+			if err != nil || stat.StartTime != key.StartTime {
+				continue
+			}
+			ticks := stat.UTime + stat.STime
+			prev, ok := st.cpu[key]
+			st.cpu[key] = ticks
+			if !ok || ticks-prev > threshold { // New processes count as activity
+				active = true
+			}
+		}
+		if inputIdle {
+			active = false
+		}
+
+		last, ok := st.lastActive[program]
+		if !ok {
+			last = now
+			st.lastActive[program] = now
+		}
+
+		if active {
+			st.lastActive[program] = now
+			sm.SetIdle(context.Background(), logger, a, program, false, now)
+		} else if now.Sub(last) >= timeout {
+			sm.SetIdle(context.Background(), logger, a, program, true, last)
+		}
+	}
+
+	for key := range st.cpu {
+		if _, ok := seen[key]; !ok {
+			delete(st.cpu, key)
+		}
+	}
+	for program := range st.lastActive {
+		if _, ok := running[program]; !ok {
+			delete(st.lastActive, program)
+		}
+	}
+}
+
+// Input source counting keyboard/mouse interrupts in /proc/interrupts. Lines for PS/2 (i8042), HID and USB host controller
+// devices are summed, so USB traffic from other devices may also count as input
+type interruptsSource struct {
+	path       string
+	count      uint64
+	lastChange time.Time
+}
+
+func (s *interruptsSource) Idle(_ context.Context, now time.Time, timeout time.Duration) (bool, error) {
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return false, err
+	}
+
+	count := countInputInterrupts(string(b))
+	if s.lastChange.IsZero() || count != s.count {
+		s.count = count
+		s.lastChange = now
+	}
+
+	return now.Sub(s.lastChange) >= timeout, nil
+}
+
+// Sums the per-CPU counts of input device lines in the contents of /proc/interrupts
+func countInputInterrupts(contents string) uint64 {
+	var total uint64
+	for _, line := range strings.Split(contents, "\n") {
+		lower := strings.ToLower(line)
+		if !strings.Contains(lower, "i8042") && !strings.Contains(lower, "hid") && !strings.Contains(lower, "xhci") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.HasSuffix(fields[0], ":") {
+			continue
+		}
+		for _, f := range fields[1:] {
+			n, err := strconv.ParseUint(f, 10, 64)
+			if err != nil { // Counts end where the interrupt chip/device names begin
+				break
+			}
+			total += n
+		}
+	}
+
+	return total
+}
+
+// Input source reading the IdleHint that desktop environments set on the logind seat. loginctl is run under the caller's
+// context, so a stopped monitor doesn't leave it running
+type logindSource struct{}
+
+func (s *logindSource) Idle(ctx context.Context, now time.Time, timeout time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "loginctl", "show-seat", "seat0", "--property=IdleHint", "--value").Output()
+	if err != nil {
+		return false, fmt.Errorf("loginctl failed: %w", err)
+	}
+
+	return strings.TrimSpace(string(out)) == "yes", nil
+}
@@ -0,0 +1,166 @@
+package events
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jms-guy/timekeep/cmd/service/internal/sessions"
+	"github.com/jms-guy/timekeep/internal/database"
+)
+
+// Program match modes stored in tracked_programs.match_mode
+const (
+	MatchExact = "exact" // Program name must equal the process basename
+	MatchGlob  = "glob"  // Program name is a shell glob pattern matched against the process basename
+	MatchRegex = "regex" // Program name is a regular expression matched against the process basename
+)
+
+// Compiled pattern for a tracked program, resolving process identities to the program's name
+type programMatcher struct {
+	program string         // Tracked program name, the session key for all matching processes
+	mode    string         // Match mode of program
+	re      *regexp.Regexp // Compiled expression for regex mode
+	exe     string         // Argv matcher - process basename required, for interpreter-hosted programs
+	args    string         // Argv matcher - substring required in the process' full command line
+}
+
+// Validates and compiles the matching rule for a tracked program
+func newProgramMatcher(p database.TrackedProgram) (*programMatcher, error) {
+	m := &programMatcher{program: p.Name, mode: p.MatchMode}
+
+	if p.Exe.Valid || p.ArgsContains.Valid { // Argv matchers identify by command line rather than program name
+		m.exe = strings.ToLower(p.Exe.String)
+		m.args = p.ArgsContains.String
+		return m, nil
+	}
+
+	switch p.MatchMode {
+	case "", MatchExact:
+		m.mode = MatchExact
+	case MatchGlob:
+		if _, err := path.Match(p.Name, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %q: %w", p.Name, err)
+		}
+	case MatchRegex:
+		re, err := regexp.Compile("(?i)" + p.Name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex pattern %q: %w", p.Name, err)
+		}
+		m.re = re
+	default:
+		return nil, fmt.Errorf("unknown match mode %q for %s", p.MatchMode, p.Name)
+	}
+
+	return m, nil
+}
+
+// Reports whether the matcher identifies processes by command line
+func (m *programMatcher) isArgv() bool {
+	return m.exe != "" || m.args != ""
+}
+
+// Reports whether a process' identity and full command line satisfy an argv matcher
+func (m *programMatcher) matchesArgv(identity string, cmdline []string) bool {
+	if m.exe != "" && m.exe != identity {
+		return false
+	}
+	return m.args == "" || strings.Contains(strings.Join(cmdline, " "), m.args)
+}
+
+// Reports whether a normalized process identity belongs to the program
+func (m *programMatcher) matches(identity string) bool {
+	switch m.mode {
+	case MatchGlob:
+		ok, _ := path.Match(m.program, identity)
+		return ok
+	case MatchRegex:
+		return m.re.MatchString(identity)
+	default:
+		return m.program == identity
+	}
+}
+
+// Compiles the pattern matchers, scopes and exclusion rules for the tracked program list, replacing any previously loaded set.
+// Programs with invalid patterns are logged and skipped
+func (e *EventController) LoadMatchers(logger *log.Logger, programs []database.TrackedProgram, exclusions []database.ProgramExclusion) {
+	matchers := make([]*programMatcher, 0, len(programs))
+	scopes := make(map[string]string, len(programs))
+	for _, p := range programs {
+		scopes[p.Name] = p.Scope
+
+		m, err := newProgramMatcher(p)
+		if err != nil {
+			logger.Printf("ERROR: Skipping program %s: %s", p.Name, err)
+			continue
+		}
+		if m.mode == MatchExact && !m.isArgv() { // Exact names are looked up directly in the sessions map
+			continue
+		}
+		matchers = append(matchers, m)
+	}
+
+	// Argv matchers are the most specific, so they're checked first
+	sort.SliceStable(matchers, func(i, j int) bool { return matchers[i].isArgv() && !matchers[j].isArgv() })
+
+	e.mu.Lock()
+	e.matchers = matchers
+	e.scopes = scopes
+	e.exclusions = groupExclusions(exclusions)
+	e.excludedProcs = nil // Rules may have changed, re-evaluate running processes
+	e.mu.Unlock()
+}
+
+// Returns the name of the tracked program a process belongs to. Argv matchers take precedence, then exact program names, then
+// patterns. The command line is only read through cmdline when an argv matcher needs it
+func (e *EventController) matchProgram(sm *sessions.SessionManager, identity string, cmdline func() []string) (string, bool) {
+	e.mu.Lock()
+	matchers := e.matchers
+	e.mu.Unlock()
+
+	var argv []string
+	argvRead := false
+	for _, m := range matchers {
+		if !m.isArgv() {
+			continue
+		}
+		if m.exe != "" && m.exe != identity {
+			continue
+		}
+		if !argvRead {
+			argv = cmdline()
+			argvRead = true
+		}
+		if m.matchesArgv(identity, argv) {
+			return m.program, true
+		}
+	}
+
+	sm.Mu.Lock()
+	_, ok := sm.Programs[identity]
+	sm.Mu.Unlock()
+	if ok && !isArgvProgram(matchers, identity) {
+		return identity, true
+	}
+
+	for _, m := range matchers {
+		if !m.isArgv() && m.matches(identity) {
+			return m.program, true
+		}
+	}
+
+	return "", false
+}
+
+// Reports whether a program name belongs to an argv matcher, those logical names never match a process basename directly
+func isArgvProgram(matchers []*programMatcher, program string) bool {
+	for _, m := range matchers {
+		if m.isArgv() && m.program == program {
+			return true
+		}
+	}
+	return false
+}
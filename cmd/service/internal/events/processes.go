@@ -0,0 +1,225 @@
+package events
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Source of process information used by the Linux monitor, abstracted so monitoring can be driven without touching the host
+type ProcessLister interface {
+	PIDs() ([]int, error)              // IDs of all live processes
+	Exe(pid int) (string, error)       // Resolved executable path of process
+	Cmdline(pid int) ([]string, error) // Full argument vector of process
+	Comm(pid int) (string, error)      // Kernel command name of process
+	Stat(pid int) (ProcStat, error)    // Parsed status fields of process
+	UID(pid int) (int, error)          // Real user ID owning process
+	Cgroup(pid int) ([]string, error)  // Cgroup membership lines of process, one per hierarchy
+	Cwd(pid int) (string, error)       // Current working directory of process
+	BootTime() (time.Time, error)      // Time the system booted, which process start times are relative to
+}
+
+// Kernel clock ticks per second (USER_HZ) used for /proc time values, fixed at 100 on Linux
+const clockTicks = 100
+
+// Converts a process start time in clock ticks after boot into wall clock time
+func processStartTime(boot time.Time, ticks uint64) time.Time {
+	return boot.Add(time.Duration(ticks) * time.Second / clockTicks)
+}
+
+// Subset of the /proc/{pid}/stat fields used by the monitor
+type ProcStat struct {
+	PPID      int    // Field 4, parent process ID
+	UTime     uint64 // Field 14, CPU time spent in user mode, in clock ticks
+	STime     uint64 // Field 15, CPU time spent in kernel mode, in clock ticks
+	StartTime uint64 // Field 22, time the process started after system boot, in clock ticks
+}
+
+// ProcessLister reading from a procfs mount
+type procfsLister struct {
+	root string // Mount point of procfs, normally /proc
+}
+
+func NewProcfsLister(root string) ProcessLister {
+	return &procfsLister{root: root}
+}
+
+// Reads procfs root, returning every numeric PID entry
+func (p *procfsLister) PIDs() ([]int, error) {
+	entries, err := os.ReadDir(p.root)
+	if err != nil {
+		return nil, err
+	}
+
+	pids := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		pid, ok := parsePID(entry.Name())
+		if !ok {
+			continue
+		}
+		pids = append(pids, pid)
+	}
+
+	return pids, nil
+}
+
+// Read process {root}/{pid}/exe path to get program path
+func (p *procfsLister) Exe(pid int) (string, error) {
+	target, err := os.Readlink(p.path(pid, "exe"))
+	if err != nil {
+		return "", err
+	}
+
+	real, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return target, nil
+	}
+
+	return real, nil
+}
+
+// Read process {root}/{pid}/cmdline path to get argument vector
+func (p *procfsLister) Cmdline(pid int) ([]string, error) {
+	b, err := os.ReadFile(p.path(pid, "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(strings.TrimRight(string(b), "\x00"), "\x00")
+	if len(parts) == 0 || parts[0] == "" {
+		return nil, fmt.Errorf("empty cmdline")
+	}
+
+	return parts, nil
+}
+
+// Read process {root}/{pid}/comm path to get kernel command name
+func (p *procfsLister) Comm(pid int) (string, error) {
+	b, err := os.ReadFile(p.path(pid, "comm"))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Read process {root}/{pid}/stat path to get process status fields
+func (p *procfsLister) Stat(pid int) (ProcStat, error) {
+	b, err := os.ReadFile(p.path(pid, "stat"))
+	if err != nil {
+		return ProcStat{}, err
+	}
+
+	return parseStat(string(b))
+}
+
+// Read process {root}/{pid}/status Uid line to get the real user ID owning process
+func (p *procfsLister) UID(pid int) (int, error) {
+	b, err := os.ReadFile(p.path(pid, "status"))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "Uid:" { // Real, effective, saved set and filesystem UIDs
+			uid, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return 0, fmt.Errorf("malformed status uid: %w", err)
+			}
+			return uid, nil
+		}
+	}
+
+	return 0, fmt.Errorf("uid not found in status")
+}
+
+// Read process {root}/{pid}/cgroup path to get cgroup membership
+func (p *procfsLister) Cgroup(pid int) ([]string, error) {
+	b, err := os.ReadFile(p.path(pid, "cgroup"))
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Split(strings.TrimSpace(string(b)), "\n"), nil
+}
+
+// Read process {root}/{pid}/cwd path to get working directory
+func (p *procfsLister) Cwd(pid int) (string, error) {
+	return os.Readlink(p.path(pid, "cwd"))
+}
+
+// Read {root}/stat btime line to get system boot time
+func (p *procfsLister) BootTime() (time.Time, error) {
+	b, err := os.ReadFile(filepath.Join(p.root, "stat"))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "btime" {
+			secs, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("malformed btime: %w", err)
+			}
+			return time.Unix(secs, 0), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("btime not found")
+}
+
+func (p *procfsLister) path(pid int, file string) string {
+	return filepath.Join(p.root, strconv.Itoa(pid), file)
+}
+
+// Parses the contents of a /proc/{pid}/stat file. The command name (field 2) is wrapped in parentheses and may itself
+// contain spaces or parentheses, so fields are counted from the last closing parenthesis
+func parseStat(s string) (ProcStat, error) {
+	i := strings.LastIndexByte(s, ')')
+	if i < 0 {
+		return ProcStat{}, fmt.Errorf("malformed stat")
+	}
+
+	fields := strings.Fields(s[i+1:]) // Begins at field 3
+	if len(fields) < 20 {
+		return ProcStat{}, fmt.Errorf("malformed stat: %d fields", len(fields)+2)
+	}
+
+	ppid, err := strconv.Atoi(fields[4-3])
+	if err != nil {
+		return ProcStat{}, fmt.Errorf("malformed stat ppid: %w", err)
+	}
+
+	utime, err := strconv.ParseUint(fields[14-3], 10, 64)
+	if err != nil {
+		return ProcStat{}, fmt.Errorf("malformed stat utime: %w", err)
+	}
+
+	stime, err := strconv.ParseUint(fields[15-3], 10, 64)
+	if err != nil {
+		return ProcStat{}, fmt.Errorf("malformed stat stime: %w", err)
+	}
+
+	startTime, err := strconv.ParseUint(fields[22-3], 10, 64)
+	if err != nil {
+		return ProcStat{}, fmt.Errorf("malformed stat starttime: %w", err)
+	}
+
+	return ProcStat{PPID: ppid, UTime: utime, STime: stime, StartTime: startTime}, nil
+}
+
+func parsePID(name string) (int, bool) {
+	pid, err := strconv.Atoi(name)
+	if err != nil || pid <= 0 {
+		return 0, false
+	}
+	return pid, true
+}
@@ -0,0 +1,148 @@
+package events
+
+import (
+	"fmt"
+	"io/fs"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Process entry held by FakeProcessLister
+type FakeProcess struct {
+	Exe       string   // Executable path, left empty to simulate an unreadable exe link
+	Cmdline   []string // Argument vector
+	Comm      string   // Kernel command name
+	StartTime uint64   // Start time in clock ticks after boot, change it alongside the PID to simulate PID reuse
+	PPID      int      // Parent process ID
+	CPUTime   uint64   // CPU time used in clock ticks, increase it to simulate activity
+	UID       int      // Real user ID owning process
+	Cgroup    []string // Cgroup membership lines, left empty for a host process outside any unit
+	Cwd       string   // Working directory
+}
+
+// In-memory ProcessLister, for driving the monitor deterministically in tests
+type FakeProcessLister struct {
+	mu    sync.Mutex
+	procs map[int]FakeProcess
+	Boot  time.Time // Boot time reported by the fake system
+}
+
+func NewFakeProcessLister() *FakeProcessLister {
+	return &FakeProcessLister{procs: make(map[int]FakeProcess), Boot: time.Now().Add(-time.Hour)}
+}
+
+// Adds a process to the fake process table, replacing any existing entry with the same PID
+func (f *FakeProcessLister) Start(pid int, proc FakeProcess) {
+	f.mu.Lock()
+	f.procs[pid] = proc
+	f.mu.Unlock()
+}
+
+// Removes a process from the fake process table
+func (f *FakeProcessLister) Stop(pid int) {
+	f.mu.Lock()
+	delete(f.procs, pid)
+	f.mu.Unlock()
+}
+
+func (f *FakeProcessLister) PIDs() ([]int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pids := make([]int, 0, len(f.procs))
+	for pid := range f.procs {
+		pids = append(pids, pid)
+	}
+	sort.Ints(pids)
+
+	return pids, nil
+}
+
+func (f *FakeProcessLister) Exe(pid int) (string, error) {
+	proc, err := f.get(pid)
+	if err != nil {
+		return "", err
+	}
+	if proc.Exe == "" {
+		return "", fmt.Errorf("no exe for pid %d", pid)
+	}
+
+	return proc.Exe, nil
+}
+
+func (f *FakeProcessLister) Cmdline(pid int) ([]string, error) {
+	proc, err := f.get(pid)
+	if err != nil {
+		return nil, err
+	}
+	if len(proc.Cmdline) == 0 || proc.Cmdline[0] == "" {
+		return nil, fmt.Errorf("empty cmdline")
+	}
+
+	return proc.Cmdline, nil
+}
+
+func (f *FakeProcessLister) Comm(pid int) (string, error) {
+	proc, err := f.get(pid)
+	if err != nil {
+		return "", err
+	}
+
+	return proc.Comm, nil
+}
+
+func (f *FakeProcessLister) Stat(pid int) (ProcStat, error) {
+	proc, err := f.get(pid)
+	if err != nil {
+		return ProcStat{}, err
+	}
+
+	return ProcStat{PPID: proc.PPID, UTime: proc.CPUTime, StartTime: proc.StartTime}, nil
+}
+
+func (f *FakeProcessLister) UID(pid int) (int, error) {
+	proc, err := f.get(pid)
+	if err != nil {
+		return 0, err
+	}
+
+	return proc.UID, nil
+}
+
+func (f *FakeProcessLister) Cgroup(pid int) ([]string, error) {
+	proc, err := f.get(pid)
+	if err != nil {
+		return nil, err
+	}
+
+	return proc.Cgroup, nil
+}
+
+func (f *FakeProcessLister) Cwd(pid int) (string, error) {
+	proc, err := f.get(pid)
+	if err != nil {
+		return "", err
+	}
+	if proc.Cwd == "" {
+		return "", fmt.Errorf("no cwd for pid %d", pid)
+	}
+
+	return proc.Cwd, nil
+}
+
+func (f *FakeProcessLister) BootTime() (time.Time, error) {
+	return f.Boot, nil
+}
+
+func (f *FakeProcessLister) get(pid int) (FakeProcess, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	proc, ok := f.procs[pid]
+	if !ok {
+		return FakeProcess{}, fs.ErrNotExist
+	}
+
+	return proc, nil
+}
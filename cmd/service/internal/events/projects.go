@@ -0,0 +1,31 @@
+package events
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Marker file naming the project of the directory tree it's placed in. Empty markers use the directory's name
+const projectMarker = ".timekeep-project"
+
+// Walks up from a process' working directory to the nearest project root, a directory containing a .timekeep-project
+// marker or a .git directory (or worktree file), returning the project's name. Returns an empty string if none is found
+func detectProject(fsys fs.FS, cwd string) string {
+	dir := strings.Trim(path.Clean("/"+cwd), "/") // fs.FS paths are unrooted
+	for dir != "" && dir != "." {
+		if b, err := fs.ReadFile(fsys, path.Join(dir, projectMarker)); err == nil {
+			if name, _, _ := strings.Cut(strings.TrimSpace(string(b)), "\n"); strings.TrimSpace(name) != "" {
+				return strings.TrimSpace(name)
+			}
+			return path.Base(dir)
+		}
+		if _, err := fs.Stat(fsys, path.Join(dir, ".git")); err == nil {
+			return path.Base(dir)
+		}
+
+		dir = path.Dir(dir)
+	}
+
+	return ""
+}
@@ -0,0 +1,78 @@
+//go:build linux
+
+package events
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"strconv"
+)
+
+// Linux specific user filtering, limiting tracked processes to those owned by the configured users
+
+// Resolves the users config value into the set of UIDs whose processes are tracked. A nil set tracks processes of all users
+func resolveUsers(users []string) (map[int]struct{}, error) {
+	if len(users) == 0 {
+		return map[int]struct{}{invokingUID(): {}}, nil
+	}
+
+	uids := make(map[int]struct{}, len(users))
+	for _, name := range users {
+		if name == "*" {
+			return nil, nil
+		}
+
+		if uid, err := strconv.Atoi(name); err == nil {
+			uids[uid] = struct{}{}
+			continue
+		}
+
+		u, err := user.Lookup(name)
+		if err != nil {
+			return nil, fmt.Errorf("unknown user %s: %w", name, err)
+		}
+		uid, err := strconv.Atoi(u.Uid)
+		if err != nil {
+			return nil, fmt.Errorf("invalid uid %s for user %s", u.Uid, name)
+		}
+		uids[uid] = struct{}{}
+	}
+
+	return uids, nil
+}
+
+// Returns the UID of the user running the service, or the user who invoked it through sudo
+func invokingUID() int {
+	if sudo, err := strconv.Atoi(os.Getenv("SUDO_UID")); err == nil {
+		return sudo
+	}
+	return os.Getuid()
+}
+
+// Loads the set of users whose processes are tracked from config. If a user can't be resolved, falls back to tracking the
+// service user only
+func (e *EventController) loadUsers(logger *log.Logger) {
+	uids, err := resolveUsers(e.Config.Users)
+	if err != nil {
+		logger.Printf("WARNING: Failed to resolve tracked users, tracking the service user only: %s", err)
+		uids = map[int]struct{}{invokingUID(): {}}
+	}
+
+	e.mu.Lock()
+	e.uids = uids
+	e.mu.Unlock()
+}
+
+// Reports whether processes owned by a UID are tracked
+func (e *EventController) userTracked(uid int) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.uids == nil {
+		return true
+	}
+	_, ok := e.uids[uid]
+	return ok
+}
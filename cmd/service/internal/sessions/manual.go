@@ -0,0 +1,98 @@
+package sessions
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/jms-guy/timekeep/internal/database"
+	"github.com/jms-guy/timekeep/internal/repository"
+)
+
+// Manual timer started by the user for an activity that isn't a process, ex. a meeting. Recorded as an active session
+// flagged manual under its label, and kept running across service restarts until stopped
+type Timer struct {
+	Category string
+	Project  string
+	StartAt  time.Time
+}
+
+// Starts a manual timer under label, creating its active session. Labels of tracked programs and running timers are refused
+func (sm *SessionManager) StartTimer(ctx context.Context, logger *log.Logger, a repository.ActiveRepository, label, category, project string) {
+	sm.Mu.Lock()
+	if sm.Timers == nil {
+		sm.Timers = make(map[string]*Timer)
+	}
+	if _, ok := sm.Timers[label]; ok {
+		sm.Mu.Unlock()
+		logger.Printf("INFO: Timer %s already running", label)
+		return
+	}
+	if _, ok := sm.Programs[label]; ok {
+		sm.Mu.Unlock()
+		logger.Printf("ERROR: Can't start timer %s, label belongs to a tracked program", label)
+		return
+	}
+
+	timer := &Timer{Category: category, Project: project, StartAt: time.Now()}
+	sm.Timers[label] = timer
+	sm.Mu.Unlock()
+
+	params := database.CreateActiveSessionParams{
+		ProgramName: label,
+		StartTime:   timer.StartAt,
+		Project:     sql.NullString{String: project, Valid: project != ""},
+		Manual:      true,
+		Category:    sql.NullString{String: category, Valid: category != ""},
+	}
+	if err := a.CreateActiveSession(ctx, params); err != nil {
+		sm.Mu.Lock()
+		delete(sm.Timers, label)
+		sm.Mu.Unlock()
+		logger.Printf("ERROR: creating active session for timer %s: %v", label, err)
+		return
+	}
+
+	logger.Printf("INFO: Started timer %s at %s", label, timer.StartAt)
+}
+
+// Stops the manual timer under label, or every running timer if label is empty, moving their sessions into history
+func (sm *SessionManager) StopTimer(ctx context.Context, logger *log.Logger, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository, label string) {
+	sm.Mu.Lock()
+	var labels []string
+	if label == "" {
+		for l := range sm.Timers {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+	} else if _, ok := sm.Timers[label]; ok {
+		labels = append(labels, label)
+	}
+	for _, l := range labels {
+		delete(sm.Timers, l)
+	}
+	sm.Mu.Unlock()
+
+	if len(labels) == 0 {
+		logger.Printf("INFO: No timer running for %q", label)
+		return
+	}
+
+	for _, l := range labels {
+		sm.MoveSessionToHistory(ctx, logger, pr, a, h, l)
+	}
+}
+
+// Puts a manual timer left running by the previous service back into the timers map
+func (sm *SessionManager) resumeTimer(logger *log.Logger, active database.ActiveSession) {
+	sm.Mu.Lock()
+	if sm.Timers == nil {
+		sm.Timers = make(map[string]*Timer)
+	}
+	sm.Timers[active.ProgramName] = &Timer{Category: active.Category.String, Project: active.Project.String, StartAt: active.StartTime}
+	sm.Mu.Unlock()
+
+	logger.Printf("INFO: Resumed timer %s started at %s", active.ProgramName, active.StartTime)
+}
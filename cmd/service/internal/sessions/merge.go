@@ -0,0 +1,78 @@
+package sessions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jms-guy/timekeep/internal/database"
+	"github.com/jms-guy/timekeep/internal/repository"
+	"github.com/jms-guy/timekeep/internal/tags"
+)
+
+// Reopens the program's last history session if it ended within gap of startAt and belongs to the same user, moving it
+// back into active_sessions so the new session extends it. The gap between the sessions counts as idle time, so sessions
+// ending before tracking last resumed from a pause aren't reopened. Returns the reopened session and its idle time, or
+// false if a new session should be created instead
+func reopenSession(ctx context.Context, logger *log.Logger, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository, processName string, info ProcInfo, startAt time.Time, gap time.Duration, resumed time.Time) (database.SessionHistory, int64, bool) {
+	last, err := h.GetLastSessionForProgram(ctx, processName)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logger.Printf("ERROR: Error getting last session for %s: %s", processName, err)
+		}
+		return database.SessionHistory{}, 0, false
+	}
+
+	between := startAt.Sub(last.EndTime)
+	if between > gap || last.Uid != info.UID || last.EndTime.Before(resumed) {
+		return database.SessionHistory{}, 0, false
+	}
+	idle := last.IdleSeconds + max(int64(between.Seconds()), 0)
+
+	err = runTx(ctx, pr, a, h, func(pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository) error {
+		lastTags, err := h.GetTagsForSession(ctx, last.ID)
+		if err != nil {
+			return fmt.Errorf("error getting session tags: %w", err)
+		}
+		if err := h.RemoveTagsForSession(ctx, last.ID); err != nil {
+			return fmt.Errorf("error removing session tags: %w", err)
+		}
+		if err := h.RemoveSessionRecord(ctx, last.ID); err != nil {
+			return fmt.Errorf("error removing session record: %w", err)
+		}
+
+		err = pr.UpdateLifetime(ctx, database.UpdateLifetimeParams{
+			Name:            processName,
+			LifetimeSeconds: -last.DurationSeconds,
+			IdleSeconds:     -last.IdleSeconds,
+		})
+		if err != nil {
+			return fmt.Errorf("error updating lifetime: %w", err)
+		}
+
+		err = a.CreateActiveSession(ctx, database.CreateActiveSessionParams{
+			ProgramName: processName,
+			StartTime:   last.StartTime,
+			Uid:         last.Uid,
+			Container:   last.Container,
+			Unit:        last.Unit,
+			Project:     last.Project,
+			Notes:       last.Notes,
+			Tags:        tags.Join(lastTags),
+		})
+		if err != nil {
+			return fmt.Errorf("error creating active session: %w", err)
+		}
+
+		return a.UpdateActiveIdle(ctx, database.UpdateActiveIdleParams{IdleSeconds: idle, ProgramName: processName})
+	})
+	if err != nil {
+		logger.Printf("ERROR: Error reopening last session for %s: %s", processName, err)
+		return database.SessionHistory{}, 0, false
+	}
+
+	return last, idle, true
+}
@@ -0,0 +1,220 @@
+package sessions
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/jms-guy/timekeep/internal/database"
+	"github.com/jms-guy/timekeep/internal/repository"
+)
+
+// Key of the pause covering every program
+const AllPrograms = ""
+
+// Pause of tracking for a program, or for every program under AllPrograms. A paused program has its session ended, and
+// no processes attributed to it until tracking resumes
+type Pause struct {
+	Until time.Time   // Time tracking resumes on its own, zero to stay paused until resumed
+	timer *time.Timer // Resumes tracking at Until
+}
+
+// Pauses tracking of the given programs, or of every program if none are given, until resumed or until is reached if set.
+// Open sessions of paused programs are ended and moved into history, manual timers are left running. The pauses are
+// saved, to be restored by RecoverPauses. onResume, if set, is called after a pause runs out
+func (sm *SessionManager) Pause(ctx context.Context, logger *log.Logger, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository, programs []string, until time.Time, onResume func()) {
+	keys := programs
+	if len(keys) == 0 {
+		keys = []string{AllPrograms}
+	}
+
+	now := time.Now()
+	for _, key := range keys {
+		err := pr.AddPause(ctx, database.AddPauseParams{
+			ProgramName: key,
+			PausedAt:    now,
+			ResumeAt:    sql.NullTime{Time: until, Valid: !until.IsZero()},
+		})
+		if err != nil {
+			logger.Printf("ERROR: Error saving pause of %s: %s", pauseName(key), err)
+		}
+
+		sm.setPause(logger, pr, key, until, onResume)
+		if until.IsZero() {
+			logger.Printf("INFO: Paused tracking of %s", pauseName(key))
+		} else {
+			logger.Printf("INFO: Paused tracking of %s until %s", pauseName(key), until)
+		}
+	}
+
+	sm.Mu.Lock()
+	var ending []string
+	for program, t := range sm.Programs {
+		if len(t.PIDs) == 0 || !sm.isPaused(program) {
+			continue
+		}
+		clear(t.PIDs)
+		sm.CloseIdle(ctx, logger, a, program, now)
+		ending = append(ending, program)
+	}
+	sm.Mu.Unlock()
+
+	for _, program := range ending {
+		sm.MoveSessionToHistory(ctx, logger, pr, a, h, program)
+	}
+}
+
+// Resumes tracking of the given programs, or of everything paused if none are given. A program paused through a pause of
+// every program stays paused until that pause is resumed. Returns whether any of the pauses existed
+func (sm *SessionManager) Resume(ctx context.Context, logger *log.Logger, pr repository.ProgramRepository, programs []string) bool {
+	sm.Mu.Lock()
+	keys := programs
+	if len(keys) == 0 {
+		for key := range sm.Paused {
+			keys = append(keys, key)
+		}
+	}
+	resumed := sm.endPauses(keys, time.Now())
+	sm.Mu.Unlock()
+
+	sm.removePauses(ctx, logger, pr, resumed)
+
+	return len(resumed) > 0
+}
+
+// Reports whether tracking of a program is paused, by its own pause or a pause of every program
+func (sm *SessionManager) IsPaused(program string) bool {
+	sm.Mu.Lock()
+	defer sm.Mu.Unlock()
+
+	return sm.isPaused(program)
+}
+
+// Restores the pauses saved by the previous service, resuming tracking where a pause ran out while it was stopped.
+// Must run before monitoring starts
+func (sm *SessionManager) RecoverPauses(ctx context.Context, logger *log.Logger, pr repository.ProgramRepository, onResume func()) {
+	pauses, err := pr.GetAllPauses(ctx)
+	if err != nil {
+		logger.Printf("ERROR: Failed to get saved pauses: %s", err)
+		return
+	}
+
+	now := time.Now()
+	for _, pause := range pauses {
+		if pause.ResumeAt.Valid && !pause.ResumeAt.Time.After(now) {
+			sm.Mu.Lock()
+			sm.markResumed(pause.ProgramName, pause.ResumeAt.Time)
+			sm.Mu.Unlock()
+			sm.removePauses(ctx, logger, pr, []string{pause.ProgramName})
+			continue
+		}
+
+		sm.setPause(logger, pr, pause.ProgramName, pause.ResumeAt.Time, onResume)
+		logger.Printf("INFO: Restored pause of %s", pauseName(pause.ProgramName))
+	}
+}
+
+// Records a pause in memory, replacing any existing pause under key, and arms its timer to resume tracking at until
+func (sm *SessionManager) setPause(logger *log.Logger, pr repository.ProgramRepository, key string, until time.Time, onResume func()) {
+	pause := &Pause{Until: until}
+
+	sm.Mu.Lock()
+	defer sm.Mu.Unlock()
+
+	if sm.Paused == nil {
+		sm.Paused = make(map[string]*Pause)
+	}
+	if old := sm.Paused[key]; old != nil && old.timer != nil {
+		old.timer.Stop()
+	}
+	sm.Paused[key] = pause
+
+	if !until.IsZero() {
+		pause.timer = time.AfterFunc(time.Until(until), func() {
+			sm.expirePause(logger, pr, key, pause, onResume)
+		})
+	}
+}
+
+// Resumes tracking once a pause runs out, unless it's been replaced or resumed since its timer was armed
+func (sm *SessionManager) expirePause(logger *log.Logger, pr repository.ProgramRepository, key string, pause *Pause, onResume func()) {
+	sm.Mu.Lock()
+	if sm.Paused[key] != pause {
+		sm.Mu.Unlock()
+		return
+	}
+	sm.endPauses([]string{key}, pause.Until)
+	sm.Mu.Unlock()
+
+	sm.removePauses(context.Background(), logger, pr, []string{key})
+
+	if onResume != nil {
+		onResume()
+	}
+}
+
+// Ends the pauses under the given keys at the given time, returning the keys that were paused
+// Caller MUST hold sm.Mu Lock
+func (sm *SessionManager) endPauses(keys []string, at time.Time) []string {
+	var ended []string
+	for _, key := range keys {
+		pause, ok := sm.Paused[key]
+		if !ok {
+			continue
+		}
+		if pause.timer != nil {
+			pause.timer.Stop()
+		}
+		delete(sm.Paused, key)
+		sm.markResumed(key, at)
+		ended = append(ended, key)
+	}
+
+	return ended
+}
+
+// Deletes saved pauses once tracking has resumed
+func (sm *SessionManager) removePauses(ctx context.Context, logger *log.Logger, pr repository.ProgramRepository, keys []string) {
+	for _, key := range keys {
+		if err := pr.RemovePause(ctx, key); err != nil {
+			logger.Printf("ERROR: Error removing pause of %s: %s", pauseName(key), err)
+			continue
+		}
+		logger.Printf("INFO: Resumed tracking of %s", pauseName(key))
+	}
+}
+
+// Records the time tracking under key resumed, sessions aren't started or merged across it
+// Caller MUST hold sm.Mu Lock
+func (sm *SessionManager) markResumed(key string, at time.Time) {
+	if sm.resumed == nil {
+		sm.resumed = make(map[string]time.Time)
+	}
+	sm.resumed[key] = at
+}
+
+// Caller MUST hold sm.Mu Lock
+func (sm *SessionManager) isPaused(program string) bool {
+	_, all := sm.Paused[AllPrograms]
+	_, paused := sm.Paused[program]
+	return all || paused
+}
+
+// Returns the last time tracking of a program resumed from a pause, zero if never
+// Caller MUST hold sm.Mu Lock
+func (sm *SessionManager) resumedAt(program string) time.Time {
+	at := sm.resumed[program]
+	if all := sm.resumed[AllPrograms]; all.After(at) {
+		at = all
+	}
+	return at
+}
+
+// Describes a pause key in log messages
+func pauseName(key string) string {
+	if key == AllPrograms {
+		return "all programs"
+	}
+	return key
+}
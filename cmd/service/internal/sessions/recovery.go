@@ -0,0 +1,78 @@
+package sessions
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/jms-guy/timekeep/internal/database"
+	"github.com/jms-guy/timekeep/internal/repository"
+	"github.com/jms-guy/timekeep/internal/tags"
+)
+
+// How often the last-seen time of open sessions is written to the database. A session orphaned by a crash is closed at
+// its last write, so at most this much tracked time is lost
+const HeartbeatInterval = time.Minute
+
+// Closes active sessions left in the database by a service that didn't shut down cleanly (killed, or power loss), moving
+// them into history marked as recovered. Each session ends at its last-seen time, or is recorded with no duration if the
+// service died before it was ever written. Processes that survived the crash open their next session from where the
+// recovered one ends (see CreateSession), so the recovered time isn't counted twice. Manual timers aren't tied to the
+// service's lifetime, so they're resumed instead. Must run before monitoring starts, while no sessions are open in memory
+func (sm *SessionManager) RecoverSessions(ctx context.Context, logger *log.Logger, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository) {
+	orphaned, err := a.GetAllActiveSessions(ctx)
+	if err != nil {
+		logger.Printf("ERROR: Failed to get active sessions for recovery: %s", err)
+		return
+	}
+
+	for _, active := range orphaned {
+		if active.Manual {
+			sm.resumeTimer(logger, active)
+			continue
+		}
+
+		endTime := active.StartTime
+		if active.LastSeen.Valid && active.LastSeen.Time.After(active.StartTime) {
+			endTime = active.LastSeen.Time
+		}
+		duration := int64(endTime.Sub(active.StartTime).Seconds())
+
+		recovered := database.AddToSessionHistoryParams{
+			ProgramName:     active.ProgramName,
+			StartTime:       active.StartTime,
+			EndTime:         endTime,
+			DurationSeconds: duration,
+			Uid:             active.Uid,
+			Container:       active.Container,
+			Unit:            active.Unit,
+			Project:         active.Project,
+			IdleSeconds:     min(active.IdleSeconds, duration),
+			Recovered:       true,
+			Notes:           active.Notes,
+		}
+		if !archiveSession(ctx, logger, pr, a, h, recovered, tags.Parse(active.Tags), sm.splitDays.Load()) {
+			continue
+		}
+
+		logger.Printf("WARNING: Recovered orphaned session for %s (duration: %d seconds)", active.ProgramName, duration)
+	}
+}
+
+// Periodically records the current time as the last-seen time of all open sessions, until context is cancelled
+func (sm *SessionManager) RecordHeartbeats(ctx context.Context, logger *log.Logger, a repository.ActiveRepository) {
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := a.UpdateActiveLastSeen(ctx, sql.NullTime{Time: now, Valid: true}); err != nil {
+				logger.Printf("ERROR: Failed to record session heartbeat: %s", err)
+			}
+		}
+	}
+}
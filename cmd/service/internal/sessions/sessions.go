@@ -2,30 +2,99 @@ package sessions
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"log"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jms-guy/timekeep/internal/database"
+	"github.com/jms-guy/timekeep/internal/programs"
+	"github.com/jms-guy/timekeep/internal/report"
 	"github.com/jms-guy/timekeep/internal/repository"
+	"github.com/jms-guy/timekeep/internal/tags"
 )
 
+// Identifies a single process instance. PIDs are recycled by the kernel, so the process start time (in clock ticks
+// since boot) is paired with the PID to tell a new process apart from an exited one. StartTime is 0 where unknown
+type ProcKey struct {
+	PID       int
+	StartTime uint64
+}
+
+// Details of the process opening a session, recorded on the session. Fields are left unset where unknown
+type ProcInfo struct {
+	UID       sql.NullInt64  // Owner of the process
+	Container sql.NullString // ID of the container the process runs in
+	Unit      sql.NullString // Systemd unit the process runs under
+	Project   sql.NullString // Project detected from the process' working directory
+}
+
 type Tracked struct {
 	Category string
 	Project  string
-	PIDs     map[int]struct{}
+	Detected string // Project detected for the current session, overrides Project in heartbeats
+	PIDs     map[ProcKey]struct{}
 	StartAt  time.Time
 	LastSeen time.Time
+
+	IdleSince   time.Time // Start of the session's open idle segment, zero while active
+	IdleSeconds int64     // Idle time of the session's closed idle segments
+}
+
+// Closes the session's open idle segment at the given time, returning the session's total idle time and whether a segment
+// was open. Caller MUST hold sm.Mu Lock
+func (t *Tracked) closeIdle(at time.Time) (int64, bool) {
+	if t.IdleSince.IsZero() {
+		return t.IdleSeconds, false
+	}
+
+	if at.After(t.IdleSince) {
+		t.IdleSeconds += int64(at.Sub(t.IdleSince).Seconds())
+	}
+	t.IdleSince = time.Time{}
+
+	return t.IdleSeconds, true
+}
+
+// Returns the tracked process key for a PID, regardless of its start time
+func (t *Tracked) KeyForPID(pid int) (ProcKey, bool) {
+	for key := range t.PIDs {
+		if key.PID == pid {
+			return key, true
+		}
+	}
+	return ProcKey{}, false
 }
 
 type SessionManager struct {
 	Programs map[string]*Tracked
+	Timers   map[string]*Timer // Running manual timers, by label
+	Paused   map[string]*Pause // Paused programs, by name or AllPrograms
 	Mu       sync.Mutex
+	MergeGap time.Duration // Sessions starting within this long of the program's last session extend it, zero to disable
+
+	resumed map[string]time.Time // Time tracking last resumed from a pause, by program name or AllPrograms
+
+	minSession atomic.Int64 // Global minimum session length in nanoseconds, read while sm.Mu may be held by the caller
+	splitDays  atomic.Bool  // Store sessions crossing midnight as one history row per local day
+}
+
+// Sets the global minimum session length, sessions shorter than it are discarded rather than moved into history.
+// Programs may override it with their own min_session
+func (sm *SessionManager) SetMinSession(d time.Duration) {
+	sm.minSession.Store(int64(d))
 }
 
 func NewSessionManager() *SessionManager {
-	return &SessionManager{Programs: make(map[string]*Tracked)}
+	return &SessionManager{
+		Programs: make(map[string]*Tracked),
+		Timers:   make(map[string]*Timer),
+		Paused:   make(map[string]*Pause),
+		resumed:  make(map[string]time.Time),
+	}
 }
 
 // Make sure map is initialized, add program to map if not already present
@@ -35,11 +104,11 @@ func (sm *SessionManager) EnsureProgram(name, category, project string) {
 		sm.Programs = make(map[string]*Tracked)
 	}
 
-	name = strings.ToLower(name)
+	name = programs.Normalize(name)
 	tracked, ok := sm.Programs[name]
 
 	if !ok { // Program not in tracked list?
-		sm.Programs[name] = &Tracked{Category: category, Project: project, PIDs: make(map[int]struct{})}
+		sm.Programs[name] = &Tracked{Category: category, Project: project, PIDs: make(map[ProcKey]struct{})}
 		return
 	}
 
@@ -52,108 +121,352 @@ func (sm *SessionManager) EnsureProgram(name, category, project string) {
 	}
 }
 
-// If no process is running with given name, will create a new active session in database.
-// If there is already a process running with given name, new PID will be added to active session
-func (sm *SessionManager) CreateSession(ctx context.Context, logger *log.Logger, a repository.ActiveRepository, processName string, pid int) {
+// If no process is running with given name, will create a new active session in database, starting at startAt (or now, if zero),
+// recording the process' info. If there is already a process running with given name, new PID will be added to active session.
+// Processes of paused programs are ignored, and sessions don't start before tracking last resumed or before the program's
+// last history session ended
+func (sm *SessionManager) CreateSession(ctx context.Context, logger *log.Logger, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository, processName string, key ProcKey, info ProcInfo, startAt time.Time) {
 	sm.Mu.Lock()
 
+	if sm.isPaused(processName) {
+		sm.Mu.Unlock()
+		return
+	}
+
 	t := sm.Programs[processName]
 	if t == nil {
-		t = &Tracked{PIDs: make(map[int]struct{})}
+		t = &Tracked{PIDs: make(map[ProcKey]struct{})}
 		sm.Programs[processName] = t
 	}
 
-	if _, ok := t.PIDs[pid]; ok {
+	if _, ok := t.PIDs[key]; ok {
 		t.LastSeen = time.Now()
 		sm.Mu.Unlock()
-		logger.Printf("INFO: PID %d already tracked for %s", pid, processName)
+		logger.Printf("INFO: PID %d already tracked for %s", key.PID, processName)
 		return
 	}
-	t.PIDs[pid] = struct{}{}
+	t.PIDs[key] = struct{}{}
 
 	now := time.Now()
+	if startAt.IsZero() || startAt.After(now) {
+		startAt = now
+	}
+	resumed := sm.resumedAt(processName)
+	if startAt.Before(resumed) {
+		startAt = resumed
+	}
 	if len(t.PIDs) == 1 {
-		t.StartAt = now
+		t.StartAt = startAt
+		t.Detected = info.Project.String
+		t.IdleSince = time.Time{}
+		t.IdleSeconds = 0
 	}
 
 	t.LastSeen = now
+	first := len(t.PIDs) == 1
+	gap := sm.MergeGap
 	sm.Mu.Unlock()
 
-	if len(t.PIDs) == 1 {
-		params := database.CreateActiveSessionParams{ProgramName: processName, StartTime: now}
+	if first {
+		startAt = sm.startAfterHistory(ctx, logger, h, processName, t, startAt)
+	}
+
+	if first && gap > 0 {
+		if last, idle, ok := reopenSession(ctx, logger, pr, a, h, processName, info, startAt, gap, resumed); ok {
+			sm.Mu.Lock()
+			t.StartAt = last.StartTime
+			t.Detected = last.Project.String
+			t.IdleSeconds = idle
+			sm.Mu.Unlock()
+			logger.Printf("INFO: Reopened session for %s from %s, last ended %s ago", processName, last.StartTime, startAt.Sub(last.EndTime).Round(time.Second))
+			return
+		}
+	}
+
+	if first {
+		params := database.CreateActiveSessionParams{
+			ProgramName: processName,
+			StartTime:   startAt,
+			Uid:         info.UID,
+			Container:   info.Container,
+			Unit:        info.Unit,
+			Project:     info.Project,
+		}
 		if err := a.CreateActiveSession(ctx, params); err != nil {
 			logger.Printf("ERROR: creating active session for %s: %v", processName, err)
 			return
 		}
-		logger.Printf("INFO: Created new session for %s at %s", processName, now)
+		logger.Printf("INFO: Created new session for %s at %s", processName, startAt)
 	} else {
-		logger.Printf("INFO: Added PID %d to existing session for %s", pid, processName)
+		logger.Printf("INFO: Added PID %d to existing session for %s", key.PID, processName)
 	}
 }
 
+// Moves a new session's start to the end of the program's last history session if it starts before then. A process
+// still running when its session was archived (by the service stopping, or recovered after a crash) would otherwise be
+// counted again from its start time
+func (sm *SessionManager) startAfterHistory(ctx context.Context, logger *log.Logger, h repository.HistoryRepository, processName string, t *Tracked, startAt time.Time) time.Time {
+	last, err := h.GetLastSessionForProgram(ctx, processName)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logger.Printf("ERROR: Error getting last session for %s: %s", processName, err)
+		}
+		return startAt
+	}
+	if !startAt.Before(last.EndTime) || last.EndTime.After(time.Now()) {
+		return startAt
+	}
+
+	sm.Mu.Lock()
+	if t.StartAt.Before(last.EndTime) {
+		t.StartAt = last.EndTime
+	}
+	sm.Mu.Unlock()
+
+	return last.EndTime
+}
+
 // Removes PID from sessions map, if there are still processes running with given name, session will not end.
 // If last process for given name ends, the active session is terminated, and session is moved into session history.
-func (sm *SessionManager) EndSession(ctx context.Context, logger *log.Logger, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository, processName string, pid int) {
+func (sm *SessionManager) EndSession(ctx context.Context, logger *log.Logger, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository, processName string, key ProcKey) {
 	sm.Mu.Lock()
 
 	t, ok := sm.Programs[processName]
 	if !ok {
 		sm.Mu.Unlock()
-		logger.Printf("INFO: No active session for %s (pid %d)", processName, pid)
+		logger.Printf("INFO: No active session for %s (pid %d)", processName, key.PID)
 		return
 	}
 
-	if _, ok := t.PIDs[pid]; !ok {
+	if _, ok := t.PIDs[key]; !ok {
 		sm.Mu.Unlock()
-		logger.Printf("INFO: PID %d not tracked for %s", pid, processName)
+		logger.Printf("INFO: PID %d not tracked for %s", key.PID, processName)
 		return
 	}
 
-	delete(t.PIDs, pid)
+	delete(t.PIDs, key)
 
 	now := time.Now()
 	t.LastSeen = now
+	last := len(t.PIDs) == 0
+	idle, closed := int64(0), false
+	if last { // Session ending while idle, the idle segment runs up to the end
+		idle, closed = t.closeIdle(now)
+	}
 	sm.Mu.Unlock()
 
-	if len(t.PIDs) == 0 {
+	if last {
+		if closed {
+			sm.persistIdle(ctx, logger, a, processName, idle)
+		}
 		sm.MoveSessionToHistory(ctx, logger, pr, a, h, processName)
 	}
 }
 
+// Marks a program's session idle from the given time, or active again, closing the open idle segment and persisting the
+// session's idle time. Sessions that aren't running are ignored
+func (sm *SessionManager) SetIdle(ctx context.Context, logger *log.Logger, a repository.ActiveRepository, processName string, idle bool, at time.Time) {
+	sm.Mu.Lock()
+
+	t := sm.Programs[processName]
+	if t == nil || len(t.PIDs) == 0 {
+		sm.Mu.Unlock()
+		return
+	}
+
+	if idle {
+		if !t.IdleSince.IsZero() { // Already idle
+			sm.Mu.Unlock()
+			return
+		}
+		if at.Before(t.StartAt) {
+			at = t.StartAt
+		}
+		t.IdleSince = at
+		sm.Mu.Unlock()
+		logger.Printf("INFO: Session for %s idle since %s", processName, at)
+		return
+	}
+
+	total, closed := t.closeIdle(at)
+	sm.Mu.Unlock()
+
+	if closed {
+		sm.persistIdle(ctx, logger, a, processName, total)
+		logger.Printf("INFO: Session for %s active again (idle: %d seconds)", processName, total)
+	}
+}
+
+// Closes a session's open idle segment at the given time, persisting its idle time ahead of the session being moved to history
+// Caller MUST hold sm.Mu Lock
+func (sm *SessionManager) CloseIdle(ctx context.Context, logger *log.Logger, a repository.ActiveRepository, processName string, at time.Time) {
+	t := sm.Programs[processName]
+	if t == nil {
+		return
+	}
+
+	if total, closed := t.closeIdle(at); closed {
+		sm.persistIdle(ctx, logger, a, processName, total)
+	}
+}
+
+// Writes a session's idle time to its active session row
+func (sm *SessionManager) persistIdle(ctx context.Context, logger *log.Logger, a repository.ActiveRepository, processName string, idle int64) {
+	err := a.UpdateActiveIdle(ctx, database.UpdateActiveIdleParams{IdleSeconds: idle, ProgramName: processName})
+	if err != nil {
+		logger.Printf("ERROR: Error updating idle time for %s: %s", processName, err)
+	}
+}
+
 // Takes an active session and moves it into session history, ending active status
 func (sm *SessionManager) MoveSessionToHistory(ctx context.Context, logger *log.Logger, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository, processName string) {
-	startTime, err := a.GetActiveSession(ctx, processName)
+	active, err := a.GetActiveSession(ctx, processName)
 	if err != nil {
 		logger.Printf("ERROR: Error getting active session from database: %s", err)
 		return
 	}
 	endTime := time.Now()
-	duration := int64(endTime.Sub(startTime).Seconds())
+	duration := int64(endTime.Sub(active.StartTime).Seconds())
+
+	if !active.Manual { // Timers are started on purpose, never discarded as too short
+		if minimum := sm.minSessionFor(ctx, logger, pr, processName); duration < int64(minimum.Seconds()) {
+			if err := a.RemoveActiveSession(ctx, processName); err != nil {
+				logger.Printf("ERROR: Error removing active session for %s: %s", processName, err)
+				return
+			}
+			logger.Printf("INFO: Discarded session for %s shorter than minimum length (duration: %d seconds, minimum: %s)", processName, duration, minimum)
+			return
+		}
+	}
 
 	archivedSession := database.AddToSessionHistoryParams{
 		ProgramName:     processName,
-		StartTime:       startTime,
+		StartTime:       active.StartTime,
 		EndTime:         endTime,
 		DurationSeconds: duration,
+		Uid:             active.Uid,
+		Container:       active.Container,
+		Unit:            active.Unit,
+		Project:         active.Project,
+		IdleSeconds:     min(active.IdleSeconds, duration),
+		Manual:          active.Manual,
+		Category:        active.Category,
+		Notes:           active.Notes,
 	}
-	err = h.AddToSessionHistory(ctx, archivedSession)
-	if err != nil {
-		logger.Printf("ERROR: Error creating session history for %s: %s", processName, err)
+	if !archiveSession(ctx, logger, pr, a, h, archivedSession, tags.Parse(active.Tags), sm.splitDays.Load()) {
 		return
 	}
 
-	err = pr.UpdateLifetime(ctx, database.UpdateLifetimeParams{
-		Name:            processName,
-		LifetimeSeconds: duration,
+	logger.Printf("INFO: Moved session for %s to history (duration: %d seconds, idle: %d seconds)", processName, duration, archivedSession.IdleSeconds)
+}
+
+// Sets whether sessions crossing local midnight are stored as one history row per day when archived
+func (sm *SessionManager) SetSplitDays(split bool) {
+	sm.splitDays.Store(split)
+}
+
+// Returns the minimum session length for a program, its own min_session if set or else the global minimum
+func (sm *SessionManager) minSessionFor(ctx context.Context, logger *log.Logger, pr repository.ProgramRepository, processName string) time.Duration {
+	program, err := pr.GetProgramByName(ctx, processName)
+	if err != nil {
+		logger.Printf("ERROR: Error getting program %s: %s", processName, err)
+	} else if program.MinSession.Valid {
+		return time.Duration(program.MinSession.Int64) * time.Second
+	}
+
+	return time.Duration(sm.minSession.Load())
+}
+
+// Writes a finished session to history, adds it to the program's lifetime and removes its active session row, as one
+// transaction where the repositories support it. With splitDays, a session crossing local midnight is written as one row
+// per day, each row given the session's tags. Returns false if archiving failed, leaving the active session in place
+func archiveSession(ctx context.Context, logger *log.Logger, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository, session database.AddToSessionHistoryParams, sessionTags []string, splitDays bool) bool {
+	rows := []database.AddToSessionHistoryParams{session}
+	if splitDays {
+		rows = splitByDay(session)
+	}
+
+	err := runTx(ctx, pr, a, h, func(pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository) error {
+		return archive(ctx, pr, a, h, session, sessionTags, rows)
 	})
 	if err != nil {
-		logger.Printf("ERROR: Error updating lifetime for %s: %s", processName, err)
+		logger.Printf("ERROR: Error archiving session for %s: %s", session.ProgramName, err)
+		return false
 	}
 
-	err = a.RemoveActiveSession(ctx, processName)
+	return true
+}
+
+func archive(ctx context.Context, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository, session database.AddToSessionHistoryParams, sessionTags []string, rows []database.AddToSessionHistoryParams) error {
+	for _, row := range rows {
+		id, err := h.AddToSessionHistory(ctx, row)
+		if err != nil {
+			return fmt.Errorf("error creating session history: %w", err)
+		}
+		for _, tag := range sessionTags {
+			if err := h.AddSessionTag(ctx, database.AddSessionTagParams{SessionID: id, Tag: tag}); err != nil {
+				return fmt.Errorf("error tagging session history: %w", err)
+			}
+		}
+	}
+
+	err := pr.UpdateLifetime(ctx, database.UpdateLifetimeParams{
+		Name:            session.ProgramName,
+		LifetimeSeconds: session.DurationSeconds,
+		IdleSeconds:     session.IdleSeconds,
+	})
 	if err != nil {
-		logger.Printf("ERROR: Error removing active session for %s: %s", processName, err)
+		return fmt.Errorf("error updating lifetime: %w", err)
 	}
 
-	logger.Printf("INFO: Moved session for %s to history (duration: %d seconds)", processName, duration)
+	if err := a.RemoveActiveSession(ctx, session.ProgramName); err != nil {
+		return fmt.Errorf("error removing active session: %w", err)
+	}
+
+	return nil
+}
+
+// Splits a session at each local midnight it crosses into one history row per day. Idle time is shared out between the
+// rows in proportion to their length, as the time of day it fell at isn't kept
+func splitByDay(session database.AddToSessionHistoryParams) []database.AddToSessionHistoryParams {
+	days := report.SplitDays(session.StartTime, session.EndTime)
+	if len(days) < 2 {
+		return []database.AddToSessionHistoryParams{session}
+	}
+
+	rows := make([]database.AddToSessionHistoryParams, 0, len(days))
+	remaining, idleRemaining := session.DurationSeconds, session.IdleSeconds
+	for i, day := range days {
+		row := session
+		row.StartTime, row.EndTime = day.Start, day.End
+
+		if i == len(days)-1 { // Last row takes what's left, so the rows add up to the session
+			row.DurationSeconds, row.IdleSeconds = remaining, idleRemaining
+		} else {
+			row.DurationSeconds = min(int64(day.End.Sub(day.Start).Seconds()), remaining)
+			row.IdleSeconds = 0
+			if session.DurationSeconds > 0 {
+				row.IdleSeconds = min(session.IdleSeconds*row.DurationSeconds/session.DurationSeconds, idleRemaining)
+			}
+		}
+		remaining -= row.DurationSeconds
+		idleRemaining -= row.IdleSeconds
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// Runs fn against repositories bound to a single transaction where they share a transactional store, or directly
+// against the given repositories otherwise
+func runTx(ctx context.Context, pr repository.ProgramRepository, a repository.ActiveRepository, h repository.HistoryRepository, fn func(repository.ProgramRepository, repository.ActiveRepository, repository.HistoryRepository) error) error {
+	store, ok := h.(repository.Store)
+	if !ok {
+		return fn(pr, a, h)
+	}
+
+	return store.WithTx(ctx, func(tx repository.Store) error {
+		return fn(tx, tx, tx)
+	})
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"time"
 
 	"github.com/jms-guy/timekeep/cmd/service/internal/daemons"
 	"github.com/jms-guy/timekeep/cmd/service/internal/events"
@@ -105,6 +106,7 @@ func (s *timekeepService) closeService(logger *log.Logger) {
 	for program, tracked := range s.sessions.Programs { // End any active sessions
 		if len(tracked.PIDs) != 0 {
 			logger.Println("INFO: Ending active sessions")
+			s.sessions.CloseIdle(context.Background(), s.logger.Logger, s.asRepo, program, time.Now())
 			s.sessions.MoveSessionToHistory(context.Background(), s.logger.Logger, s.prRepo, s.asRepo, s.hsRepo, program)
 		}
 	}
@@ -52,6 +52,18 @@ func (s *timekeepService) Execute(args []string, r <-chan svc.ChangeRequest, sta
 		status <- svc.Status{State: svc.Stopped}
 		return false, 1
 	}
+	exclusions, err := s.prRepo.GetAllExclusions(context.Background())
+	if err != nil {
+		s.logger.Logger.Printf("ERROR: Failed to get program exclusions: %s", err)
+	}
+
+	s.eventCtrl.LoadMatchers(s.logger.Logger, programs, exclusions)
+
+	// Close sessions left open by an unclean shutdown before tracking starts again
+	s.sessions.RecoverSessions(context.Background(), s.logger.Logger, s.prRepo, s.asRepo, s.hsRepo)
+	s.eventCtrl.RecoverPauses(serviceCtx, s.logger.Logger, s.sessions, s.prRepo, s.asRepo, s.hsRepo)
+	go s.sessions.RecordHeartbeats(serviceCtx, s.logger.Logger, s.asRepo)
+
 	if len(programs) > 0 {
 		toTrack := []string{}
 		for _, program := range programs {
@@ -0,0 +1,230 @@
+package chart
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// Charts of tracked time drawn for the terminal, fitted to its width. Unicode block characters are used unless ASCII is
+// asked for, and shading is colored with ANSI escapes only when color is enabled, so NO_COLOR and piped output stay plain
+
+// How a chart is drawn
+type Options struct {
+	Width int  // Columns available to the chart
+	Color bool // Color bars and heatmap cells with ANSI escapes
+	ASCII bool // Draw with ASCII characters only
+}
+
+// Narrowest width charts are squeezed into, for terminals reporting no or a tiny width
+const minWidth = 40
+
+func (o Options) width() int {
+	return max(o.Width, minWidth)
+}
+
+// One bar of a bar chart
+type Bar struct {
+	Label string
+	Value int64  // Length of the bar, relative to the largest bar
+	Text  string // Shown after the bar, ex. the duration it stands for
+}
+
+const (
+	reset     = "\x1b[0m"
+	barColor  = "\x1b[36m"
+	zeroColor = "\x1b[90m"
+)
+
+// Partial blocks drawing the fraction of a cell at the end of a bar, in eighths
+var eighths = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+// Heatmap shading from an empty cell up to the largest value, and the colors of each level (GitHub's greens)
+var (
+	shades      = []string{"·", "░", "▒", "▓", "█"}
+	asciiShades = []string{".", ":", "+", "*", "#"}
+	shadeColors = []string{zeroColor, "\x1b[38;5;22m", "\x1b[38;5;28m", "\x1b[38;5;34m", "\x1b[38;5;40m"}
+)
+
+// Draws a horizontal bar chart, one labeled bar per row scaled to the largest value
+func Bars(w io.Writer, bars []Bar, opts Options) {
+	labelWidth, textWidth := 0, 0
+	var largest int64
+	for _, bar := range bars {
+		labelWidth = max(labelWidth, len([]rune(bar.Label)))
+		textWidth = max(textWidth, len([]rune(bar.Text)))
+		largest = max(largest, bar.Value)
+	}
+	labelWidth = min(labelWidth, opts.width()/3)
+	barWidth := max(opts.width()-labelWidth-textWidth-4, 10)
+
+	for _, bar := range bars {
+		length := 0.0
+		if largest > 0 {
+			length = float64(bar.Value) / float64(largest) * float64(barWidth)
+		}
+		drawn, cells := drawBar(length, opts)
+		if opts.Color && drawn != "" {
+			drawn = barColor + drawn + reset
+		}
+
+		fmt.Fprintf(w, "  %-*s %s%s %s\n", labelWidth, truncate(bar.Label, labelWidth), drawn, strings.Repeat(" ", barWidth-cells), bar.Text)
+	}
+}
+
+// Returns a bar of the given length in cells, and the number of cells it takes up
+func drawBar(length float64, opts Options) (string, int) {
+	if opts.ASCII {
+		cells := int(math.Round(length))
+		return strings.Repeat("#", cells), cells
+	}
+
+	full := int(length)
+	part := eighths[int((length-float64(full))*8)]
+	cells := full
+	if part != "" {
+		cells++
+	}
+	return strings.Repeat("█", full) + part, cells
+}
+
+// Totals of one local day, for Calendar
+type Day struct {
+	Date    time.Time // Local midnight starting the day
+	Seconds int64
+}
+
+// Draws a calendar heatmap of daily totals, one column per week (Monday first) and one row per weekday, in the style of
+// GitHub's contribution graph. Days must be consecutive and in order. Only the most recent weeks that fit are drawn
+func Calendar(w io.Writer, days []Day, opts Options) {
+	if len(days) == 0 {
+		return
+	}
+
+	// Pad the first week back to its Monday, cells before the first day are left blank
+	lead := (int(days[0].Date.Weekday()) + 6) % 7
+	weeks := (lead + len(days) + 6) / 7
+
+	const labelWidth = 4
+	if fit := (opts.width() - labelWidth - 2) / 2; weeks > fit {
+		drop := weeks - fit
+		days = days[max(drop*7-lead, 0):]
+		lead = (int(days[0].Date.Weekday()) + 6) % 7
+		weeks = fit
+	}
+
+	var largest int64
+	for _, day := range days {
+		largest = max(largest, day.Seconds)
+	}
+
+	// Month names above the week their first day falls in, where they don't run into the previous name. The month the
+	// chart starts in is only named if over two weeks of it are shown, leaving room before the next name
+	months := []byte(strings.Repeat(" ", weeks*2))
+	next := 0
+	for i, day := range days {
+		if day.Date.Day() != 1 && (i != 0 || day.Date.Day() > 14) {
+			continue
+		}
+		col := (lead + i) / 7 * 2
+		if col < next || col+3 > len(months) {
+			continue
+		}
+		copy(months[col:], day.Date.Format("Jan"))
+		next = col + 4
+	}
+	fmt.Fprintf(w, "  %*s%s\n", labelWidth, "", strings.TrimRight(string(months), " "))
+
+	labels := []string{"Mon", "", "Wed", "", "Fri", "", ""}
+	for row := range 7 {
+		var line strings.Builder
+		for week := range weeks {
+			i := week*7 + row - lead
+			if i < 0 || i >= len(days) {
+				line.WriteString("  ")
+				continue
+			}
+			line.WriteString(cell(days[i].Seconds, largest, opts) + " ")
+		}
+		fmt.Fprintf(w, "  %-*s%s\n", labelWidth, labels[row], strings.TrimRight(line.String(), " "))
+	}
+
+	fmt.Fprintf(w, "\n  %*s%s\n", labelWidth, "", legend(opts))
+}
+
+// Draws a heatmap of time by hour of the day (columns) and weekday (rows, Monday first)
+func HourGrid(w io.Writer, grid [7][24]int64, opts Options) {
+	const labelWidth = 4
+	cellWidth := 2
+	if labelWidth+24*cellWidth+2 > opts.width() {
+		cellWidth = 1
+	}
+
+	var largest int64
+	for _, hours := range grid {
+		for _, seconds := range hours {
+			largest = max(largest, seconds)
+		}
+	}
+
+	// Hour labels every 3 hours, or every 6 in narrow cells
+	step := 3
+	if cellWidth == 1 {
+		step = 6
+	}
+	header := []byte(strings.Repeat(" ", 24*cellWidth))
+	for hour := 0; hour < 24; hour += step {
+		copy(header[hour*cellWidth:], fmt.Sprintf("%d", hour))
+	}
+	fmt.Fprintf(w, "  %*s%s\n", labelWidth, "", strings.TrimRight(string(header), " "))
+
+	for day, hours := range grid {
+		var line strings.Builder
+		for _, seconds := range hours {
+			line.WriteString(cell(seconds, largest, opts))
+			if cellWidth == 2 {
+				line.WriteString(" ")
+			}
+		}
+		fmt.Fprintf(w, "  %-*s%s\n", labelWidth, time.Weekday((day + 1) % 7).String()[:3], strings.TrimRight(line.String(), " "))
+	}
+
+	fmt.Fprintf(w, "\n  %*s%s\n", labelWidth, "", legend(opts))
+}
+
+// Returns the shaded cell of a heatmap value, on a scale of four levels up to the largest value
+func cell(value, largest int64, opts Options) string {
+	level := 0
+	if value > 0 && largest > 0 {
+		level = int(math.Ceil(float64(value) / float64(largest) * 4))
+	}
+
+	shade := shades[level]
+	if opts.ASCII {
+		shade = asciiShades[level]
+	}
+	if opts.Color {
+		return shadeColors[level] + shade + reset
+	}
+	return shade
+}
+
+// Returns the key to a heatmap's shading
+func legend(opts Options) string {
+	cells := make([]string, len(shades))
+	for level := range shades {
+		cells[level] = cell(int64(level), int64(len(shades)-1), opts)
+	}
+	return "Less " + strings.Join(cells, " ") + " More"
+}
+
+// Cuts a label down to width, marking the cut with a trailing "~"
+func truncate(label string, width int) string {
+	runes := []rune(label)
+	if len(runes) <= width {
+		return label
+	}
+	return string(runes[:width-1]) + "~"
+}
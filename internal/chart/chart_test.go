@@ -0,0 +1,81 @@
+package chart
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Returns the lines written, asserting none is wider than width
+func lines(t *testing.T, out string, width int) []string {
+	t.Helper()
+	split := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	for _, line := range split {
+		assert.LessOrEqual(t, utf8.RuneCountInString(line), width, "Line should fit the width: %q", line)
+	}
+	return split
+}
+
+func TestBars(t *testing.T) {
+	bars := []Bar{{Label: "firefox", Value: 4 * 3600, Text: "4h 0m"}, {Label: "code", Value: 3600, Text: "1h 0m"}}
+
+	var out bytes.Buffer
+	Bars(&out, bars, Options{Width: 60})
+	rows := lines(t, out.String(), 60)
+	require.Len(t, rows, 2)
+	assert.Equal(t, 4*strings.Count(rows[1], "█"), strings.Count(rows[0], "█"), "Bars should be scaled to their values")
+	assert.True(t, strings.HasSuffix(rows[0], " 4h 0m"))
+	assert.NotContains(t, out.String(), "\x1b[", "Output shouldn't be colored without Color")
+
+	out.Reset()
+	Bars(&out, bars, Options{Width: 60, ASCII: true, Color: true})
+	assert.Contains(t, out.String(), "#")
+	assert.NotContains(t, out.String(), "█", "ASCII output should use ASCII characters only")
+	assert.Contains(t, out.String(), barColor)
+}
+
+func TestCalendar(t *testing.T) {
+	first := time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local) // Wednesday
+	var days []Day
+	for i := range 365 {
+		days = append(days, Day{Date: first.AddDate(0, 0, i), Seconds: int64(i % 5 * 3600)})
+	}
+
+	var out bytes.Buffer
+	Calendar(&out, days, Options{Width: 120})
+	rows := lines(t, out.String(), 120)
+	require.Len(t, rows, 10, "Calendar should hold a month row, a row per weekday and the legend")
+	assert.True(t, strings.HasPrefix(strings.TrimSpace(rows[0]), "Jan"))
+	assert.Contains(t, rows[0], "Dec")
+	assert.True(t, strings.HasPrefix(rows[1], "  Mon   "), "Days before the first should be blank: %q", rows[1])
+
+	out.Reset()
+	Calendar(&out, days, Options{Width: 40})
+	rows = lines(t, out.String(), 40)
+	assert.Contains(t, rows[0], "Dec", "Narrow calendar should keep the most recent weeks")
+	assert.NotContains(t, rows[0], "Jan")
+}
+
+func TestHourGrid(t *testing.T) {
+	var grid [7][24]int64
+	grid[0][9] = 3600
+	grid[6][23] = 1800
+
+	var out bytes.Buffer
+	HourGrid(&out, grid, Options{Width: 80})
+	rows := lines(t, out.String(), 80)
+	require.Len(t, rows, 10)
+	assert.True(t, strings.HasPrefix(rows[1], "  Mon "))
+	assert.Equal(t, "█", strings.Fields(rows[1])[1+9], "Largest hour should be fully shaded")
+	assert.Equal(t, "▒", strings.Fields(rows[7])[1+23])
+
+	out.Reset()
+	HourGrid(&out, grid, Options{Width: 40, ASCII: true})
+	rows = lines(t, out.String(), 40)
+	assert.Equal(t, "  Mon .........#..............", rows[1], "Narrow grid should use single column cells")
+}
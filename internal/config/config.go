@@ -10,10 +10,19 @@ import (
 
 // Main user configuration struct
 type Config struct {
-	WakaTime     WakaTimeConfig `json:"wakatime"`                // WakaTime integration variables
-	Wakapi       WakapiConfig   `json:"wakapi"`                  // Wakapi integration variables
-	PollInterval string         `json:"poll_interval,omitempty"` // Linux - monitor polling interval, default 1s
-	PollGrace    int            `json:"poll_grace,omitempty"`    // Linux - number representing the grace period granted to PIDs accidently missed by polling, default 3
+	WakaTime       WakaTimeConfig `json:"wakatime"`                  // WakaTime integration variables
+	Wakapi         WakapiConfig   `json:"wakapi"`                    // Wakapi integration variables
+	PollInterval   string         `json:"poll_interval,omitempty"`   // Linux - monitor polling interval, default 1s
+	PollGrace      int            `json:"poll_grace,omitempty"`      // Linux - number representing the grace period granted to PIDs accidently missed by polling, default 3
+	MonitorBackend string         `json:"monitor_backend,omitempty"` // Linux - process monitor backend, "poll" or "netlink", default poll. Netlink falls back to polling if the socket can't be opened
+	DetectedStart  bool           `json:"detected_start,omitempty"`  // Linux - start sessions at the time the monitor first sees a process, instead of the kernel process start time
+	Users          []string       `json:"users,omitempty"`           // Linux - users whose processes are tracked, by name or UID, "*" for all users. Default is the user running the service
+	DetectProject  bool           `json:"detect_project,omitempty"`  // Linux - detect session projects from the working directory of processes, overriding programs' static project
+	IdleTimeout    string         `json:"idle_timeout,omitempty"`    // Linux - time without activity after which a session counts as idle, ex. "5m". Idle detection is disabled if unset
+	IdleInput      string         `json:"idle_input,omitempty"`      // Linux - optional system input idle source used with CPU activity, "interrupts" or "logind"
+	MergeGap       string         `json:"merge_gap,omitempty"`       // Sessions starting within this long of the last session end extend it instead, ex. "30s". Disabled if unset
+	MinSession     string         `json:"min_session,omitempty"`     // Sessions shorter than this are discarded, ex. "5s". Programs may set their own minimum. Disabled if unset
+	SplitDays      bool           `json:"split_days,omitempty"`      // Store sessions crossing midnight as one history row per local day
 }
 
 type WakaTimeConfig struct {
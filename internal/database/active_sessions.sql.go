@@ -7,38 +7,82 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 )
 
 const createActiveSession = `-- name: CreateActiveSession :exec
-INSERT INTO active_sessions (program_name, start_time)
-VALUES (?, ?)
+INSERT INTO active_sessions (program_name, start_time, uid, container, unit, project, manual, category, notes, tags)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 `
 
 type CreateActiveSessionParams struct {
 	ProgramName string
 	StartTime   time.Time
+	Uid         sql.NullInt64
+	Container   sql.NullString
+	Unit        sql.NullString
+	Project     sql.NullString
+	Manual      bool
+	Category    sql.NullString
+	Notes       sql.NullString
+	Tags        sql.NullString
 }
 
 func (q *Queries) CreateActiveSession(ctx context.Context, arg CreateActiveSessionParams) error {
-	_, err := q.db.ExecContext(ctx, createActiveSession, arg.ProgramName, arg.StartTime)
+	_, err := q.db.ExecContext(ctx, createActiveSession,
+		arg.ProgramName,
+		arg.StartTime,
+		arg.Uid,
+		arg.Container,
+		arg.Unit,
+		arg.Project,
+		arg.Manual,
+		arg.Category,
+		arg.Notes,
+		arg.Tags,
+	)
 	return err
 }
 
 const getActiveSession = `-- name: GetActiveSession :one
-SELECT start_time FROM active_sessions
+SELECT start_time, uid, container, unit, project, idle_seconds, manual, category, notes, tags FROM active_sessions
 WHERE program_name = ?
 `
 
-func (q *Queries) GetActiveSession(ctx context.Context, programName string) (time.Time, error) {
+type GetActiveSessionRow struct {
+	StartTime   time.Time
+	Uid         sql.NullInt64
+	Container   sql.NullString
+	Unit        sql.NullString
+	Project     sql.NullString
+	IdleSeconds int64
+	Manual      bool
+	Category    sql.NullString
+	Notes       sql.NullString
+	Tags        sql.NullString
+}
+
+func (q *Queries) GetActiveSession(ctx context.Context, programName string) (GetActiveSessionRow, error) {
 	row := q.db.QueryRowContext(ctx, getActiveSession, programName)
-	var start_time time.Time
-	err := row.Scan(&start_time)
-	return start_time, err
+	var i GetActiveSessionRow
+	err := row.Scan(
+		&i.StartTime,
+		&i.Uid,
+		&i.Container,
+		&i.Unit,
+		&i.Project,
+		&i.IdleSeconds,
+		&i.Manual,
+		&i.Category,
+		&i.Notes,
+		&i.Tags,
+	)
+	return i, err
 }
 
 const getAllActiveSessions = `-- name: GetAllActiveSessions :many
-SELECT id, program_name, start_time FROM active_sessions
+SELECT id, program_name, start_time, uid, container, unit, project, idle_seconds, last_seen, manual, category, notes, tags FROM active_sessions
 `
 
 func (q *Queries) GetAllActiveSessions(ctx context.Context) ([]ActiveSession, error) {
@@ -50,7 +94,21 @@ func (q *Queries) GetAllActiveSessions(ctx context.Context) ([]ActiveSession, er
 	var items []ActiveSession
 	for rows.Next() {
 		var i ActiveSession
-		if err := rows.Scan(&i.ID, &i.ProgramName, &i.StartTime); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProgramName,
+			&i.StartTime,
+			&i.Uid,
+			&i.Container,
+			&i.Unit,
+			&i.Project,
+			&i.IdleSeconds,
+			&i.LastSeen,
+			&i.Manual,
+			&i.Category,
+			&i.Notes,
+			&i.Tags,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -82,3 +140,61 @@ func (q *Queries) RemoveAllSessions(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, removeAllSessions)
 	return err
 }
+
+const updateActiveIdle = `-- name: UpdateActiveIdle :exec
+UPDATE active_sessions
+SET idle_seconds = ?
+WHERE program_name = ?
+`
+
+type UpdateActiveIdleParams struct {
+	IdleSeconds int64
+	ProgramName string
+}
+
+func (q *Queries) UpdateActiveIdle(ctx context.Context, arg UpdateActiveIdleParams) error {
+	_, err := q.db.ExecContext(ctx, updateActiveIdle, arg.IdleSeconds, arg.ProgramName)
+	return err
+}
+
+const updateActiveLastSeen = `-- name: UpdateActiveLastSeen :exec
+UPDATE active_sessions
+SET last_seen = ?
+`
+
+func (q *Queries) UpdateActiveLastSeen(ctx context.Context, lastSeen sql.NullTime) error {
+	_, err := q.db.ExecContext(ctx, updateActiveLastSeen, lastSeen)
+	return err
+}
+
+const updateActiveNotes = `-- name: UpdateActiveNotes :exec
+UPDATE active_sessions
+SET notes = ?
+WHERE program_name = ?
+`
+
+type UpdateActiveNotesParams struct {
+	Notes       sql.NullString
+	ProgramName string
+}
+
+func (q *Queries) UpdateActiveNotes(ctx context.Context, arg UpdateActiveNotesParams) error {
+	_, err := q.db.ExecContext(ctx, updateActiveNotes, arg.Notes, arg.ProgramName)
+	return err
+}
+
+const updateActiveTags = `-- name: UpdateActiveTags :exec
+UPDATE active_sessions
+SET tags = ?
+WHERE program_name = ?
+`
+
+type UpdateActiveTagsParams struct {
+	Tags        sql.NullString
+	ProgramName string
+}
+
+func (q *Queries) UpdateActiveTags(ctx context.Context, arg UpdateActiveTagsParams) error {
+	_, err := q.db.ExecContext(ctx, updateActiveTags, arg.Tags, arg.ProgramName)
+	return err
+}
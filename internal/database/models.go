@@ -13,6 +13,23 @@ type ActiveSession struct {
 	ID          int64
 	ProgramName string
 	StartTime   time.Time
+	Uid         sql.NullInt64
+	Container   sql.NullString
+	Unit        sql.NullString
+	Project     sql.NullString
+	IdleSeconds int64
+	LastSeen    sql.NullTime
+	Manual      bool
+	Category    sql.NullString
+	Notes       sql.NullString
+	Tags        sql.NullString
+}
+
+type ProgramExclusion struct {
+	ID          int64
+	ProgramName string
+	Kind        string
+	Pattern     string
 }
 
 type SessionHistory struct {
@@ -21,6 +38,23 @@ type SessionHistory struct {
 	StartTime       time.Time
 	EndTime         time.Time
 	DurationSeconds int64
+	Uid             sql.NullInt64
+	Container       sql.NullString
+	Unit            sql.NullString
+	Project         sql.NullString
+	IdleSeconds     int64
+	Recovered       bool
+	Manual          bool
+	Category        sql.NullString
+	Notes           sql.NullString
+	StartUnix       int64
+	EndUnix         int64
+}
+
+type SessionTag struct {
+	ID        int64
+	SessionID int64
+	Tag       string
 }
 
 type TrackedProgram struct {
@@ -29,4 +63,16 @@ type TrackedProgram struct {
 	LifetimeSeconds int64
 	Category        sql.NullString
 	Project         sql.NullString
+	MatchMode       string
+	Exe             sql.NullString
+	ArgsContains    sql.NullString
+	Scope           string
+	IdleSeconds     int64
+	MinSession      sql.NullInt64
+}
+
+type TrackingPause struct {
+	ProgramName string
+	PausedAt    time.Time
+	ResumeAt    sql.NullTime
 }
@@ -7,12 +7,13 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 )
 
-const addToSessionHistory = `-- name: AddToSessionHistory :exec
-INSERT INTO session_history (program_name, start_time, end_time, duration_seconds)
-VALUES (?, ?, ?, ?)
+const addToSessionHistory = `-- name: AddToSessionHistory :execlastid
+INSERT INTO session_history (program_name, start_time, end_time, duration_seconds, uid, container, unit, project, idle_seconds, recovered, manual, category, notes, start_unix, end_unix)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 `
 
 type AddToSessionHistoryParams struct {
@@ -20,29 +21,82 @@ type AddToSessionHistoryParams struct {
 	StartTime       time.Time
 	EndTime         time.Time
 	DurationSeconds int64
+	Uid             sql.NullInt64
+	Container       sql.NullString
+	Unit            sql.NullString
+	Project         sql.NullString
+	IdleSeconds     int64
+	Recovered       bool
+	Manual          bool
+	Category        sql.NullString
+	Notes           sql.NullString
+	StartUnix       int64
+	EndUnix         int64
 }
 
-func (q *Queries) AddToSessionHistory(ctx context.Context, arg AddToSessionHistoryParams) error {
-	_, err := q.db.ExecContext(ctx, addToSessionHistory,
+func (q *Queries) AddToSessionHistory(ctx context.Context, arg AddToSessionHistoryParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, addToSessionHistory,
 		arg.ProgramName,
 		arg.StartTime,
 		arg.EndTime,
 		arg.DurationSeconds,
+		arg.Uid,
+		arg.Container,
+		arg.Unit,
+		arg.Project,
+		arg.IdleSeconds,
+		arg.Recovered,
+		arg.Manual,
+		arg.Category,
+		arg.Notes,
+		arg.StartUnix,
+		arg.EndUnix,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+const editSessionRecord = `-- name: EditSessionRecord :exec
+UPDATE session_history
+SET start_time = ?, end_time = ?, duration_seconds = ?, idle_seconds = ?, project = ?, start_unix = ?, end_unix = ?
+WHERE id = ?
+`
+
+type EditSessionRecordParams struct {
+	StartTime       time.Time
+	EndTime         time.Time
+	DurationSeconds int64
+	IdleSeconds     int64
+	Project         sql.NullString
+	StartUnix       int64
+	EndUnix         int64
+	ID              int64
+}
+
+func (q *Queries) EditSessionRecord(ctx context.Context, arg EditSessionRecordParams) error {
+	_, err := q.db.ExecContext(ctx, editSessionRecord,
+		arg.StartTime,
+		arg.EndTime,
+		arg.DurationSeconds,
+		arg.IdleSeconds,
+		arg.Project,
+		arg.StartUnix,
+		arg.EndUnix,
+		arg.ID,
 	)
 	return err
 }
 
-const getAllSessionHistory = `-- name: GetAllSessionHistory :many
-SELECT id, program_name, start_time, end_time, duration_seconds FROM (
-    SELECT id, program_name, start_time, end_time, duration_seconds FROM session_history
-    ORDER BY end_time DESC
-    LIMIT ?
-) AS results
-ORDER BY end_time ASC
+const getAllSessionsForProgram = `-- name: GetAllSessionsForProgram :many
+SELECT id, program_name, start_time, end_time, duration_seconds, uid, container, unit, project, idle_seconds, recovered, manual, category, notes, start_unix, end_unix FROM session_history
+WHERE program_name = ?
+ORDER BY start_unix ASC
 `
 
-func (q *Queries) GetAllSessionHistory(ctx context.Context, limit int64) ([]SessionHistory, error) {
-	rows, err := q.db.QueryContext(ctx, getAllSessionHistory, limit)
+func (q *Queries) GetAllSessionsForProgram(ctx context.Context, programName string) ([]SessionHistory, error) {
+	rows, err := q.db.QueryContext(ctx, getAllSessionsForProgram, programName)
 	if err != nil {
 		return nil, err
 	}
@@ -56,6 +110,17 @@ func (q *Queries) GetAllSessionHistory(ctx context.Context, limit int64) ([]Sess
 			&i.StartTime,
 			&i.EndTime,
 			&i.DurationSeconds,
+			&i.Uid,
+			&i.Container,
+			&i.Unit,
+			&i.Project,
+			&i.IdleSeconds,
+			&i.Recovered,
+			&i.Manual,
+			&i.Category,
+			&i.Notes,
+			&i.StartUnix,
+			&i.EndUnix,
 		); err != nil {
 			return nil, err
 		}
@@ -70,24 +135,56 @@ func (q *Queries) GetAllSessionHistory(ctx context.Context, limit int64) ([]Sess
 	return items, nil
 }
 
-const getAllSessionHistoryByDate = `-- name: GetAllSessionHistoryByDate :many
-SELECT id, program_name, start_time, end_time, duration_seconds FROM (
-    SELECT id, program_name, start_time, end_time, duration_seconds FROM session_history
-    WHERE start_time <= ? AND end_time >= ?
-    ORDER BY start_time DESC
+const getCountOfSessionsForProgram = `-- name: GetCountOfSessionsForProgram :one
+SELECT COUNT(*) FROM session_history
+WHERE session_history.program_name = ?
+`
+
+func (q *Queries) GetCountOfSessionsForProgram(ctx context.Context, programName string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getCountOfSessionsForProgram, programName)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getFilteredSessionHistory = `-- name: GetFilteredSessionHistory :many
+SELECT results.id, results.program_name, results.start_time, results.end_time, results.duration_seconds, results.uid, results.container, results.unit, results.project, results.idle_seconds, results.recovered, results.manual, results.category, results.notes, results.start_unix, results.end_unix FROM (
+    SELECT session_history.id, session_history.program_name, session_history.start_time, session_history.end_time, session_history.duration_seconds, session_history.uid, session_history.container, session_history.unit, session_history.project, session_history.idle_seconds, session_history.recovered, session_history.manual, session_history.category, session_history.notes, session_history.start_unix, session_history.end_unix FROM session_history
+    WHERE (? = '' OR session_history.program_name = ?)
+      AND (? = 0 OR (start_unix < ? AND end_unix > ?))
+      AND (? IS NULL OR uid = ?)
+      AND duration_seconds >= ?
+      AND (? IS NULL OR session_history.id IN (SELECT session_id FROM session_tags WHERE tag = ?))
+    ORDER BY end_unix DESC, id DESC
     LIMIT ?
 ) AS results
-ORDER BY start_time ASC
+ORDER BY results.end_unix ASC, results.id ASC
 `
 
-type GetAllSessionHistoryByDateParams struct {
-	StartTime time.Time
-	EndTime   time.Time
-	Limit     int64
+type GetFilteredSessionHistoryParams struct {
+	ProgramName string
+	WindowEnd   int64
+	WindowStart int64
+	Uid         sql.NullInt64
+	MinDuration int64
+	Tag         sql.NullString
+	Limit       int64
 }
 
-func (q *Queries) GetAllSessionHistoryByDate(ctx context.Context, arg GetAllSessionHistoryByDateParams) ([]SessionHistory, error) {
-	rows, err := q.db.QueryContext(ctx, getAllSessionHistoryByDate, arg.StartTime, arg.EndTime, arg.Limit)
+func (q *Queries) GetFilteredSessionHistory(ctx context.Context, arg GetFilteredSessionHistoryParams) ([]SessionHistory, error) {
+	rows, err := q.db.QueryContext(ctx, getFilteredSessionHistory,
+		arg.ProgramName,
+		arg.ProgramName,
+		arg.WindowEnd,
+		arg.WindowEnd,
+		arg.WindowStart,
+		arg.Uid,
+		arg.Uid,
+		arg.MinDuration,
+		arg.Tag,
+		arg.Tag,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -101,6 +198,17 @@ func (q *Queries) GetAllSessionHistoryByDate(ctx context.Context, arg GetAllSess
 			&i.StartTime,
 			&i.EndTime,
 			&i.DurationSeconds,
+			&i.Uid,
+			&i.Container,
+			&i.Unit,
+			&i.Project,
+			&i.IdleSeconds,
+			&i.Recovered,
+			&i.Manual,
+			&i.Category,
+			&i.Notes,
+			&i.StartUnix,
+			&i.EndUnix,
 		); err != nil {
 			return nil, err
 		}
@@ -115,24 +223,97 @@ func (q *Queries) GetAllSessionHistoryByDate(ctx context.Context, arg GetAllSess
 	return items, nil
 }
 
-const getAllSessionHistoryByRange = `-- name: GetAllSessionHistoryByRange :many
-SELECT id, program_name, start_time, end_time, duration_seconds FROM (
-    SELECT id, program_name, start_time, end_time, duration_seconds FROM session_history
-    WHERE start_time <= ? AND end_time >= ?
-    ORDER BY start_time DESC
-    LIMIT ?
-) AS results
-ORDER BY start_time ASC
+const getFilteredSessionTotal = `-- name: GetFilteredSessionTotal :one
+SELECT CAST(COALESCE(SUM(MIN(end_unix, ?) - MAX(start_unix, ?)), 0) AS INTEGER) AS total_seconds
+FROM session_history
+WHERE (? = '' OR program_name = ?)
+  AND start_unix < ? AND end_unix > ?
+  AND (? IS NULL OR uid = ?)
+  AND duration_seconds >= ?
+  AND (? IS NULL OR id IN (SELECT session_id FROM session_tags WHERE tag = ?))
+`
+
+type GetFilteredSessionTotalParams struct {
+	WindowEnd   int64
+	WindowStart int64
+	ProgramName string
+	Uid         sql.NullInt64
+	MinDuration int64
+	Tag         sql.NullString
+}
+
+func (q *Queries) GetFilteredSessionTotal(ctx context.Context, arg GetFilteredSessionTotalParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getFilteredSessionTotal,
+		arg.WindowEnd,
+		arg.WindowStart,
+		arg.ProgramName,
+		arg.ProgramName,
+		arg.WindowEnd,
+		arg.WindowStart,
+		arg.Uid,
+		arg.Uid,
+		arg.MinDuration,
+		arg.Tag,
+		arg.Tag,
+	)
+	var total_seconds int64
+	err := row.Scan(&total_seconds)
+	return total_seconds, err
+}
+
+const getLastSessionForProgram = `-- name: GetLastSessionForProgram :one
+SELECT id, program_name, start_time, end_time, duration_seconds, uid, container, unit, project, idle_seconds, recovered, manual, category, notes, start_unix, end_unix FROM session_history
+WHERE session_history.program_name = ?
+ORDER BY end_unix DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLastSessionForProgram(ctx context.Context, programName string) (SessionHistory, error) {
+	row := q.db.QueryRowContext(ctx, getLastSessionForProgram, programName)
+	var i SessionHistory
+	err := row.Scan(
+		&i.ID,
+		&i.ProgramName,
+		&i.StartTime,
+		&i.EndTime,
+		&i.DurationSeconds,
+		&i.Uid,
+		&i.Container,
+		&i.Unit,
+		&i.Project,
+		&i.IdleSeconds,
+		&i.Recovered,
+		&i.Manual,
+		&i.Category,
+		&i.Notes,
+		&i.StartUnix,
+		&i.EndUnix,
+	)
+	return i, err
+}
+
+const getOverlappingSessions = `-- name: GetOverlappingSessions :many
+SELECT id, program_name, start_time, end_time, duration_seconds, uid, container, unit, project, idle_seconds, recovered, manual, category, notes, start_unix, end_unix FROM session_history
+WHERE program_name = ?
+  AND start_unix < ? AND end_unix > ?
+  AND id != ?
+ORDER BY start_unix ASC
 `
 
-type GetAllSessionHistoryByRangeParams struct {
-	StartTime time.Time
-	EndTime   time.Time
-	Limit     int64
+type GetOverlappingSessionsParams struct {
+	ProgramName string
+	EndUnix     int64
+	StartUnix   int64
+	ExcludeID   int64
 }
 
-func (q *Queries) GetAllSessionHistoryByRange(ctx context.Context, arg GetAllSessionHistoryByRangeParams) ([]SessionHistory, error) {
-	rows, err := q.db.QueryContext(ctx, getAllSessionHistoryByRange, arg.StartTime, arg.EndTime, arg.Limit)
+func (q *Queries) GetOverlappingSessions(ctx context.Context, arg GetOverlappingSessionsParams) ([]SessionHistory, error) {
+	rows, err := q.db.QueryContext(ctx, getOverlappingSessions,
+		arg.ProgramName,
+		arg.EndUnix,
+		arg.StartUnix,
+		arg.ExcludeID,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -146,6 +327,17 @@ func (q *Queries) GetAllSessionHistoryByRange(ctx context.Context, arg GetAllSes
 			&i.StartTime,
 			&i.EndTime,
 			&i.DurationSeconds,
+			&i.Uid,
+			&i.Container,
+			&i.Unit,
+			&i.Project,
+			&i.IdleSeconds,
+			&i.Recovered,
+			&i.Manual,
+			&i.Category,
+			&i.Notes,
+			&i.StartUnix,
+			&i.EndUnix,
 		); err != nil {
 			return nil, err
 		}
@@ -160,27 +352,13 @@ func (q *Queries) GetAllSessionHistoryByRange(ctx context.Context, arg GetAllSes
 	return items, nil
 }
 
-const getCountOfSessionsForProgram = `-- name: GetCountOfSessionsForProgram :one
-SELECT COUNT(*) FROM session_history
-WHERE session_history.program_name = ?
+const getSessionRecord = `-- name: GetSessionRecord :one
+SELECT id, program_name, start_time, end_time, duration_seconds, uid, container, unit, project, idle_seconds, recovered, manual, category, notes, start_unix, end_unix FROM session_history
+WHERE id = ?
 `
 
-func (q *Queries) GetCountOfSessionsForProgram(ctx context.Context, programName string) (int64, error) {
-	row := q.db.QueryRowContext(ctx, getCountOfSessionsForProgram, programName)
-	var count int64
-	err := row.Scan(&count)
-	return count, err
-}
-
-const getLastSessionForProgram = `-- name: GetLastSessionForProgram :one
-SELECT id, program_name, start_time, end_time, duration_seconds FROM session_history
-WHERE session_history.program_name = ?
-ORDER BY end_time DESC
-LIMIT 1
-`
-
-func (q *Queries) GetLastSessionForProgram(ctx context.Context, programName string) (SessionHistory, error) {
-	row := q.db.QueryRowContext(ctx, getLastSessionForProgram, programName)
+func (q *Queries) GetSessionRecord(ctx context.Context, id int64) (SessionHistory, error) {
+	row := q.db.QueryRowContext(ctx, getSessionRecord, id)
 	var i SessionHistory
 	err := row.Scan(
 		&i.ID,
@@ -188,40 +366,61 @@ func (q *Queries) GetLastSessionForProgram(ctx context.Context, programName stri
 		&i.StartTime,
 		&i.EndTime,
 		&i.DurationSeconds,
+		&i.Uid,
+		&i.Container,
+		&i.Unit,
+		&i.Project,
+		&i.IdleSeconds,
+		&i.Recovered,
+		&i.Manual,
+		&i.Category,
+		&i.Notes,
+		&i.StartUnix,
+		&i.EndUnix,
 	)
 	return i, err
 }
 
-const getSessionHistory = `-- name: GetSessionHistory :many
-SELECT id, program_name, start_time, end_time, duration_seconds FROM (
-    SELECT id, program_name, start_time, end_time, duration_seconds FROM session_history
-    WHERE program_name = ?
-    ORDER BY end_time DESC
-    LIMIT ?
-) AS results
-ORDER BY end_time ASC
+const getSessionSpans = `-- name: GetSessionSpans :many
+SELECT id, program_name,
+  CAST(MAX(start_unix, ?) AS INTEGER) AS start_unix,
+  CAST(MIN(end_unix, ?) AS INTEGER) AS end_unix
+FROM session_history
+WHERE start_unix < ? AND end_unix > ?
+ORDER BY start_unix ASC
 `
 
-type GetSessionHistoryParams struct {
+type GetSessionSpansParams struct {
+	WindowStart int64
+	WindowEnd   int64
+}
+
+type GetSessionSpansRow struct {
+	ID          int64
 	ProgramName string
-	Limit       int64
+	StartUnix   int64
+	EndUnix     int64
 }
 
-func (q *Queries) GetSessionHistory(ctx context.Context, arg GetSessionHistoryParams) ([]SessionHistory, error) {
-	rows, err := q.db.QueryContext(ctx, getSessionHistory, arg.ProgramName, arg.Limit)
+func (q *Queries) GetSessionSpans(ctx context.Context, arg GetSessionSpansParams) ([]GetSessionSpansRow, error) {
+	rows, err := q.db.QueryContext(ctx, getSessionSpans,
+		arg.WindowStart,
+		arg.WindowEnd,
+		arg.WindowEnd,
+		arg.WindowStart,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []SessionHistory
+	var items []GetSessionSpansRow
 	for rows.Next() {
-		var i SessionHistory
+		var i GetSessionSpansRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.ProgramName,
-			&i.StartTime,
-			&i.EndTime,
-			&i.DurationSeconds,
+			&i.StartUnix,
+			&i.EndUnix,
 		); err != nil {
 			return nil, err
 		}
@@ -236,45 +435,89 @@ func (q *Queries) GetSessionHistory(ctx context.Context, arg GetSessionHistoryPa
 	return items, nil
 }
 
-const getSessionHistoryByDate = `-- name: GetSessionHistoryByDate :many
-SELECT id, program_name, start_time, end_time, duration_seconds FROM (
-    SELECT id, program_name, start_time, end_time, duration_seconds FROM session_history
-    WHERE program_name = ? 
-      AND start_time <= ? AND end_time >= ?
-    ORDER BY start_time DESC
-    LIMIT ?
-) AS results
-ORDER BY start_time ASC
+const getSessionStatsForProgram = `-- name: GetSessionStatsForProgram :one
+SELECT COUNT(*) AS count,
+  CAST(COALESCE(SUM(CASE WHEN ? = 0 THEN duration_seconds
+    ELSE MIN(end_unix, ?) - MAX(start_unix, ?) END), 0) AS INTEGER) AS total_seconds,
+  CAST(COALESCE(SUM(CASE WHEN ? = 0 THEN idle_seconds
+    ELSE idle_seconds * (MIN(end_unix, ?) - MAX(start_unix, ?)) / MAX(end_unix - start_unix, 1) END), 0) AS INTEGER) AS idle_seconds FROM session_history
+WHERE session_history.program_name = ?
+  AND (? = 0 OR (start_unix < ? AND end_unix > ?))
+  AND duration_seconds >= ?
+  AND (? IS NULL OR session_history.id IN (SELECT session_id FROM session_tags WHERE tag = ?))
 `
 
-type GetSessionHistoryByDateParams struct {
+type GetSessionStatsForProgramParams struct {
+	WindowEnd   int64
+	WindowStart int64
 	ProgramName string
-	StartTime   time.Time
-	EndTime     time.Time
-	Limit       int64
+	MinDuration int64
+	Tag         sql.NullString
 }
 
-func (q *Queries) GetSessionHistoryByDate(ctx context.Context, arg GetSessionHistoryByDateParams) ([]SessionHistory, error) {
-	rows, err := q.db.QueryContext(ctx, getSessionHistoryByDate,
+type GetSessionStatsForProgramRow struct {
+	Count        int64
+	TotalSeconds int64
+	IdleSeconds  int64
+}
+
+func (q *Queries) GetSessionStatsForProgram(ctx context.Context, arg GetSessionStatsForProgramParams) (GetSessionStatsForProgramRow, error) {
+	row := q.db.QueryRowContext(ctx, getSessionStatsForProgram,
+		arg.WindowEnd,
+		arg.WindowEnd,
+		arg.WindowStart,
+		arg.WindowEnd,
+		arg.WindowEnd,
+		arg.WindowStart,
 		arg.ProgramName,
-		arg.StartTime,
-		arg.EndTime,
-		arg.Limit,
+		arg.WindowEnd,
+		arg.WindowEnd,
+		arg.WindowStart,
+		arg.MinDuration,
+		arg.Tag,
+		arg.Tag,
+	)
+	var i GetSessionStatsForProgramRow
+	err := row.Scan(&i.Count, &i.TotalSeconds, &i.IdleSeconds)
+	return i, err
+}
+
+const getUsageByCategory = `-- name: GetUsageByCategory :many
+SELECT CAST(COALESCE(session_history.category, tracked_programs.category, '') AS TEXT) AS name, COUNT(*) AS sessions,
+  CAST(SUM(MIN(end_unix, ?) - MAX(start_unix, ?)) AS INTEGER) AS total_seconds
+FROM session_history
+LEFT JOIN tracked_programs ON tracked_programs.name = session_history.program_name
+WHERE start_unix < ? AND end_unix > ?
+GROUP BY 1
+ORDER BY total_seconds DESC, name ASC
+`
+
+type GetUsageByCategoryParams struct {
+	WindowEnd   int64
+	WindowStart int64
+}
+
+type GetUsageByCategoryRow struct {
+	Name         string
+	Sessions     int64
+	TotalSeconds int64
+}
+
+func (q *Queries) GetUsageByCategory(ctx context.Context, arg GetUsageByCategoryParams) ([]GetUsageByCategoryRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUsageByCategory,
+		arg.WindowEnd,
+		arg.WindowStart,
+		arg.WindowEnd,
+		arg.WindowStart,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []SessionHistory
+	var items []GetUsageByCategoryRow
 	for rows.Next() {
-		var i SessionHistory
-		if err := rows.Scan(
-			&i.ID,
-			&i.ProgramName,
-			&i.StartTime,
-			&i.EndTime,
-			&i.DurationSeconds,
-		); err != nil {
+		var i GetUsageByCategoryRow
+		if err := rows.Scan(&i.Name, &i.Sessions, &i.TotalSeconds); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -288,45 +531,139 @@ func (q *Queries) GetSessionHistoryByDate(ctx context.Context, arg GetSessionHis
 	return items, nil
 }
 
-const getSessionHistoryByRange = `-- name: GetSessionHistoryByRange :many
-SELECT id, program_name, start_time, end_time, duration_seconds FROM (
-    SELECT id, program_name, start_time, end_time, duration_seconds FROM session_history
-    WHERE program_name = ?
-      AND start_time <= ? AND end_time >= ?
-    ORDER BY start_time DESC
-    LIMIT ?
-) AS results
-ORDER BY start_time ASC
+const getUsageByProgram = `-- name: GetUsageByProgram :many
+SELECT program_name AS name, COUNT(*) AS sessions,
+  CAST(SUM(MIN(end_unix, ?) - MAX(start_unix, ?)) AS INTEGER) AS total_seconds
+FROM session_history
+WHERE start_unix < ? AND end_unix > ?
+GROUP BY program_name
+ORDER BY total_seconds DESC, name ASC
 `
 
-type GetSessionHistoryByRangeParams struct {
-	ProgramName string
-	StartTime   time.Time
-	EndTime     time.Time
-	Limit       int64
+type GetUsageByProgramParams struct {
+	WindowEnd   int64
+	WindowStart int64
 }
 
-func (q *Queries) GetSessionHistoryByRange(ctx context.Context, arg GetSessionHistoryByRangeParams) ([]SessionHistory, error) {
-	rows, err := q.db.QueryContext(ctx, getSessionHistoryByRange,
-		arg.ProgramName,
-		arg.StartTime,
-		arg.EndTime,
-		arg.Limit,
+type GetUsageByProgramRow struct {
+	Name         string
+	Sessions     int64
+	TotalSeconds int64
+}
+
+func (q *Queries) GetUsageByProgram(ctx context.Context, arg GetUsageByProgramParams) ([]GetUsageByProgramRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUsageByProgram,
+		arg.WindowEnd,
+		arg.WindowStart,
+		arg.WindowEnd,
+		arg.WindowStart,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []SessionHistory
+	var items []GetUsageByProgramRow
 	for rows.Next() {
-		var i SessionHistory
-		if err := rows.Scan(
-			&i.ID,
-			&i.ProgramName,
-			&i.StartTime,
-			&i.EndTime,
-			&i.DurationSeconds,
-		); err != nil {
+		var i GetUsageByProgramRow
+		if err := rows.Scan(&i.Name, &i.Sessions, &i.TotalSeconds); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUsageByProject = `-- name: GetUsageByProject :many
+SELECT CAST(COALESCE(session_history.project, tracked_programs.project, '') AS TEXT) AS name, COUNT(*) AS sessions,
+  CAST(SUM(MIN(end_unix, ?) - MAX(start_unix, ?)) AS INTEGER) AS total_seconds
+FROM session_history
+LEFT JOIN tracked_programs ON tracked_programs.name = session_history.program_name
+WHERE start_unix < ? AND end_unix > ?
+GROUP BY 1
+ORDER BY total_seconds DESC, name ASC
+`
+
+type GetUsageByProjectParams struct {
+	WindowEnd   int64
+	WindowStart int64
+}
+
+type GetUsageByProjectRow struct {
+	Name         string
+	Sessions     int64
+	TotalSeconds int64
+}
+
+func (q *Queries) GetUsageByProject(ctx context.Context, arg GetUsageByProjectParams) ([]GetUsageByProjectRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUsageByProject,
+		arg.WindowEnd,
+		arg.WindowStart,
+		arg.WindowEnd,
+		arg.WindowStart,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUsageByProjectRow
+	for rows.Next() {
+		var i GetUsageByProjectRow
+		if err := rows.Scan(&i.Name, &i.Sessions, &i.TotalSeconds); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUsageByTag = `-- name: GetUsageByTag :many
+SELECT CAST(COALESCE(session_tags.tag, '') AS TEXT) AS name, COUNT(*) AS sessions,
+  CAST(SUM(MIN(end_unix, ?) - MAX(start_unix, ?)) AS INTEGER) AS total_seconds
+FROM session_history
+LEFT JOIN session_tags ON session_tags.session_id = session_history.id
+WHERE start_unix < ? AND end_unix > ?
+GROUP BY 1
+ORDER BY total_seconds DESC, name ASC
+`
+
+type GetUsageByTagParams struct {
+	WindowEnd   int64
+	WindowStart int64
+}
+
+type GetUsageByTagRow struct {
+	Name         string
+	Sessions     int64
+	TotalSeconds int64
+}
+
+func (q *Queries) GetUsageByTag(ctx context.Context, arg GetUsageByTagParams) ([]GetUsageByTagRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUsageByTag,
+		arg.WindowEnd,
+		arg.WindowStart,
+		arg.WindowEnd,
+		arg.WindowStart,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUsageByTagRow
+	for rows.Next() {
+		var i GetUsageByTagRow
+		if err := rows.Scan(&i.Name, &i.Sessions, &i.TotalSeconds); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -340,6 +677,35 @@ func (q *Queries) GetSessionHistoryByRange(ctx context.Context, arg GetSessionHi
 	return items, nil
 }
 
+const getUsageTotal = `-- name: GetUsageTotal :one
+SELECT COUNT(*) AS sessions,
+  CAST(COALESCE(SUM(MIN(end_unix, ?) - MAX(start_unix, ?)), 0) AS INTEGER) AS total_seconds
+FROM session_history
+WHERE start_unix < ? AND end_unix > ?
+`
+
+type GetUsageTotalParams struct {
+	WindowEnd   int64
+	WindowStart int64
+}
+
+type GetUsageTotalRow struct {
+	Sessions     int64
+	TotalSeconds int64
+}
+
+func (q *Queries) GetUsageTotal(ctx context.Context, arg GetUsageTotalParams) (GetUsageTotalRow, error) {
+	row := q.db.QueryRowContext(ctx, getUsageTotal,
+		arg.WindowEnd,
+		arg.WindowStart,
+		arg.WindowEnd,
+		arg.WindowStart,
+	)
+	var i GetUsageTotalRow
+	err := row.Scan(&i.Sessions, &i.TotalSeconds)
+	return i, err
+}
+
 const removeAllRecords = `-- name: RemoveAllRecords :exec
 DELETE FROM session_history
 `
@@ -358,3 +724,54 @@ func (q *Queries) RemoveRecordsForProgram(ctx context.Context, programName strin
 	_, err := q.db.ExecContext(ctx, removeRecordsForProgram, programName)
 	return err
 }
+
+const removeSessionRecord = `-- name: RemoveSessionRecord :exec
+DELETE FROM session_history
+WHERE id = ?
+`
+
+func (q *Queries) RemoveSessionRecord(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, removeSessionRecord, id)
+	return err
+}
+
+const updateSessionNotes = `-- name: UpdateSessionNotes :exec
+UPDATE session_history
+SET notes = ?
+WHERE id = ?
+`
+
+type UpdateSessionNotesParams struct {
+	Notes sql.NullString
+	ID    int64
+}
+
+func (q *Queries) UpdateSessionNotes(ctx context.Context, arg UpdateSessionNotesParams) error {
+	_, err := q.db.ExecContext(ctx, updateSessionNotes, arg.Notes, arg.ID)
+	return err
+}
+
+const updateSessionRecord = `-- name: UpdateSessionRecord :exec
+UPDATE session_history
+SET end_time = ?, duration_seconds = ?, idle_seconds = ?, end_unix = ?
+WHERE id = ?
+`
+
+type UpdateSessionRecordParams struct {
+	EndTime         time.Time
+	DurationSeconds int64
+	IdleSeconds     int64
+	EndUnix         int64
+	ID              int64
+}
+
+func (q *Queries) UpdateSessionRecord(ctx context.Context, arg UpdateSessionRecordParams) error {
+	_, err := q.db.ExecContext(ctx, updateSessionRecord,
+		arg.EndTime,
+		arg.DurationSeconds,
+		arg.IdleSeconds,
+		arg.EndUnix,
+		arg.ID,
+	)
+	return err
+}
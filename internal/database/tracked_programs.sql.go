@@ -11,18 +11,30 @@ import (
 )
 
 const addProgram = `-- name: AddProgram :exec
-INSERT OR IGNORE INTO tracked_programs (name, category, project)
-VALUES (?, ?, ?)
+INSERT OR IGNORE INTO tracked_programs (name, category, project, match_mode, exe, args_contains, scope)
+VALUES (?, ?, ?, ?, ?, ?, ?)
 `
 
 type AddProgramParams struct {
-	Name     string
-	Category sql.NullString
-	Project  sql.NullString
+	Name         string
+	Category     sql.NullString
+	Project      sql.NullString
+	MatchMode    string
+	Exe          sql.NullString
+	ArgsContains sql.NullString
+	Scope        string
 }
 
 func (q *Queries) AddProgram(ctx context.Context, arg AddProgramParams) error {
-	_, err := q.db.ExecContext(ctx, addProgram, arg.Name, arg.Category, arg.Project)
+	_, err := q.db.ExecContext(ctx, addProgram,
+		arg.Name,
+		arg.Category,
+		arg.Project,
+		arg.MatchMode,
+		arg.Exe,
+		arg.ArgsContains,
+		arg.Scope,
+	)
 	return err
 }
 
@@ -54,7 +66,7 @@ func (q *Queries) GetAllProgramNames(ctx context.Context) ([]string, error) {
 }
 
 const getAllPrograms = `-- name: GetAllPrograms :many
-SELECT id, name, lifetime_seconds, category, project FROM tracked_programs
+SELECT id, name, lifetime_seconds, category, project, match_mode, exe, args_contains, scope, idle_seconds, min_session FROM tracked_programs
 `
 
 func (q *Queries) GetAllPrograms(ctx context.Context) ([]TrackedProgram, error) {
@@ -72,6 +84,12 @@ func (q *Queries) GetAllPrograms(ctx context.Context) ([]TrackedProgram, error)
 			&i.LifetimeSeconds,
 			&i.Category,
 			&i.Project,
+			&i.MatchMode,
+			&i.Exe,
+			&i.ArgsContains,
+			&i.Scope,
+			&i.IdleSeconds,
+			&i.MinSession,
 		); err != nil {
 			return nil, err
 		}
@@ -87,7 +105,7 @@ func (q *Queries) GetAllPrograms(ctx context.Context) ([]TrackedProgram, error)
 }
 
 const getProgramByName = `-- name: GetProgramByName :one
-SELECT id, name, lifetime_seconds, category, project FROM tracked_programs
+SELECT id, name, lifetime_seconds, category, project, match_mode, exe, args_contains, scope, idle_seconds, min_session FROM tracked_programs
 WHERE name = ?
 `
 
@@ -100,10 +118,28 @@ func (q *Queries) GetProgramByName(ctx context.Context, name string) (TrackedPro
 		&i.LifetimeSeconds,
 		&i.Category,
 		&i.Project,
+		&i.MatchMode,
+		&i.Exe,
+		&i.ArgsContains,
+		&i.Scope,
+		&i.IdleSeconds,
+		&i.MinSession,
 	)
 	return i, err
 }
 
+const recomputeLifetime = `-- name: RecomputeLifetime :exec
+UPDATE tracked_programs
+SET lifetime_seconds = (SELECT COALESCE(SUM(duration_seconds), 0) FROM session_history WHERE program_name = tracked_programs.name),
+    idle_seconds = (SELECT COALESCE(SUM(idle_seconds), 0) FROM session_history WHERE program_name = tracked_programs.name)
+WHERE name = ?
+`
+
+func (q *Queries) RecomputeLifetime(ctx context.Context, name string) error {
+	_, err := q.db.ExecContext(ctx, recomputeLifetime, name)
+	return err
+}
+
 const removeAllPrograms = `-- name: RemoveAllPrograms :exec
 DELETE FROM tracked_programs
 `
@@ -125,7 +161,7 @@ func (q *Queries) RemoveProgram(ctx context.Context, name string) error {
 
 const resetAllLifetimes = `-- name: ResetAllLifetimes :exec
 UPDATE tracked_programs 
-SET lifetime_seconds = 0
+SET lifetime_seconds = 0, idle_seconds = 0
 `
 
 func (q *Queries) ResetAllLifetimes(ctx context.Context) error {
@@ -135,7 +171,7 @@ func (q *Queries) ResetAllLifetimes(ctx context.Context) error {
 
 const resetLifetimeForProgram = `-- name: ResetLifetimeForProgram :exec
 UPDATE tracked_programs 
-SET lifetime_seconds = 0 
+SET lifetime_seconds = 0, idle_seconds = 0
 WHERE name = ?
 `
 
@@ -162,17 +198,35 @@ func (q *Queries) UpdateCategory(ctx context.Context, arg UpdateCategoryParams)
 
 const updateLifetime = `-- name: UpdateLifetime :exec
 UPDATE tracked_programs
-SET lifetime_seconds = lifetime_seconds + ?
+SET lifetime_seconds = lifetime_seconds + ?,
+    idle_seconds = idle_seconds + ?
 WHERE name = ?
 `
 
 type UpdateLifetimeParams struct {
 	LifetimeSeconds int64
+	IdleSeconds     int64
 	Name            string
 }
 
 func (q *Queries) UpdateLifetime(ctx context.Context, arg UpdateLifetimeParams) error {
-	_, err := q.db.ExecContext(ctx, updateLifetime, arg.LifetimeSeconds, arg.Name)
+	_, err := q.db.ExecContext(ctx, updateLifetime, arg.LifetimeSeconds, arg.IdleSeconds, arg.Name)
+	return err
+}
+
+const updateMinSession = `-- name: UpdateMinSession :exec
+UPDATE tracked_programs
+SET min_session = ?
+WHERE name = ?
+`
+
+type UpdateMinSessionParams struct {
+	MinSession sql.NullInt64
+	Name       string
+}
+
+func (q *Queries) UpdateMinSession(ctx context.Context, arg UpdateMinSessionParams) error {
+	_, err := q.db.ExecContext(ctx, updateMinSession, arg.MinSession, arg.Name)
 	return err
 }
 
@@ -191,3 +245,19 @@ func (q *Queries) UpdateProject(ctx context.Context, arg UpdateProjectParams) er
 	_, err := q.db.ExecContext(ctx, updateProject, arg.Project, arg.Name)
 	return err
 }
+
+const updateScope = `-- name: UpdateScope :exec
+UPDATE tracked_programs
+SET scope = ?
+WHERE name = ?
+`
+
+type UpdateScopeParams struct {
+	Scope string
+	Name  string
+}
+
+func (q *Queries) UpdateScope(ctx context.Context, arg UpdateScopeParams) error {
+	_, err := q.db.ExecContext(ctx, updateScope, arg.Scope, arg.Name)
+	return err
+}
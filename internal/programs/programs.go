@@ -0,0 +1,45 @@
+package programs
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tracked program names are stored lowercase, whatever their match mode, so the service's session map, IPC commands, pause
+// records and CLI lookups all agree on them. Regex patterns are matched case-insensitively, so lowercasing their literal
+// characters doesn't change what they match
+
+// Normalizes a program name or pattern given by the user. Letters are lowercased except where they're part of regex
+// syntax, escapes (ex. \D, \S or \p{Greek}) and flag groups (ex. (?U)), which change meaning with case. Names without a
+// backslash or flag group are simply lowercased
+func Normalize(name string) string {
+	var b strings.Builder
+	b.Grow(len(name))
+
+	runes := []rune(name)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes): // Escape, kept with its class name if it has one
+			b.WriteRune(r)
+			i++
+			b.WriteRune(runes[i])
+			if (runes[i] == 'p' || runes[i] == 'P') && i+1 < len(runes) && runes[i+1] == '{' {
+				for i+1 < len(runes) && runes[i] != '}' {
+					i++
+					b.WriteRune(runes[i])
+				}
+			}
+		case r == '(' && i+1 < len(runes) && runes[i+1] == '?': // Flag or named group, kept up to its flags' end
+			for i < len(runes) && runes[i] != ')' && runes[i] != ':' && runes[i] != '<' && runes[i] != '\'' {
+				b.WriteRune(runes[i])
+				i++
+			}
+			i--
+		default:
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,41 @@
+package programs
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "Code.exe", want: "code.exe"},
+		{name: "Fire*.EXE", want: "fire*.exe"},
+		{name: `^Python3?\.\d+$`, want: `^python3?\.\d+$`},
+		{name: `App\D\S\W\B`, want: `app\D\S\W\B`},
+		{name: `\P{Greek}X\p{Lu}`, want: `\P{Greek}x\p{Lu}`},
+		{name: `(?U)Tool.+`, want: `(?U)tool.+`},
+		{name: `(?P<Name>Code)`, want: `(?P<name>code)`},
+		{name: `(?s:A.B)`, want: `(?s:a.b)`},
+		{name: `Trailing\`, want: `trailing\`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Normalize(tt.name))
+		})
+	}
+}
+
+func TestNormalize_KeepsRegexMatches(t *testing.T) {
+	for _, pattern := range []string{`^Code\D+$`, `(?U)Sh.+`, `\P{Lu}+X`} {
+		before := regexp.MustCompile("(?i)" + pattern)
+		after := regexp.MustCompile("(?i)" + Normalize(pattern))
+		for _, name := range []string{"code-x", "code1", "shell", "abcx", "ABCX"} {
+			assert.Equal(t, before.MatchString(name), after.MatchString(name), "%s should match %s the same after normalizing", pattern, name)
+		}
+	}
+}
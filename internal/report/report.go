@@ -0,0 +1,113 @@
+package report
+
+import (
+	"time"
+)
+
+// Reporting helpers working on session history in the user's local time zone. Sessions may run across the edges of a
+// requested period (ex. past midnight), so totals for a period count only the part of each session inside it
+
+// Layout of dates given on the command line
+const DateLayout = "2006-01-02"
+
+// Half-open period of time [Start, End)
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Parses a date in the local time zone, returning its midnight
+func ParseDay(date string) (time.Time, error) {
+	return time.ParseInLocation(DateLayout, date, time.Local)
+}
+
+// Returns the local calendar day containing t. Days are built from calendar dates rather than 24h steps, so they stay
+// aligned to midnight across daylight saving changes
+func Day(t time.Time) Window {
+	t = t.In(time.Local)
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+
+	return Window{Start: start, End: start.AddDate(0, 0, 1)}
+}
+
+// Returns the local calendar week containing t, starting on Monday
+func Week(t time.Time) Window {
+	start := Day(t).Start
+	start = start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+
+	return Window{Start: start, End: start.AddDate(0, 0, 7)}
+}
+
+// Returns the local calendar month containing t
+func Month(t time.Time) Window {
+	t = t.In(time.Local)
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local)
+
+	return Window{Start: start, End: start.AddDate(0, 1, 0)}
+}
+
+// Returns the local calendar year containing t
+func Year(t time.Time) Window {
+	t = t.In(time.Local)
+	start := time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.Local)
+
+	return Window{Start: start, End: start.AddDate(1, 0, 0)}
+}
+
+// Returns the window from the start of the first day to the end of the last day, inclusive of both
+func Days(first, last time.Time) Window {
+	return Window{Start: Day(first).Start, End: Day(last).End}
+}
+
+// Reports whether a session running from start to end overlaps the window
+func (w Window) Overlaps(start, end time.Time) bool {
+	return start.Before(w.End) && end.After(w.Start)
+}
+
+// Returns the part of a session running from start to end that falls inside the window
+func (w Window) Clip(start, end time.Time) time.Duration {
+	if start.Before(w.Start) {
+		start = w.Start
+	}
+	if end.After(w.End) {
+		end = w.End
+	}
+	if !end.After(start) {
+		return 0
+	}
+
+	return end.Sub(start)
+}
+
+// Splits the period from start to end at each local midnight, returning one window per calendar day it touches
+func SplitDays(start, end time.Time) []Window {
+	var days []Window
+	for start.Before(end) {
+		day := Day(start)
+		if day.End.After(end) {
+			day.End = end
+		}
+		days = append(days, Window{Start: start, End: day.End})
+		start = day.End
+	}
+
+	return days
+}
+
+// Splits the period from start to end at each local hour, returning one window per hour of the clock it touches. Steps
+// are taken from the time into the local hour, as a wall clock hour repeats when daylight saving ends
+func SplitHours(start, end time.Time) []Window {
+	var hours []Window
+	for start.Before(end) {
+		local := start.In(time.Local)
+		into := time.Duration(local.Minute())*time.Minute + time.Duration(local.Second())*time.Second + time.Duration(local.Nanosecond())
+		next := start.Add(time.Hour - into)
+		if next.After(end) {
+			next = end
+		}
+		hours = append(hours, Window{Start: start, End: next})
+		start = next
+	}
+
+	return hours
+}
@@ -0,0 +1,66 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClip_ClipsSessionsToDay(t *testing.T) {
+	day, err := ParseDay("2025-09-30")
+	require.NoError(t, err)
+
+	evening := [2]time.Time{day.Add(-5 * time.Hour), day.Add(14 * time.Hour)} // 19h session from the evening before
+	night := [2]time.Time{day.Add(20 * time.Hour), day.Add(26 * time.Hour)}   // Runs into the next day
+
+	assert.Equal(t, 14*time.Hour, Day(day).Clip(evening[0], evening[1]), "Only time inside the day should count")
+	assert.Equal(t, 4*time.Hour, Day(day).Clip(night[0], night[1]), "Only time inside the day should count")
+	assert.Equal(t, 19*time.Hour, Days(day.AddDate(0, 0, -1), day).Clip(evening[0], evening[1]), "Window should include both days in full")
+	assert.Zero(t, Day(day.AddDate(0, 0, 2)).Clip(night[0], night[1]), "Session outside the window shouldn't count")
+}
+
+func TestSplitDays(t *testing.T) {
+	day, err := ParseDay("2025-09-30")
+	require.NoError(t, err)
+
+	days := SplitDays(day.Add(-5*time.Hour), day.Add(26*time.Hour))
+	require.Len(t, days, 3)
+	assert.Equal(t, day.Add(-5*time.Hour), days[0].Start)
+	assert.Equal(t, day, days[0].End, "Split should fall on local midnight")
+	assert.Equal(t, Day(day), days[1])
+	assert.Equal(t, 2*time.Hour, days[2].End.Sub(days[2].Start))
+
+	assert.Len(t, SplitDays(day.Add(time.Hour), day.Add(2*time.Hour)), 1, "Session within a day shouldn't be split")
+	assert.Empty(t, SplitDays(day, day))
+}
+
+func TestWeekAndMonth(t *testing.T) {
+	day, err := ParseDay("2025-10-01") // Wednesday
+	require.NoError(t, err)
+
+	week := Week(day.Add(15 * time.Hour))
+	assert.Equal(t, "2025-09-29", week.Start.Format(DateLayout), "Week should start on Monday")
+	assert.Equal(t, "2025-10-06", week.End.Format(DateLayout))
+	assert.Equal(t, week, Week(week.Start), "Monday should belong to its own week")
+	assert.Equal(t, week, Week(week.End.Add(-time.Second)), "Sunday should close the week")
+
+	month := Month(day)
+	assert.Equal(t, "2025-10-01", month.Start.Format(DateLayout))
+	assert.Equal(t, "2025-11-01", month.End.Format(DateLayout))
+}
+
+func TestSplitHours(t *testing.T) {
+	day, err := ParseDay("2025-09-30")
+	require.NoError(t, err)
+
+	hours := SplitHours(day.Add(9*time.Hour+30*time.Minute), day.Add(11*time.Hour+15*time.Minute))
+	require.Len(t, hours, 3)
+	assert.Equal(t, 30*time.Minute, hours[0].End.Sub(hours[0].Start))
+	assert.Equal(t, 10, hours[1].Start.Hour(), "Split should fall on the hour")
+	assert.Equal(t, time.Hour, hours[1].End.Sub(hours[1].Start))
+	assert.Equal(t, 15*time.Minute, hours[2].End.Sub(hours[2].Start))
+
+	assert.Empty(t, SplitHours(day, day))
+}
@@ -0,0 +1,159 @@
+package report
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Times and periods given to filter flags (--date, --start, --end) are parsed here, so every command reading session
+// history accepts the same forms
+
+// Layouts of a date and time, with or without seconds and a UTC offset, ex. 2025-09-30T13:00 or 2025-09-30 13:00:05+02:00.
+// Fractional seconds are accepted after the seconds by time.Parse
+var timestampLayouts = func() []string {
+	var layouts []string
+	for _, clock := range []string{"15:04:05", "15:04"} {
+		for _, sep := range []string{"T", " "} {
+			for _, zone := range []string{"", "Z07:00", "Z0700"} {
+				layouts = append(layouts, DateLayout+sep+clock+zone)
+			}
+		}
+	}
+	return layouts
+}()
+
+// Time back from now, ex. -7d or 3 days ago
+var relativePattern = regexp.MustCompile(`^(?:-(\d+) ?([a-z]+)|(\d+) ?([a-z]+) ago)$`)
+
+// Units of relative times, by the letter they're normalized to
+var relativeUnits = map[string]string{
+	"m": "m", "min": "m", "mins": "m", "minute": "m", "minutes": "m",
+	"h": "h", "hour": "h", "hours": "h",
+	"d": "d", "day": "d", "days": "d",
+	"w": "w", "week": "w", "weeks": "w",
+}
+
+// Parses a time or period given on the command line, returning the window of time it covers. Accepted forms are:
+//   - a day: today, yesterday, a weekday name (the latest one up to today) or a date, ex. 2025-09-30
+//   - a calendar period: this or last week, month or year
+//   - a time back from now: -30m, -12h, -7d, -2w or ex. "3 days ago", covering the time since then up to now
+//   - an instant: now, or a date and time, ex. 2025-09-30T13:00 or 2025-09-30 13:00:05+02:00
+//
+// Dates and times are local unless they carry a UTC offset or end in a time zone name, ex. "2025-09-30 America/New_York".
+// An instant is returned as a window starting and ending at it
+func ParseRange(value string, now time.Time) (Window, error) {
+	window, _, err := parseRange(value, now)
+	return window, err
+}
+
+// Parses the start of a period given on the command line, the start of the window ParseRange returns
+func ParseStart(value string, now time.Time) (time.Time, error) {
+	window, _, err := parseRange(value, now)
+	return window.Start, err
+}
+
+// Parses the end of a period given on the command line, the end of the window ParseRange returns, except a time back
+// from now ends the period at that time, ex. -2d ends it two days ago
+func ParseEnd(value string, now time.Time) (time.Time, error) {
+	window, relative, err := parseRange(value, now)
+	if relative {
+		return window.Start, err
+	}
+	return window.End, err
+}
+
+// Parses a time or period as ParseRange does, reporting whether it's a time back from now
+func parseRange(value string, now time.Time) (Window, bool, error) {
+	text := strings.ToLower(strings.Join(strings.Fields(value), " "))
+	now = now.In(time.Local)
+	today := Day(now)
+
+	switch text {
+	case "now":
+		return Window{Start: now, End: now}, false, nil
+	case "today":
+		return today, false, nil
+	case "yesterday":
+		return Day(today.Start.AddDate(0, 0, -1)), false, nil
+	case "this week":
+		return Week(now), false, nil
+	case "last week":
+		return Week(Week(now).Start.AddDate(0, 0, -7)), false, nil
+	case "this month":
+		return Month(now), false, nil
+	case "last month":
+		return Month(Month(now).Start.AddDate(0, -1, 0)), false, nil
+	case "this year":
+		return Year(now), false, nil
+	case "last year":
+		return Year(Year(now).Start.AddDate(-1, 0, 0)), false, nil
+	}
+
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if text == strings.ToLower(day.String()) {
+			back := (int(now.Weekday()) - int(day) + 7) % 7
+			return Day(today.Start.AddDate(0, 0, -back)), false, nil
+		}
+	}
+
+	if match := relativePattern.FindStringSubmatch(text); match != nil {
+		count, unit := match[1]+match[3], match[2]+match[4]
+		window, err := relativeRange(value, count, unit, now)
+		return window, true, err
+	}
+
+	window, err := parseTimestamp(strings.TrimSpace(value))
+	return window, false, err
+}
+
+// Returns the window from count units before now up to now. Days and weeks step back by calendar days, so they keep the
+// time of day across daylight saving changes
+func relativeRange(value, count, unit string, now time.Time) (Window, error) {
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid relative time %q: %w", value, err)
+	}
+
+	var start time.Time
+	switch relativeUnits[unit] {
+	case "m":
+		start = now.Add(-time.Duration(n) * time.Minute)
+	case "h":
+		start = now.Add(-time.Duration(n) * time.Hour)
+	case "d":
+		start = now.AddDate(0, 0, -n)
+	case "w":
+		start = now.AddDate(0, 0, -7*n)
+	default:
+		return Window{}, fmt.Errorf("invalid relative time %q, expected minutes (m), hours (h), days (d) or weeks (w)", value)
+	}
+
+	return Window{Start: start, End: now}, nil
+}
+
+// Parses a date, as the day it names, or a date and time, as an instant. Either may end in a time zone name
+func parseTimestamp(value string) (Window, error) {
+	loc := time.Local
+	if i := strings.LastIndexByte(value, ' '); i > 0 && unicode.IsDigit(rune(value[0])) && unicode.IsLetter(rune(value[i+1])) {
+		zone, err := time.LoadLocation(value[i+1:])
+		if err != nil {
+			return Window{}, fmt.Errorf("invalid time zone in %q: %w", value, err)
+		}
+		loc, value = zone, strings.TrimSpace(value[:i])
+	}
+
+	if day, err := time.ParseInLocation(DateLayout, value, loc); err == nil {
+		return Window{Start: day, End: day.AddDate(0, 0, 1)}, nil
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return Window{Start: t, End: t}, nil
+		}
+	}
+
+	return Window{}, fmt.Errorf("invalid time %q, expected ex. today, yesterday, monday, last week, this month, -7d, 2025-09-30 or 2025-09-30T13:00", value)
+}
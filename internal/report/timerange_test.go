@@ -0,0 +1,97 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRange(t *testing.T) {
+	now := time.Date(2025, time.October, 1, 15, 30, 0, 0, time.Local) // Wednesday
+	day := func(date string) time.Time {
+		d, err := ParseDay(date)
+		require.NoError(t, err)
+		return d
+	}
+
+	tests := []struct {
+		value string
+		want  Window
+	}{
+		{value: "today", want: Day(now)},
+		{value: "Yesterday", want: Day(day("2025-09-30"))},
+		{value: "monday", want: Day(day("2025-09-29"))},
+		{value: "wednesday", want: Day(now)},
+		{value: "thursday", want: Day(day("2025-09-25"))},
+		{value: "this week", want: Week(now)},
+		{value: "last  week", want: Days(day("2025-09-22"), day("2025-09-28"))},
+		{value: "last month", want: Days(day("2025-09-01"), day("2025-09-30"))},
+		{value: "this year", want: Days(day("2025-01-01"), day("2025-12-31"))},
+		{value: "last year", want: Days(day("2024-01-01"), day("2024-12-31"))},
+		{value: "now", want: Window{Start: now, End: now}},
+		{value: "-30m", want: Window{Start: now.Add(-30 * time.Minute), End: now}},
+		{value: "-12h", want: Window{Start: now.Add(-12 * time.Hour), End: now}},
+		{value: "-7d", want: Window{Start: now.AddDate(0, 0, -7), End: now}},
+		{value: "3 days ago", want: Window{Start: now.AddDate(0, 0, -3), End: now}},
+		{value: "2 weeks ago", want: Window{Start: now.AddDate(0, 0, -14), End: now}},
+		{value: "2025-09-30", want: Day(day("2025-09-30"))},
+		{value: "2025-09-30 13:00", want: Window{Start: day("2025-09-30").Add(13 * time.Hour), End: day("2025-09-30").Add(13 * time.Hour)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseRange(tt.value, now)
+			require.NoError(t, err)
+			assert.True(t, tt.want.Start.Equal(got.Start), "start: want %s, got %s", tt.want.Start, got.Start)
+			assert.True(t, tt.want.End.Equal(got.End), "end: want %s, got %s", tt.want.End, got.End)
+		})
+	}
+}
+
+func TestParseStartAndEnd(t *testing.T) {
+	now := time.Date(2025, time.October, 1, 15, 30, 0, 0, time.Local)
+
+	start, err := ParseStart("yesterday", now)
+	require.NoError(t, err)
+	assert.True(t, Day(now).Start.AddDate(0, 0, -1).Equal(start), "Start should be the start of the day")
+	end, err := ParseEnd("yesterday", now)
+	require.NoError(t, err)
+	assert.True(t, Day(now).Start.Equal(end), "End should be the end of the day")
+
+	end, err = ParseEnd("-2d", now)
+	require.NoError(t, err)
+	assert.True(t, now.AddDate(0, 0, -2).Equal(end), "Relative end should be the time back from now")
+	start, err = ParseStart("-2d", now)
+	require.NoError(t, err)
+	assert.True(t, now.AddDate(0, 0, -2).Equal(start))
+}
+
+func TestParseRange_TimeZones(t *testing.T) {
+	now := time.Now()
+	instant := time.Date(2025, time.September, 30, 11, 0, 0, 0, time.UTC)
+
+	for _, value := range []string{"2025-09-30T11:00Z", "2025-09-30T13:00:00+02:00", "2025-09-30 13:00+0200", "2025-09-30T11:00:00.000Z", "2025-09-30 11:00 UTC"} {
+		got, err := ParseRange(value, now)
+		require.NoError(t, err, value)
+		assert.True(t, instant.Equal(got.Start), "%s should parse to %s, got %s", value, instant, got.Start)
+		assert.Equal(t, got.Start, got.End, "An instant should start and end at the same time")
+	}
+
+	zone, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("Time zone database not available")
+	}
+	got, err := ParseRange("2025-09-30 America/New_York", now)
+	require.NoError(t, err)
+	assert.True(t, time.Date(2025, time.September, 30, 0, 0, 0, 0, zone).Equal(got.Start), "Date should start at midnight in its time zone")
+	assert.Equal(t, 24*time.Hour, got.End.Sub(got.Start))
+}
+
+func TestParseRange_Invalid(t *testing.T) {
+	for _, value := range []string{"", "someday", "next week", "-7y", "2025-13-01", "2025-09-30 Nowhere/City", "30/09/2025"} {
+		_, err := ParseRange(value, time.Now())
+		assert.Error(t, err, "%q should not parse", value)
+	}
+}
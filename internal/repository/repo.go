@@ -2,7 +2,8 @@ package repository
 
 import (
 	"context"
-	"time"
+	"database/sql"
+	"fmt"
 
 	"github.com/jms-guy/timekeep/internal/database"
 )
@@ -19,38 +20,102 @@ type ProgramRepository interface {
 	ResetAllLifetimes(ctx context.Context) error
 	ResetLifetimeForProgram(ctx context.Context, name string) error
 	UpdateLifetime(ctx context.Context, arg database.UpdateLifetimeParams) error
+	RecomputeLifetime(ctx context.Context, name string) error
 	UpdateCategory(ctx context.Context, arg database.UpdateCategoryParams) error
 	UpdateProject(ctx context.Context, arg database.UpdateProjectParams) error
+	UpdateScope(ctx context.Context, arg database.UpdateScopeParams) error
+	UpdateMinSession(ctx context.Context, arg database.UpdateMinSessionParams) error
+	AddExclusion(ctx context.Context, arg database.AddExclusionParams) error
+	GetAllExclusions(ctx context.Context) ([]database.ProgramExclusion, error)
+	GetExclusionsForProgram(ctx context.Context, programName string) ([]database.ProgramExclusion, error)
+	RemoveAllExclusions(ctx context.Context) error
+	RemoveExclusionsForProgram(ctx context.Context, programName string) error
+	AddPause(ctx context.Context, arg database.AddPauseParams) error
+	GetAllPauses(ctx context.Context) ([]database.TrackingPause, error)
+	RemovePause(ctx context.Context, programName string) error
+	RemoveAllPauses(ctx context.Context) error
 }
 
 type ActiveRepository interface {
 	CreateActiveSession(ctx context.Context, arg database.CreateActiveSessionParams) error
-	GetActiveSession(ctx context.Context, programName string) (time.Time, error)
+	GetActiveSession(ctx context.Context, programName string) (database.GetActiveSessionRow, error)
 	GetAllActiveSessions(ctx context.Context) ([]database.ActiveSession, error)
+	UpdateActiveIdle(ctx context.Context, arg database.UpdateActiveIdleParams) error
+	UpdateActiveLastSeen(ctx context.Context, lastSeen sql.NullTime) error
+	UpdateActiveNotes(ctx context.Context, arg database.UpdateActiveNotesParams) error
+	UpdateActiveTags(ctx context.Context, arg database.UpdateActiveTagsParams) error
 	RemoveActiveSession(ctx context.Context, programName string) error
 	RemoveAllSessions(ctx context.Context) error
 }
 
 type HistoryRepository interface {
-	AddToSessionHistory(ctx context.Context, arg database.AddToSessionHistoryParams) error
+	AddToSessionHistory(ctx context.Context, arg database.AddToSessionHistoryParams) (int64, error)
 	GetCountOfSessionsForProgram(ctx context.Context, programName string) (int64, error)
+	GetSessionStatsForProgram(ctx context.Context, arg database.GetSessionStatsForProgramParams) (database.GetSessionStatsForProgramRow, error)
 	GetLastSessionForProgram(ctx context.Context, programName string) (database.SessionHistory, error)
 	RemoveAllRecords(ctx context.Context) error
 	RemoveRecordsForProgram(ctx context.Context, programName string) error
-	GetSessionHistory(ctx context.Context, arg database.GetSessionHistoryParams) ([]database.SessionHistory, error)
-	GetAllSessionHistory(ctx context.Context, limit int64) ([]database.SessionHistory, error)
-	GetSessionHistoryByDate(ctx context.Context, arg database.GetSessionHistoryByDateParams) ([]database.SessionHistory, error)
-	GetAllSessionHistoryByDate(ctx context.Context, arg database.GetAllSessionHistoryByDateParams) ([]database.SessionHistory, error)
-	GetSessionHistoryByRange(ctx context.Context, arg database.GetSessionHistoryByRangeParams) ([]database.SessionHistory, error)
-	GetAllSessionHistoryByRange(ctx context.Context, arg database.GetAllSessionHistoryByRangeParams) ([]database.SessionHistory, error)
+	GetFilteredSessionHistory(ctx context.Context, arg database.GetFilteredSessionHistoryParams) ([]database.SessionHistory, error)
+	GetFilteredSessionTotal(ctx context.Context, arg database.GetFilteredSessionTotalParams) (int64, error)
+	GetAllSessionsForProgram(ctx context.Context, programName string) ([]database.SessionHistory, error)
+	RemoveSessionRecord(ctx context.Context, id int64) error
+	UpdateSessionRecord(ctx context.Context, arg database.UpdateSessionRecordParams) error
+	GetSessionRecord(ctx context.Context, id int64) (database.SessionHistory, error)
+	GetOverlappingSessions(ctx context.Context, arg database.GetOverlappingSessionsParams) ([]database.SessionHistory, error)
+	GetUsageTotal(ctx context.Context, arg database.GetUsageTotalParams) (database.GetUsageTotalRow, error)
+	GetUsageByProgram(ctx context.Context, arg database.GetUsageByProgramParams) ([]database.GetUsageByProgramRow, error)
+	GetUsageByCategory(ctx context.Context, arg database.GetUsageByCategoryParams) ([]database.GetUsageByCategoryRow, error)
+	GetUsageByProject(ctx context.Context, arg database.GetUsageByProjectParams) ([]database.GetUsageByProjectRow, error)
+	GetUsageByTag(ctx context.Context, arg database.GetUsageByTagParams) ([]database.GetUsageByTagRow, error)
+	GetSessionSpans(ctx context.Context, arg database.GetSessionSpansParams) ([]database.GetSessionSpansRow, error)
+	EditSessionRecord(ctx context.Context, arg database.EditSessionRecordParams) error
+	UpdateSessionNotes(ctx context.Context, arg database.UpdateSessionNotesParams) error
+	AddSessionTag(ctx context.Context, arg database.AddSessionTagParams) error
+	GetTagsForSession(ctx context.Context, sessionID int64) ([]string, error)
+	RemoveSessionTag(ctx context.Context, arg database.RemoveSessionTagParams) error
+	RemoveTagsForSession(ctx context.Context, sessionID int64) error
+	RemoveOrphanedTags(ctx context.Context) error
+}
+
+// Combined repository over every table, able to run a unit of work atomically
+type Store interface {
+	ProgramRepository
+	ActiveRepository
+	HistoryRepository
+	WithTx(ctx context.Context, fn func(tx Store) error) error // Runs fn in a transaction, committed only if fn returns nil
 }
 
 type sqliteStore struct {
-	db *database.Queries
+	db   *database.Queries
+	conn *sql.DB // Connection transactions are started on, nil for a store already inside a transaction
 }
 
-func NewSqliteStore(queries *database.Queries) *sqliteStore {
-	return &sqliteStore{db: queries}
+func NewSqliteStore(conn *sql.DB) *sqliteStore {
+	return &sqliteStore{db: database.New(conn), conn: conn}
+}
+
+// Runs fn against a store bound to a new transaction, rolling back if fn or the commit fails. Calls made on a store
+// already inside a transaction join it
+func (s *sqliteStore) WithTx(ctx context.Context, fn func(tx Store) error) error {
+	if s.conn == nil {
+		return fn(s)
+	}
+
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op once committed
+
+	if err := fn(&sqliteStore{db: s.db.WithTx(tx)}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
 }
 
 // //////////////// Program Repository //////////////////
@@ -93,6 +158,10 @@ func (s *sqliteStore) UpdateLifetime(ctx context.Context, arg database.UpdateLif
 	return s.db.UpdateLifetime(ctx, arg)
 }
 
+func (s *sqliteStore) RecomputeLifetime(ctx context.Context, name string) error {
+	return s.db.RecomputeLifetime(ctx, name)
+}
+
 func (s *sqliteStore) UpdateCategory(ctx context.Context, arg database.UpdateCategoryParams) error {
 	return s.db.UpdateCategory(ctx, arg)
 }
@@ -101,13 +170,60 @@ func (s *sqliteStore) UpdateProject(ctx context.Context, arg database.UpdateProj
 	return s.db.UpdateProject(ctx, arg)
 }
 
+func (s *sqliteStore) UpdateScope(ctx context.Context, arg database.UpdateScopeParams) error {
+	return s.db.UpdateScope(ctx, arg)
+}
+
+func (s *sqliteStore) UpdateMinSession(ctx context.Context, arg database.UpdateMinSessionParams) error {
+	return s.db.UpdateMinSession(ctx, arg)
+}
+
+func (s *sqliteStore) AddExclusion(ctx context.Context, arg database.AddExclusionParams) error {
+	return s.db.AddExclusion(ctx, arg)
+}
+
+func (s *sqliteStore) GetAllExclusions(ctx context.Context) ([]database.ProgramExclusion, error) {
+	results, err := s.db.GetAllExclusions(ctx)
+	return results, err
+}
+
+func (s *sqliteStore) GetExclusionsForProgram(ctx context.Context, programName string) ([]database.ProgramExclusion, error) {
+	results, err := s.db.GetExclusionsForProgram(ctx, programName)
+	return results, err
+}
+
+func (s *sqliteStore) RemoveAllExclusions(ctx context.Context) error {
+	return s.db.RemoveAllExclusions(ctx)
+}
+
+func (s *sqliteStore) RemoveExclusionsForProgram(ctx context.Context, programName string) error {
+	return s.db.RemoveExclusionsForProgram(ctx, programName)
+}
+
+func (s *sqliteStore) AddPause(ctx context.Context, arg database.AddPauseParams) error {
+	return s.db.AddPause(ctx, arg)
+}
+
+func (s *sqliteStore) GetAllPauses(ctx context.Context) ([]database.TrackingPause, error) {
+	results, err := s.db.GetAllPauses(ctx)
+	return results, err
+}
+
+func (s *sqliteStore) RemovePause(ctx context.Context, programName string) error {
+	return s.db.RemovePause(ctx, programName)
+}
+
+func (s *sqliteStore) RemoveAllPauses(ctx context.Context) error {
+	return s.db.RemoveAllPauses(ctx)
+}
+
 ////////////////// Active Repository //////////////////
 
 func (s *sqliteStore) CreateActiveSession(ctx context.Context, arg database.CreateActiveSessionParams) error {
 	return s.db.CreateActiveSession(ctx, arg)
 }
 
-func (s *sqliteStore) GetActiveSession(ctx context.Context, programName string) (time.Time, error) {
+func (s *sqliteStore) GetActiveSession(ctx context.Context, programName string) (database.GetActiveSessionRow, error) {
 	result, err := s.db.GetActiveSession(ctx, programName)
 	return result, err
 }
@@ -117,6 +233,22 @@ func (s *sqliteStore) GetAllActiveSessions(ctx context.Context) ([]database.Acti
 	return result, err
 }
 
+func (s *sqliteStore) UpdateActiveIdle(ctx context.Context, arg database.UpdateActiveIdleParams) error {
+	return s.db.UpdateActiveIdle(ctx, arg)
+}
+
+func (s *sqliteStore) UpdateActiveLastSeen(ctx context.Context, lastSeen sql.NullTime) error {
+	return s.db.UpdateActiveLastSeen(ctx, lastSeen)
+}
+
+func (s *sqliteStore) UpdateActiveNotes(ctx context.Context, arg database.UpdateActiveNotesParams) error {
+	return s.db.UpdateActiveNotes(ctx, arg)
+}
+
+func (s *sqliteStore) UpdateActiveTags(ctx context.Context, arg database.UpdateActiveTagsParams) error {
+	return s.db.UpdateActiveTags(ctx, arg)
+}
+
 func (s *sqliteStore) RemoveActiveSession(ctx context.Context, programName string) error {
 	return s.db.RemoveActiveSession(ctx, programName)
 }
@@ -127,8 +259,11 @@ func (s *sqliteStore) RemoveAllSessions(ctx context.Context) error {
 
 ////////////////// History Repository //////////////////
 
-func (s *sqliteStore) AddToSessionHistory(ctx context.Context, arg database.AddToSessionHistoryParams) error {
-	return s.db.AddToSessionHistory(ctx, arg)
+// Session writes fill in start_unix and end_unix from the session's times, which reports filter and sum on
+func (s *sqliteStore) AddToSessionHistory(ctx context.Context, arg database.AddToSessionHistoryParams) (int64, error) {
+	arg.StartUnix, arg.EndUnix = arg.StartTime.Unix(), arg.EndTime.Unix()
+	id, err := s.db.AddToSessionHistory(ctx, arg)
+	return id, err
 }
 
 func (s *sqliteStore) GetCountOfSessionsForProgram(ctx context.Context, programName string) (int64, error) {
@@ -136,6 +271,11 @@ func (s *sqliteStore) GetCountOfSessionsForProgram(ctx context.Context, programN
 	return result, err
 }
 
+func (s *sqliteStore) GetSessionStatsForProgram(ctx context.Context, arg database.GetSessionStatsForProgramParams) (database.GetSessionStatsForProgramRow, error) {
+	result, err := s.db.GetSessionStatsForProgram(ctx, arg)
+	return result, err
+}
+
 func (s *sqliteStore) GetLastSessionForProgram(ctx context.Context, programName string) (database.SessionHistory, error) {
 	result, err := s.db.GetLastSessionForProgram(ctx, programName)
 	return result, err
@@ -149,32 +289,96 @@ func (s *sqliteStore) RemoveRecordsForProgram(ctx context.Context, programName s
 	return s.db.RemoveRecordsForProgram(ctx, programName)
 }
 
-func (s *sqliteStore) GetSessionHistory(ctx context.Context, arg database.GetSessionHistoryParams) ([]database.SessionHistory, error) {
-	results, err := s.db.GetSessionHistory(ctx, arg)
+func (s *sqliteStore) GetFilteredSessionHistory(ctx context.Context, arg database.GetFilteredSessionHistoryParams) ([]database.SessionHistory, error) {
+	results, err := s.db.GetFilteredSessionHistory(ctx, arg)
 	return results, err
 }
 
-func (s *sqliteStore) GetAllSessionHistory(ctx context.Context, limit int64) ([]database.SessionHistory, error) {
-	results, err := s.db.GetAllSessionHistory(ctx, limit)
+func (s *sqliteStore) GetFilteredSessionTotal(ctx context.Context, arg database.GetFilteredSessionTotalParams) (int64, error) {
+	result, err := s.db.GetFilteredSessionTotal(ctx, arg)
+	return result, err
+}
+
+func (s *sqliteStore) GetAllSessionsForProgram(ctx context.Context, programName string) ([]database.SessionHistory, error) {
+	results, err := s.db.GetAllSessionsForProgram(ctx, programName)
 	return results, err
 }
 
-func (s *sqliteStore) GetSessionHistoryByDate(ctx context.Context, arg database.GetSessionHistoryByDateParams) ([]database.SessionHistory, error) {
-	results, err := s.db.GetSessionHistoryByDate(ctx, arg)
+func (s *sqliteStore) RemoveSessionRecord(ctx context.Context, id int64) error {
+	return s.db.RemoveSessionRecord(ctx, id)
+}
+
+func (s *sqliteStore) UpdateSessionRecord(ctx context.Context, arg database.UpdateSessionRecordParams) error {
+	arg.EndUnix = arg.EndTime.Unix()
+	return s.db.UpdateSessionRecord(ctx, arg)
+}
+
+func (s *sqliteStore) GetSessionRecord(ctx context.Context, id int64) (database.SessionHistory, error) {
+	result, err := s.db.GetSessionRecord(ctx, id)
+	return result, err
+}
+
+func (s *sqliteStore) GetOverlappingSessions(ctx context.Context, arg database.GetOverlappingSessionsParams) ([]database.SessionHistory, error) {
+	results, err := s.db.GetOverlappingSessions(ctx, arg)
 	return results, err
 }
 
-func (s *sqliteStore) GetAllSessionHistoryByDate(ctx context.Context, arg database.GetAllSessionHistoryByDateParams) ([]database.SessionHistory, error) {
-	results, err := s.db.GetAllSessionHistoryByDate(ctx, arg)
+func (s *sqliteStore) GetUsageTotal(ctx context.Context, arg database.GetUsageTotalParams) (database.GetUsageTotalRow, error) {
+	result, err := s.db.GetUsageTotal(ctx, arg)
+	return result, err
+}
+
+func (s *sqliteStore) GetUsageByProgram(ctx context.Context, arg database.GetUsageByProgramParams) ([]database.GetUsageByProgramRow, error) {
+	results, err := s.db.GetUsageByProgram(ctx, arg)
 	return results, err
 }
 
-func (s *sqliteStore) GetSessionHistoryByRange(ctx context.Context, arg database.GetSessionHistoryByRangeParams) ([]database.SessionHistory, error) {
-	results, err := s.db.GetSessionHistoryByRange(ctx, arg)
+func (s *sqliteStore) GetUsageByCategory(ctx context.Context, arg database.GetUsageByCategoryParams) ([]database.GetUsageByCategoryRow, error) {
+	results, err := s.db.GetUsageByCategory(ctx, arg)
 	return results, err
 }
 
-func (s *sqliteStore) GetAllSessionHistoryByRange(ctx context.Context, arg database.GetAllSessionHistoryByRangeParams) ([]database.SessionHistory, error) {
-	results, err := s.db.GetAllSessionHistoryByRange(ctx, arg)
+func (s *sqliteStore) GetUsageByProject(ctx context.Context, arg database.GetUsageByProjectParams) ([]database.GetUsageByProjectRow, error) {
+	results, err := s.db.GetUsageByProject(ctx, arg)
 	return results, err
 }
+
+func (s *sqliteStore) GetUsageByTag(ctx context.Context, arg database.GetUsageByTagParams) ([]database.GetUsageByTagRow, error) {
+	results, err := s.db.GetUsageByTag(ctx, arg)
+	return results, err
+}
+
+func (s *sqliteStore) GetSessionSpans(ctx context.Context, arg database.GetSessionSpansParams) ([]database.GetSessionSpansRow, error) {
+	results, err := s.db.GetSessionSpans(ctx, arg)
+	return results, err
+}
+
+func (s *sqliteStore) EditSessionRecord(ctx context.Context, arg database.EditSessionRecordParams) error {
+	arg.StartUnix, arg.EndUnix = arg.StartTime.Unix(), arg.EndTime.Unix()
+	return s.db.EditSessionRecord(ctx, arg)
+}
+
+func (s *sqliteStore) UpdateSessionNotes(ctx context.Context, arg database.UpdateSessionNotesParams) error {
+	return s.db.UpdateSessionNotes(ctx, arg)
+}
+
+func (s *sqliteStore) AddSessionTag(ctx context.Context, arg database.AddSessionTagParams) error {
+	return s.db.AddSessionTag(ctx, arg)
+}
+
+func (s *sqliteStore) GetTagsForSession(ctx context.Context, sessionID int64) ([]string, error) {
+	results, err := s.db.GetTagsForSession(ctx, sessionID)
+	return results, err
+}
+
+func (s *sqliteStore) RemoveSessionTag(ctx context.Context, arg database.RemoveSessionTagParams) error {
+	return s.db.RemoveSessionTag(ctx, arg)
+}
+
+func (s *sqliteStore) RemoveTagsForSession(ctx context.Context, sessionID int64) error {
+	return s.db.RemoveTagsForSession(ctx, sessionID)
+}
+
+func (s *sqliteStore) RemoveOrphanedTags(ctx context.Context) error {
+	return s.db.RemoveOrphanedTags(ctx)
+}
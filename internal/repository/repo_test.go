@@ -0,0 +1,104 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jms-guy/timekeep/internal/database"
+	"github.com/jms-guy/timekeep/internal/repository"
+	mysql "github.com/jms-guy/timekeep/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func setupStore(t *testing.T) repository.Store {
+	db, err := mysql.OpenTestDatabase()
+	require.NoError(t, err, "Failed to open test database")
+
+	store := repository.NewSqliteStore(db)
+	require.NoError(t, store.AddProgram(context.Background(), database.AddProgramParams{Name: "code", MatchMode: "exact", Scope: "any"}))
+
+	return store
+}
+
+func TestWithTx_Commits(t *testing.T) {
+	store := setupStore(t)
+
+	err := store.WithTx(t.Context(), func(tx repository.Store) error {
+		return tx.UpdateLifetime(t.Context(), database.UpdateLifetimeParams{Name: "code", LifetimeSeconds: 60})
+	})
+	require.NoError(t, err)
+
+	program, err := store.GetProgramByName(t.Context(), "code")
+	require.NoError(t, err)
+	assert.Equal(t, int64(60), program.LifetimeSeconds, "Committed changes should be visible")
+}
+
+func TestWithTx_RollsBackOnError(t *testing.T) {
+	store := setupStore(t)
+	failed := errors.New("failed")
+
+	err := store.WithTx(t.Context(), func(tx repository.Store) error {
+		_, err := tx.AddToSessionHistory(t.Context(), database.AddToSessionHistoryParams{
+			ProgramName:     "code",
+			StartTime:       time.Now().Add(-time.Minute),
+			EndTime:         time.Now(),
+			DurationSeconds: 60,
+		})
+		require.NoError(t, err)
+
+		// Nested calls join the outer transaction
+		err = tx.WithTx(t.Context(), func(tx repository.Store) error {
+			return tx.UpdateLifetime(t.Context(), database.UpdateLifetimeParams{Name: "code", LifetimeSeconds: 60})
+		})
+		require.NoError(t, err)
+
+		return failed
+	})
+	assert.ErrorIs(t, err, failed)
+
+	count, err := store.GetCountOfSessionsForProgram(t.Context(), "code")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count, "History insert should be rolled back")
+
+	program, err := store.GetProgramByName(t.Context(), "code")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), program.LifetimeSeconds, "Lifetime update should be rolled back")
+}
+
+func TestSessionHistory_WritesUnixTimes(t *testing.T) {
+	store := setupStore(t)
+	start := time.Date(2025, 9, 30, 9, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	end := start.Add(time.Hour)
+
+	id, err := store.AddToSessionHistory(t.Context(), database.AddToSessionHistoryParams{
+		ProgramName:     "code",
+		StartTime:       start,
+		EndTime:         end,
+		DurationSeconds: 3600,
+	})
+	require.NoError(t, err)
+
+	session, err := store.GetSessionRecord(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, start.Unix(), session.StartUnix)
+	assert.Equal(t, end.Unix(), session.EndUnix)
+
+	end = end.Add(30 * time.Minute)
+	require.NoError(t, store.UpdateSessionRecord(t.Context(), database.UpdateSessionRecordParams{ID: id, EndTime: end, DurationSeconds: 5400}))
+
+	session, err = store.GetSessionRecord(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, end.Unix(), session.EndUnix, "Updating a session should move its end")
+
+	start, end = start.Add(-time.Hour).UTC(), end.Add(-time.Hour).UTC()
+	require.NoError(t, store.EditSessionRecord(t.Context(), database.EditSessionRecordParams{ID: id, StartTime: start, EndTime: end, DurationSeconds: 5400}))
+
+	session, err = store.GetSessionRecord(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, start.Unix(), session.StartUnix, "Editing a session should move its start")
+	assert.Equal(t, end.Unix(), session.EndUnix, "Editing a session should move its end")
+}
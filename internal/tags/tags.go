@@ -0,0 +1,46 @@
+package tags
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+)
+
+// Session tags are lowercase words without commas or spaces. An active session keeps its tags in a single comma-separated
+// column until it's archived, when they're moved into the session_tags table
+
+// Normalizes a tag given by the user, reporting false if nothing usable is left
+func Normalize(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" || strings.ContainsAny(tag, ", \t") {
+		return "", false
+	}
+	return tag, true
+}
+
+// Splits an active session's tags column into its tags
+func Parse(column sql.NullString) []string {
+	if !column.Valid || column.String == "" {
+		return nil
+	}
+	return strings.Split(column.String, ",")
+}
+
+// Joins tags into an active session's tags column, sorted and without duplicates. No tags gives NULL
+func Join(tags []string) sql.NullString {
+	set := make(map[string]struct{}, len(tags))
+	unique := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if _, ok := set[tag]; ok {
+			continue
+		}
+		set[tag] = struct{}{}
+		unique = append(unique, tag)
+	}
+	if len(unique) == 0 {
+		return sql.NullString{}
+	}
+
+	sort.Strings(unique)
+	return sql.NullString{String: strings.Join(unique, ","), Valid: true}
+}
@@ -9,7 +9,6 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/jms-guy/timekeep/internal/database"
 	"github.com/pressly/goose/v3"
 )
 
@@ -17,7 +16,7 @@ import (
 var embedMigrations embed.FS
 
 // Open database connection with embedded migrations
-func OpenLocalDatabase() (*database.Queries, error) {
+func OpenLocalDatabase() (*sql.DB, error) {
 	dbPath, err := getDatabasePath()
 	if err != nil {
 		return nil, err
@@ -44,17 +43,16 @@ func OpenLocalDatabase() (*database.Queries, error) {
 		return nil, err
 	}
 
-	queries := database.New(db)
-
-	return queries, nil
+	return db, nil
 }
 
 // Opens functional in-memory testing database
-func OpenTestDatabase() (*database.Queries, error) {
+func OpenTestDatabase() (*sql.DB, error) {
 	db, err := sql.Open("sqlite", ":memory:")
 	if err != nil {
 		return nil, err
 	}
+	db.SetMaxOpenConns(1) // Every connection opens its own in-memory database, keep transactions on the migrated one
 
 	goose.SetBaseFS(embedMigrations)
 	goose.SetLogger(log.New(io.Discard, "", 0))
@@ -67,7 +65,5 @@ func OpenTestDatabase() (*database.Queries, error) {
 		return nil, err
 	}
 
-	queries := database.New(db)
-
-	return queries, nil
+	return db, nil
 }
@@ -1,9 +1,9 @@
 -- name: CreateActiveSession :exec
-INSERT INTO active_sessions (program_name, start_time)
-VALUES (?, ?);
+INSERT INTO active_sessions (program_name, start_time, uid, container, unit, project, manual, category, notes, tags)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
 
 -- name: GetActiveSession :one
-SELECT start_time FROM active_sessions
+SELECT start_time, uid, container, unit, project, idle_seconds, manual, category, notes, tags FROM active_sessions
 WHERE program_name = ?;
 
 -- name: GetAllActiveSessions :many
@@ -14,4 +14,23 @@ DELETE FROM active_sessions
 WHERE program_name = ?;
 
 -- name: RemoveAllSessions :exec
-DELETE FROM active_sessions;
\ No newline at end of file
+DELETE FROM active_sessions;
+
+-- name: UpdateActiveIdle :exec
+UPDATE active_sessions
+SET idle_seconds = ?
+WHERE program_name = ?;
+
+-- name: UpdateActiveNotes :exec
+UPDATE active_sessions
+SET notes = ?
+WHERE program_name = ?;
+
+-- name: UpdateActiveTags :exec
+UPDATE active_sessions
+SET tags = ?
+WHERE program_name = ?;
+
+-- name: UpdateActiveLastSeen :exec
+UPDATE active_sessions
+SET last_seen = ?;
\ No newline at end of file
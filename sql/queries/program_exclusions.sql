@@ -0,0 +1,17 @@
+-- name: AddExclusion :exec
+INSERT OR IGNORE INTO program_exclusions (program_name, kind, pattern)
+VALUES (?, ?, ?);
+
+-- name: GetAllExclusions :many
+SELECT * FROM program_exclusions;
+
+-- name: GetExclusionsForProgram :many
+SELECT * FROM program_exclusions
+WHERE program_name = ?;
+
+-- name: RemoveAllExclusions :exec
+DELETE FROM program_exclusions;
+
+-- name: RemoveExclusionsForProgram :exec
+DELETE FROM program_exclusions
+WHERE program_name = ?;
\ No newline at end of file
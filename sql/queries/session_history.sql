@@ -1,17 +1,39 @@
--- name: AddToSessionHistory :exec
-INSERT INTO session_history (program_name, start_time, end_time, duration_seconds)
-VALUES (?, ?, ?, ?);
+-- name: AddToSessionHistory :execlastid
+INSERT INTO session_history (program_name, start_time, end_time, duration_seconds, uid, container, unit, project, idle_seconds, recovered, manual, category, notes, start_unix, end_unix)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
 
 -- name: GetLastSessionForProgram :one 
 SELECT * FROM session_history
 WHERE session_history.program_name = ?
-ORDER BY end_time DESC
+ORDER BY end_unix DESC
 LIMIT 1;
 
+-- name: GetSessionRecord :one
+SELECT * FROM session_history
+WHERE id = ?;
+
+-- name: GetOverlappingSessions :many
+SELECT * FROM session_history
+WHERE program_name = ?
+  AND start_unix < sqlc.arg('end_unix') AND end_unix > sqlc.arg('start_unix')
+  AND id != sqlc.arg('exclude_id')
+ORDER BY start_unix ASC;
+
 -- name: GetCountOfSessionsForProgram :one
 SELECT COUNT(*) FROM session_history
 WHERE session_history.program_name = ?;
 
+-- name: GetSessionStatsForProgram :one
+SELECT COUNT(*) AS count,
+  CAST(COALESCE(SUM(CASE WHEN sqlc.arg('window_end') = 0 THEN duration_seconds
+    ELSE MIN(end_unix, sqlc.arg('window_end')) - MAX(start_unix, sqlc.arg('window_start')) END), 0) AS INTEGER) AS total_seconds,
+  CAST(COALESCE(SUM(CASE WHEN sqlc.arg('window_end') = 0 THEN idle_seconds
+    ELSE idle_seconds * (MIN(end_unix, sqlc.arg('window_end')) - MAX(start_unix, sqlc.arg('window_start'))) / MAX(end_unix - start_unix, 1) END), 0) AS INTEGER) AS idle_seconds FROM session_history
+WHERE session_history.program_name = ?
+  AND (sqlc.arg('window_end') = 0 OR (start_unix < sqlc.arg('window_end') AND end_unix > sqlc.arg('window_start')))
+  AND duration_seconds >= sqlc.arg('min_duration')
+  AND (sqlc.narg('tag') IS NULL OR session_history.id IN (SELECT session_id FROM session_tags WHERE tag = sqlc.narg('tag')));
+
 -- name: RemoveAllRecords :exec
 DELETE FROM session_history;
 
@@ -19,57 +41,97 @@ DELETE FROM session_history;
 DELETE FROM session_history
 WHERE session_history.program_name = ?;
 
--- name: GetSessionHistory :many
-SELECT * FROM (
-    SELECT * FROM session_history
-    WHERE program_name = ?
-    ORDER BY end_time DESC
-    LIMIT ?
-) AS results
-ORDER BY end_time ASC;
-
--- name: GetSessionHistoryByDate :many
-SELECT * FROM (
-    SELECT * FROM session_history
-    WHERE program_name = ? 
-      AND start_time <= ? AND end_time >= ?
-    ORDER BY start_time DESC
-    LIMIT ?
-) AS results
-ORDER BY start_time ASC;
-
--- name: GetSessionHistoryByRange :many
-SELECT * FROM (
-    SELECT * FROM session_history
-    WHERE program_name = ?
-      AND start_time <= ? AND end_time >= ?
-    ORDER BY start_time DESC
-    LIMIT ?
-) AS results
-ORDER BY start_time ASC;
+-- name: RemoveSessionRecord :exec
+DELETE FROM session_history
+WHERE id = ?;
 
--- name: GetAllSessionHistory :many
-SELECT * FROM (
-    SELECT * FROM session_history
-    ORDER BY end_time DESC
-    LIMIT ?
-) AS results
-ORDER BY end_time ASC;
+-- name: EditSessionRecord :exec
+UPDATE session_history
+SET start_time = ?, end_time = ?, duration_seconds = ?, idle_seconds = ?, project = ?, start_unix = ?, end_unix = ?
+WHERE id = ?;
 
--- name: GetAllSessionHistoryByDate :many
-SELECT * FROM (
-    SELECT * FROM session_history
-    WHERE start_time <= ? AND end_time >= ?
-    ORDER BY start_time DESC
-    LIMIT ?
-) AS results
-ORDER BY start_time ASC;
+-- name: UpdateSessionNotes :exec
+UPDATE session_history
+SET notes = ?
+WHERE id = ?;
+
+-- name: UpdateSessionRecord :exec
+UPDATE session_history
+SET end_time = ?, duration_seconds = ?, idle_seconds = ?, end_unix = ?
+WHERE id = ?;
 
--- name: GetAllSessionHistoryByRange :many
-SELECT * FROM (
-    SELECT * FROM session_history
-    WHERE start_time <= ? AND end_time >= ?
-    ORDER BY start_time DESC
+-- name: GetAllSessionsForProgram :many
+SELECT * FROM session_history
+WHERE program_name = ?
+ORDER BY start_unix ASC;
+
+-- name: GetFilteredSessionHistory :many
+SELECT results.* FROM (
+    SELECT session_history.* FROM session_history
+    WHERE (sqlc.arg('program_name') = '' OR session_history.program_name = sqlc.arg('program_name'))
+      AND (sqlc.arg('window_end') = 0 OR (start_unix < sqlc.arg('window_end') AND end_unix > sqlc.arg('window_start')))
+      AND (sqlc.narg('uid') IS NULL OR uid = sqlc.narg('uid'))
+      AND duration_seconds >= sqlc.arg('min_duration')
+      AND (sqlc.narg('tag') IS NULL OR session_history.id IN (SELECT session_id FROM session_tags WHERE tag = sqlc.narg('tag')))
+    ORDER BY end_unix DESC, id DESC
     LIMIT ?
 ) AS results
-ORDER BY start_time ASC;
\ No newline at end of file
+ORDER BY results.end_unix ASC, results.id ASC;
+
+-- name: GetFilteredSessionTotal :one
+SELECT CAST(COALESCE(SUM(MIN(end_unix, sqlc.arg('window_end')) - MAX(start_unix, sqlc.arg('window_start'))), 0) AS INTEGER) AS total_seconds
+FROM session_history
+WHERE (sqlc.arg('program_name') = '' OR program_name = sqlc.arg('program_name'))
+  AND start_unix < sqlc.arg('window_end') AND end_unix > sqlc.arg('window_start')
+  AND (sqlc.narg('uid') IS NULL OR uid = sqlc.narg('uid'))
+  AND duration_seconds >= sqlc.arg('min_duration')
+  AND (sqlc.narg('tag') IS NULL OR id IN (SELECT session_id FROM session_tags WHERE tag = sqlc.narg('tag')));
+
+-- name: GetUsageTotal :one
+SELECT COUNT(*) AS sessions,
+  CAST(COALESCE(SUM(MIN(end_unix, sqlc.arg('window_end')) - MAX(start_unix, sqlc.arg('window_start'))), 0) AS INTEGER) AS total_seconds
+FROM session_history
+WHERE start_unix < sqlc.arg('window_end') AND end_unix > sqlc.arg('window_start');
+
+-- name: GetUsageByProgram :many
+SELECT program_name AS name, COUNT(*) AS sessions,
+  CAST(SUM(MIN(end_unix, sqlc.arg('window_end')) - MAX(start_unix, sqlc.arg('window_start'))) AS INTEGER) AS total_seconds
+FROM session_history
+WHERE start_unix < sqlc.arg('window_end') AND end_unix > sqlc.arg('window_start')
+GROUP BY program_name
+ORDER BY total_seconds DESC, name ASC;
+
+-- name: GetUsageByCategory :many
+SELECT CAST(COALESCE(session_history.category, tracked_programs.category, '') AS TEXT) AS name, COUNT(*) AS sessions,
+  CAST(SUM(MIN(end_unix, sqlc.arg('window_end')) - MAX(start_unix, sqlc.arg('window_start'))) AS INTEGER) AS total_seconds
+FROM session_history
+LEFT JOIN tracked_programs ON tracked_programs.name = session_history.program_name
+WHERE start_unix < sqlc.arg('window_end') AND end_unix > sqlc.arg('window_start')
+GROUP BY 1
+ORDER BY total_seconds DESC, name ASC;
+
+-- name: GetUsageByProject :many
+SELECT CAST(COALESCE(session_history.project, tracked_programs.project, '') AS TEXT) AS name, COUNT(*) AS sessions,
+  CAST(SUM(MIN(end_unix, sqlc.arg('window_end')) - MAX(start_unix, sqlc.arg('window_start'))) AS INTEGER) AS total_seconds
+FROM session_history
+LEFT JOIN tracked_programs ON tracked_programs.name = session_history.program_name
+WHERE start_unix < sqlc.arg('window_end') AND end_unix > sqlc.arg('window_start')
+GROUP BY 1
+ORDER BY total_seconds DESC, name ASC;
+
+-- name: GetUsageByTag :many
+SELECT CAST(COALESCE(session_tags.tag, '') AS TEXT) AS name, COUNT(*) AS sessions,
+  CAST(SUM(MIN(end_unix, sqlc.arg('window_end')) - MAX(start_unix, sqlc.arg('window_start'))) AS INTEGER) AS total_seconds
+FROM session_history
+LEFT JOIN session_tags ON session_tags.session_id = session_history.id
+WHERE start_unix < sqlc.arg('window_end') AND end_unix > sqlc.arg('window_start')
+GROUP BY 1
+ORDER BY total_seconds DESC, name ASC;
+
+-- name: GetSessionSpans :many
+SELECT id, program_name,
+  CAST(MAX(start_unix, sqlc.arg('window_start')) AS INTEGER) AS start_unix,
+  CAST(MIN(end_unix, sqlc.arg('window_end')) AS INTEGER) AS end_unix
+FROM session_history
+WHERE start_unix < sqlc.arg('window_end') AND end_unix > sqlc.arg('window_start')
+ORDER BY start_unix ASC;
@@ -0,0 +1,20 @@
+-- name: AddSessionTag :exec
+INSERT OR IGNORE INTO session_tags (session_id, tag)
+VALUES (?, ?);
+
+-- name: GetTagsForSession :many
+SELECT tag FROM session_tags
+WHERE session_id = ?
+ORDER BY tag ASC;
+
+-- name: RemoveSessionTag :exec
+DELETE FROM session_tags
+WHERE session_id = ? AND tag = ?;
+
+-- name: RemoveTagsForSession :exec
+DELETE FROM session_tags
+WHERE session_id = ?;
+
+-- name: RemoveOrphanedTags :exec
+DELETE FROM session_tags
+WHERE session_id NOT IN (SELECT id FROM session_history);
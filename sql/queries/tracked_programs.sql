@@ -9,8 +9,8 @@ SELECT name FROM tracked_programs;
 SELECT * FROM tracked_programs;
 
 -- name: AddProgram :exec
-INSERT OR IGNORE INTO tracked_programs (name, category, project)
-VALUES (?, ?, ?);
+INSERT OR IGNORE INTO tracked_programs (name, category, project, match_mode, exe, args_contains, scope)
+VALUES (?, ?, ?, ?, ?, ?, ?);
 
 -- name: RemoveProgram :exec
 DELETE FROM tracked_programs
@@ -18,7 +18,14 @@ WHERE name = ?;
 
 -- name: UpdateLifetime :exec
 UPDATE tracked_programs
-SET lifetime_seconds = lifetime_seconds + ?
+SET lifetime_seconds = lifetime_seconds + ?,
+    idle_seconds = idle_seconds + ?
+WHERE name = ?;
+
+-- name: RecomputeLifetime :exec
+UPDATE tracked_programs
+SET lifetime_seconds = (SELECT COALESCE(SUM(duration_seconds), 0) FROM session_history WHERE program_name = tracked_programs.name),
+    idle_seconds = (SELECT COALESCE(SUM(idle_seconds), 0) FROM session_history WHERE program_name = tracked_programs.name)
 WHERE name = ?;
 
 -- name: RemoveAllPrograms :exec
@@ -26,12 +33,12 @@ DELETE FROM tracked_programs;
 
 -- name: ResetLifetimeForProgram :exec
 UPDATE tracked_programs 
-SET lifetime_seconds = 0 
+SET lifetime_seconds = 0, idle_seconds = 0
 WHERE name = ?;
 
 -- name: ResetAllLifetimes :exec
 UPDATE tracked_programs 
-SET lifetime_seconds = 0;
+SET lifetime_seconds = 0, idle_seconds = 0;
 
 -- name: UpdateCategory :exec 
 UPDATE tracked_programs
@@ -41,4 +48,14 @@ WHERE name = ?;
 -- name: UpdateProject :exec
 UPDATE tracked_programs
 SET project = ?
+WHERE name = ?;
+
+-- name: UpdateScope :exec
+UPDATE tracked_programs
+SET scope = ?
+WHERE name = ?;
+
+-- name: UpdateMinSession :exec
+UPDATE tracked_programs
+SET min_session = ?
 WHERE name = ?;
\ No newline at end of file
@@ -0,0 +1,14 @@
+-- name: AddPause :exec
+INSERT OR REPLACE INTO tracking_pauses (program_name, paused_at, resume_at)
+VALUES (?, ?, ?);
+
+-- name: GetAllPauses :many
+SELECT * FROM tracking_pauses
+ORDER BY program_name;
+
+-- name: RemovePause :exec
+DELETE FROM tracking_pauses
+WHERE program_name = ?;
+
+-- name: RemoveAllPauses :exec
+DELETE FROM tracking_pauses;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Names are stored lowercase whatever the mode, keeping only regex escapes and flag groups as given. Names stored before
+-- this are exact and already lowercase
+ALTER TABLE tracked_programs
+ADD match_mode TEXT NOT NULL DEFAULT 'exact';
+
+-- +goose Down
+ALTER TABLE tracked_programs
+DROP COLUMN match_mode;
\ No newline at end of file
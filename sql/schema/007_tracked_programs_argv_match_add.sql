@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE tracked_programs
+ADD exe TEXT;
+
+ALTER TABLE tracked_programs
+ADD args_contains TEXT;
+
+-- +goose Down
+ALTER TABLE tracked_programs
+DROP COLUMN args_contains;
+
+ALTER TABLE tracked_programs
+DROP COLUMN exe;
\ No newline at end of file
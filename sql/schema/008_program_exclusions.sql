@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE program_exclusions (
+    id INTEGER PRIMARY KEY,
+    program_name TEXT NOT NULL REFERENCES tracked_programs(name)
+    ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    pattern TEXT NOT NULL,
+    UNIQUE (program_name, kind, pattern)
+);
+
+-- +goose Down
+DROP TABLE program_exclusions;
\ No newline at end of file
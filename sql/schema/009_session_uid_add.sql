@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE active_sessions
+ADD uid INTEGER;
+
+ALTER TABLE session_history
+ADD uid INTEGER;
+
+-- +goose Down
+ALTER TABLE session_history
+DROP COLUMN uid;
+
+ALTER TABLE active_sessions
+DROP COLUMN uid;
@@ -0,0 +1,31 @@
+-- +goose Up
+ALTER TABLE tracked_programs
+ADD scope TEXT NOT NULL DEFAULT 'any';
+
+ALTER TABLE active_sessions
+ADD container TEXT;
+
+ALTER TABLE active_sessions
+ADD unit TEXT;
+
+ALTER TABLE session_history
+ADD container TEXT;
+
+ALTER TABLE session_history
+ADD unit TEXT;
+
+-- +goose Down
+ALTER TABLE session_history
+DROP COLUMN unit;
+
+ALTER TABLE session_history
+DROP COLUMN container;
+
+ALTER TABLE active_sessions
+DROP COLUMN unit;
+
+ALTER TABLE active_sessions
+DROP COLUMN container;
+
+ALTER TABLE tracked_programs
+DROP COLUMN scope;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE active_sessions
+ADD project TEXT;
+
+ALTER TABLE session_history
+ADD project TEXT;
+
+-- +goose Down
+ALTER TABLE session_history
+DROP COLUMN project;
+
+ALTER TABLE active_sessions
+DROP COLUMN project;
@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE tracked_programs
+ADD idle_seconds INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE active_sessions
+ADD idle_seconds INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE session_history
+ADD idle_seconds INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE session_history
+DROP COLUMN idle_seconds;
+
+ALTER TABLE active_sessions
+DROP COLUMN idle_seconds;
+
+ALTER TABLE tracked_programs
+DROP COLUMN idle_seconds;
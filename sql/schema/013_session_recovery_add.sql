@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE active_sessions
+ADD last_seen DATETIME;
+
+ALTER TABLE session_history
+ADD recovered BOOLEAN NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE session_history
+DROP COLUMN recovered;
+
+ALTER TABLE active_sessions
+DROP COLUMN last_seen;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE tracked_programs
+ADD min_session INTEGER;
+
+-- +goose Down
+ALTER TABLE tracked_programs
+DROP COLUMN min_session;
@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE active_sessions
+ADD manual BOOLEAN NOT NULL DEFAULT 0;
+
+ALTER TABLE active_sessions
+ADD category TEXT;
+
+ALTER TABLE session_history
+ADD manual BOOLEAN NOT NULL DEFAULT 0;
+
+ALTER TABLE session_history
+ADD category TEXT;
+
+-- +goose Down
+ALTER TABLE session_history
+DROP COLUMN category;
+
+ALTER TABLE session_history
+DROP COLUMN manual;
+
+ALTER TABLE active_sessions
+DROP COLUMN category;
+
+ALTER TABLE active_sessions
+DROP COLUMN manual;
@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE session_tags (
+    id INTEGER PRIMARY KEY,
+    session_id INTEGER NOT NULL REFERENCES session_history(id)
+    ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    UNIQUE (session_id, tag)
+);
+
+ALTER TABLE session_history
+ADD notes TEXT;
+
+ALTER TABLE active_sessions
+ADD notes TEXT;
+
+ALTER TABLE active_sessions
+ADD tags TEXT;
+
+-- +goose Down
+ALTER TABLE active_sessions
+DROP COLUMN tags;
+
+ALTER TABLE active_sessions
+DROP COLUMN notes;
+
+ALTER TABLE session_history
+DROP COLUMN notes;
+
+DROP TABLE session_tags;
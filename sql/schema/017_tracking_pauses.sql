@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE TABLE tracking_pauses (
+    program_name TEXT PRIMARY KEY, -- Empty for a pause of every program
+    paused_at DATETIME NOT NULL,
+    resume_at DATETIME
+);
+
+-- +goose Down
+DROP TABLE tracking_pauses;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- Session history with start and end times as unix seconds, for reports aggregating time in SQL. Times are stored in
+-- the layout of Go's time.Time.String (ex. "2025-09-30 09:00:00.5 +0200 CEST"), which SQLite's date functions can't
+-- read, so the wall clock and UTC offset are cut out and rejoined as "2025-09-30 09:00:00+02:00"
+CREATE VIEW session_spans AS
+SELECT
+    id,
+    program_name,
+    project,
+    category,
+    CAST(strftime('%s',
+        substr(start_time, 1, 19)
+        || substr(start_time, 19 + instr(substr(start_time, 20), ' ') + 1, 3)
+        || ':'
+        || substr(start_time, 19 + instr(substr(start_time, 20), ' ') + 4, 2)
+    ) AS INTEGER) AS start_unix,
+    CAST(strftime('%s',
+        substr(end_time, 1, 19)
+        || substr(end_time, 19 + instr(substr(end_time, 20), ' ') + 1, 3)
+        || ':'
+        || substr(end_time, 19 + instr(substr(end_time, 20), ' ') + 4, 2)
+    ) AS INTEGER) AS end_unix
+FROM session_history;
+
+-- +goose Down
+DROP VIEW session_spans;
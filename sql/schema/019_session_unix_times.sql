@@ -0,0 +1,64 @@
+-- +goose Up
+-- Session start and end times as unix seconds, written alongside start_time and end_time, so reports and filters compare
+-- and index plain integers. Existing rows are filled from the text of their times, in the layout of Go's time.Time.String
+-- (ex. "2025-09-30 09:00:00.5 +0200 CEST"), falling back to reading the wall clock as UTC if the offset can't be read
+ALTER TABLE session_history
+ADD start_unix INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE session_history
+ADD end_unix INTEGER NOT NULL DEFAULT 0;
+
+UPDATE session_history SET
+    start_unix = COALESCE(
+        CAST(strftime('%s',
+            substr(start_time, 1, 19)
+            || substr(start_time, 19 + instr(substr(start_time, 20), ' ') + 1, 3)
+            || ':'
+            || substr(start_time, 19 + instr(substr(start_time, 20), ' ') + 4, 2)
+        ) AS INTEGER),
+        CAST(strftime('%s', substr(start_time, 1, 19)) AS INTEGER),
+        0),
+    end_unix = COALESCE(
+        CAST(strftime('%s',
+            substr(end_time, 1, 19)
+            || substr(end_time, 19 + instr(substr(end_time, 20), ' ') + 1, 3)
+            || ':'
+            || substr(end_time, 19 + instr(substr(end_time, 20), ' ') + 4, 2)
+        ) AS INTEGER),
+        CAST(strftime('%s', substr(end_time, 1, 19)) AS INTEGER),
+        0);
+
+CREATE INDEX session_history_start_unix ON session_history (start_unix);
+CREATE INDEX session_history_end_unix ON session_history (end_unix);
+
+DROP VIEW session_spans;
+
+-- +goose Down
+CREATE VIEW session_spans AS
+SELECT
+    id,
+    program_name,
+    project,
+    category,
+    CAST(strftime('%s',
+        substr(start_time, 1, 19)
+        || substr(start_time, 19 + instr(substr(start_time, 20), ' ') + 1, 3)
+        || ':'
+        || substr(start_time, 19 + instr(substr(start_time, 20), ' ') + 4, 2)
+    ) AS INTEGER) AS start_unix,
+    CAST(strftime('%s',
+        substr(end_time, 1, 19)
+        || substr(end_time, 19 + instr(substr(end_time, 20), ' ') + 1, 3)
+        || ':'
+        || substr(end_time, 19 + instr(substr(end_time, 20), ' ') + 4, 2)
+    ) AS INTEGER) AS end_unix
+FROM session_history;
+
+DROP INDEX session_history_end_unix;
+DROP INDEX session_history_start_unix;
+
+ALTER TABLE session_history
+DROP COLUMN end_unix;
+
+ALTER TABLE session_history
+DROP COLUMN start_unix;